@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bm-lrs/pkg/route"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runConvert streams an LRS route file from one format to another. Sources
+// are esri-geojson, rfc7946-geojson, and csv; the only supported target is
+// geoparquet. --t-srs is accepted but no reprojection is performed: a
+// non-empty value makes the command fail with an error before sinking.
+func runConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	from := fs.String("from", "esri-geojson", "source format: esri-geojson, rfc7946-geojson, csv")
+	to := fs.String("to", "geoparquet", "target format: geoparquet")
+	input := fs.String("input", "-", "input file, - for stdin")
+	output := fs.String("output", "-", "output file, - for stdout")
+	tSRS := fs.String("t-srs", "", "target CRS WKT for reprojection")
+	featureIdx := fs.Int("feature", 0, "feature index to convert")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *to != "geoparquet" {
+		return fmt.Errorf("unsupported target format %q", *to)
+	}
+
+	in, err := openInput(*input)
+	if err != nil {
+		return fmt.Errorf("failed to open input: %w", err)
+	}
+	if in != os.Stdin {
+		defer in.Close()
+	}
+
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	var lrs route.LRSRoute
+	switch *from {
+	case "esri-geojson":
+		lrs, err = route.NewLRSRouteFromESRIGeoJSON(data, *featureIdx, "")
+	case "rfc7946-geojson":
+		lrs, err = route.NewLRSRouteFromGeoJSON(data, *featureIdx, "", "")
+	case "csv":
+		lrs, err = route.NewLRSRouteFromCSV(bytes.NewReader(data), route.CSVOptions{})
+	default:
+		return fmt.Errorf("unsupported source format %q", *from)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+	defer lrs.Release()
+
+	if *tSRS != "" {
+		return fmt.Errorf("--t-srs reprojection requires the DuckDB spatial extension and is not wired up in this CLI build")
+	}
+
+	if err := lrs.Sink(route.WithGeoParquetMetadata()); err != nil {
+		return fmt.Errorf("failed to sink to parquet: %w", err)
+	}
+
+	pointFile := lrs.GetPointFile()
+	if pointFile == nil {
+		return fmt.Errorf("sink did not produce a point file")
+	}
+
+	src, err := os.Open(*pointFile)
+	if err != nil {
+		return fmt.Errorf("failed to open sunk parquet file: %w", err)
+	}
+	defer src.Close()
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return fmt.Errorf("failed to open output: %w", err)
+	}
+	if out != os.Stdout {
+		defer out.Close()
+	}
+
+	if _, err := io.Copy(out, src); err != nil {
+		return fmt.Errorf("failed to stream output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// schemaField is one entry of describeReport's Schema, naming an Arrow
+// field and its type.
+type schemaField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// describeReport is runDescribe's structured summary of a Parquet file,
+// emitted as JSON so CI can assert on it directly.
+type describeReport struct {
+	NumRows      int64            `json:"num_rows"`
+	NumRowGroups int              `json:"num_row_groups"`
+	Schema       []schemaField    `json:"schema"`
+	RouteIDs     []string         `json:"route_ids,omitempty"`
+	VertexCounts map[string]int64 `json:"vertex_counts,omitempty"`
+	CRS          string           `json:"crs,omitempty"`
+	MValueRange  *[2]float64      `json:"mvalue_range,omitempty"`
+	BoundingBox  *[4]float64      `json:"bounding_box,omitempty"`
+}
+
+// geoMeta is the subset of the GeoParquet "geo" file-level metadata
+// describe.go reads, kept minimal and local since pkg/route's own
+// geoParquetMeta type is unexported.
+type geoMeta struct {
+	Columns map[string]struct {
+		CRS any `json:"crs"`
+	} `json:"columns"`
+	PrimaryColumn string `json:"primary_column"`
+}
+
+// runDescribe opens a Parquet file produced by LRSRoute.Sink or
+// ParquetBatchHandler.MergeParquetFiles and emits a JSON summary: schema,
+// route ids, per-route vertex counts, CRS, MVAL range, and bounding box.
+func runDescribe(args []string) error {
+	fs := flag.NewFlagSet("describe", flag.ExitOnError)
+	input := fs.String("input", "-", "input parquet file, - for stdin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := *input
+	if path == "-" {
+		// Parquet requires random access, so stdin has to be staged to a
+		// temporary file first.
+		tmp, err := stageStdinToTempFile()
+		if err != nil {
+			return fmt.Errorf("failed to stage stdin: %w", err)
+		}
+		defer os.Remove(tmp)
+		path = tmp
+	}
+
+	pf, err := file.OpenParquetFile(path, false)
+	if err != nil {
+		return fmt.Errorf("failed to open parquet file: %w", err)
+	}
+	defer pf.Close()
+
+	reader, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{}, memory.NewGoAllocator())
+	if err != nil {
+		return fmt.Errorf("failed to create arrow reader: %w", err)
+	}
+
+	schema, err := reader.Schema()
+	if err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	report := &describeReport{
+		NumRows:      pf.NumRows(),
+		NumRowGroups: pf.NumRowGroups(),
+	}
+	for _, f := range schema.Fields() {
+		report.Schema = append(report.Schema, schemaField{Name: f.Name, Type: f.Type.String()})
+	}
+
+	if blob, ok := geoMetadataJSON(schema); ok {
+		var meta geoMeta
+		if err := json.Unmarshal([]byte(blob), &meta); err == nil {
+			if col, ok := meta.Columns[meta.PrimaryColumn]; ok {
+				report.CRS = crsName(col.CRS)
+			}
+		}
+	}
+
+	if err := describeRecords(reader, report); err != nil {
+		return fmt.Errorf("failed to scan records: %w", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// crsName extracts a short name from a GeoParquet "crs" value, which may
+// be a plain string identifier or a PROJJSON object carrying a "name" key.
+func crsName(crs any) string {
+	switch v := crs.(type) {
+	case string:
+		return v
+	case map[string]any:
+		if name, ok := v["name"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// describeRecords streams the file's record batches once, filling in
+// report's RouteIDs/VertexCounts/MValueRange/BoundingBox from whichever of
+// ROUTEID/LAT/LON/MVAL columns are present.
+func describeRecords(reader *pqarrow.FileReader, report *describeReport) error {
+	rr, err := reader.GetRecordReader(context.Background(), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer rr.Release()
+
+	var routeOrder []string
+	counts := make(map[string]int64)
+	mvalMin, mvalMax := math.Inf(1), math.Inf(-1)
+	lonMin, lonMax, latMin, latMax := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	haveMVal, haveBBox := false, false
+
+	for rr.Next() {
+		rec := rr.RecordBatch()
+		schema := rec.Schema()
+
+		if idx := schema.FieldIndices("ROUTEID"); len(idx) > 0 {
+			for _, id := range floatColumnAsStrings(rec, idx[0]) {
+				if _, seen := counts[id]; !seen {
+					routeOrder = append(routeOrder, id)
+				}
+				counts[id]++
+			}
+		}
+
+		if idx := schema.FieldIndices("MVAL"); len(idx) > 0 {
+			if col, ok := rec.Column(idx[0]).(*array.Float64); ok {
+				haveMVal = true
+				for i := 0; i < col.Len(); i++ {
+					if col.IsNull(i) {
+						continue
+					}
+					mvalMin = math.Min(mvalMin, col.Value(i))
+					mvalMax = math.Max(mvalMax, col.Value(i))
+				}
+			}
+		}
+
+		latIdx := schema.FieldIndices("LAT")
+		lonIdx := schema.FieldIndices("LON")
+		if len(latIdx) > 0 && len(lonIdx) > 0 {
+			lat, latOK := rec.Column(latIdx[0]).(*array.Float64)
+			lon, lonOK := rec.Column(lonIdx[0]).(*array.Float64)
+			if latOK && lonOK {
+				haveBBox = true
+				for i := 0; i < lat.Len(); i++ {
+					if lat.IsNull(i) || lon.IsNull(i) {
+						continue
+					}
+					latMin = math.Min(latMin, lat.Value(i))
+					latMax = math.Max(latMax, lat.Value(i))
+					lonMin = math.Min(lonMin, lon.Value(i))
+					lonMax = math.Max(lonMax, lon.Value(i))
+				}
+			}
+		}
+	}
+	if err := rr.Err(); err != nil {
+		return err
+	}
+
+	if len(routeOrder) > 0 {
+		report.RouteIDs = routeOrder
+		report.VertexCounts = counts
+	}
+	if haveMVal {
+		report.MValueRange = &[2]float64{mvalMin, mvalMax}
+	}
+	if haveBBox {
+		report.BoundingBox = &[4]float64{lonMin, latMin, lonMax, latMax}
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+// Command bmlrs is a small stdin/stdout CLI for validating, describing and
+// converting LRS route files, following the pattern of geo tooling that
+// unifies validate/describe/convert subcommands with "-" meaning
+// stdin/stdout.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "validate":
+		err = runValidate(os.Args[2:])
+	case "describe":
+		err = runDescribe(os.Args[2:])
+	case "convert":
+		err = runConvert(os.Args[2:])
+	case "-h", "--help", "help":
+		usage()
+		return
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bmlrs %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: bmlrs <command> [flags]
+
+commands:
+  validate  --input <file|->                                    validate an LRS parquet file's schema, VERTEX_SEQ, and MVAL
+  describe  --input <file|->                                     describe an LRS parquet file
+  convert   --from <fmt> --to <fmt> --input <file|-> --output <file|-> [--t-srs <wkt>]
+            convert between LRS route formats (from: esri-geojson, rfc7946-geojson, csv)`)
+}
+
+// openInput returns a reader for path, treating "-" as stdin. The caller is
+// responsible for closing the returned file when it isn't stdin.
+func openInput(path string) (*os.File, error) {
+	if path == "-" || path == "" {
+		return os.Stdin, nil
+	}
+	return os.Open(path)
+}
+
+// createOutput returns a writer for path, treating "-" as stdout. The
+// caller is responsible for closing the returned file when it isn't stdout.
+func createOutput(path string) (*os.File, error) {
+	if path == "-" || path == "" {
+		return os.Stdout, nil
+	}
+	return os.Create(path)
+}
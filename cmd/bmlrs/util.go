@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// stageStdinToTempFile copies stdin into a temporary file, needed whenever a
+// command requires random access (e.g. Parquet) that stdin can't provide.
+func stageStdinToTempFile() (string, error) {
+	tmp, err := os.CreateTemp("", "bmlrs_stdin_*.parquet")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, os.Stdin); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+// geoMetadataJSON returns the raw "geo" GeoParquet key/value metadata blob
+// from a schema, if present.
+func geoMetadataJSON(schema *arrow.Schema) (string, bool) {
+	md := schema.Metadata()
+	for i, k := range md.Keys() {
+		if k == "geo" {
+			return md.Values()[i], true
+		}
+	}
+	return "", false
+}
+
+// floatColumnAsStrings renders a column's values as strings, for columns
+// whose concrete Arrow type isn't known ahead of time (e.g. ROUTEID, which
+// may be String or LargeString).
+func floatColumnAsStrings(rec arrow.RecordBatch, colIdx int) []string {
+	col := rec.Column(colIdx)
+	out := make([]string, col.Len())
+	switch c := col.(type) {
+	case *array.String:
+		for i := range out {
+			if !c.IsNull(i) {
+				out[i] = c.Value(i)
+			}
+		}
+	case *array.LargeString:
+		for i := range out {
+			if !c.IsNull(i) {
+				out[i] = c.Value(i)
+			}
+		}
+	default:
+		for i := range out {
+			out[i] = col.ValueStr(i)
+		}
+	}
+	return out
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// requiredValidateColumns are the columns every LRS Parquet point file
+// produced by LRSRoute.Sink must carry.
+var requiredValidateColumns = []string{"LAT", "LON", "MVAL", "VERTEX_SEQ", "ROUTEID"}
+
+// validateIssue flags one problem runValidate found with a route's
+// vertices, naming the offending vertex-sequence range so operators can
+// locate it without re-scanning the file themselves.
+type validateIssue struct {
+	RouteID string  `json:"route_id"`
+	Kind    string  `json:"kind"` // "vertex_seq_gap", "vertex_seq_not_monotonic", "mval_not_monotonic"
+	Detail  string  `json:"detail"`
+	FromSeq float64 `json:"from_seq"`
+	ToSeq   float64 `json:"to_seq"`
+}
+
+// validateReport is runValidate's structured report, emitted as JSON so CI
+// can assert on it directly.
+type validateReport struct {
+	Valid          bool            `json:"valid"`
+	SchemaOK       bool            `json:"schema_ok"`
+	MissingColumns []string        `json:"missing_columns,omitempty"`
+	RouteIDs       []string        `json:"route_ids,omitempty"`
+	Issues         []validateIssue `json:"issues,omitempty"`
+}
+
+// runValidate checks a Parquet file produced by LRSRoute.Sink for the
+// expected LAT/LON/MVAL/VERTEX_SEQ/ROUTEID schema, that VERTEX_SEQ is dense
+// and monotonic per route, and that MVAL is monotonic per route, reporting
+// any offending vertex ranges. Exits non-zero when the file fails any
+// check, after printing the JSON report.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	input := fs.String("input", "-", "input parquet file, - for stdin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := *input
+	if path == "-" {
+		tmp, err := stageStdinToTempFile()
+		if err != nil {
+			return fmt.Errorf("failed to stage stdin: %w", err)
+		}
+		defer os.Remove(tmp)
+		path = tmp
+	}
+
+	pf, err := file.OpenParquetFile(path, false)
+	if err != nil {
+		return fmt.Errorf("failed to open parquet file: %w", err)
+	}
+	defer pf.Close()
+
+	reader, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{}, memory.NewGoAllocator())
+	if err != nil {
+		return fmt.Errorf("failed to create arrow reader: %w", err)
+	}
+
+	schema, err := reader.Schema()
+	if err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	report := &validateReport{SchemaOK: true}
+	for _, col := range requiredValidateColumns {
+		if len(schema.FieldIndices(col)) == 0 {
+			report.SchemaOK = false
+			report.MissingColumns = append(report.MissingColumns, col)
+		}
+	}
+
+	if report.SchemaOK {
+		issues, routeIDs, err := validateVertices(reader)
+		if err != nil {
+			return fmt.Errorf("failed to scan records: %w", err)
+		}
+		report.RouteIDs = routeIDs
+		report.Issues = issues
+	}
+
+	report.Valid = report.SchemaOK && len(report.Issues) == 0
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	if !report.Valid {
+		return fmt.Errorf("validation found issues, see report above")
+	}
+	return nil
+}
+
+// routeVertex is one row's VERTEX_SEQ/MVAL pair, kept in file order.
+type routeVertex struct {
+	seq, mval float64
+}
+
+// validateVertices streams the file's record batches once, grouping rows
+// by ROUTEID in file order, then checks each route's VERTEX_SEQ for
+// density/monotonicity and its MVAL for monotonicity.
+func validateVertices(reader *pqarrow.FileReader) ([]validateIssue, []string, error) {
+	rr, err := reader.GetRecordReader(context.Background(), nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rr.Release()
+
+	var routeOrder []string
+	vertices := make(map[string][]routeVertex)
+
+	for rr.Next() {
+		rec := rr.RecordBatch()
+		schema := rec.Schema()
+
+		routeIDIdx := schema.FieldIndices("ROUTEID")[0]
+		seqIdx := schema.FieldIndices("VERTEX_SEQ")[0]
+		mvalIdx := schema.FieldIndices("MVAL")[0]
+
+		routeIDs := floatColumnAsStrings(rec, routeIDIdx)
+		seqAt, err := seqValueFunc(rec.Column(seqIdx))
+		if err != nil {
+			return nil, nil, fmt.Errorf("VERTEX_SEQ column: %w", err)
+		}
+		mval, ok := rec.Column(mvalIdx).(*array.Float64)
+		if !ok {
+			return nil, nil, fmt.Errorf("MVAL column is not float64")
+		}
+
+		for i, routeID := range routeIDs {
+			if _, seen := vertices[routeID]; !seen {
+				routeOrder = append(routeOrder, routeID)
+			}
+			vertices[routeID] = append(vertices[routeID], routeVertex{seq: seqAt(i), mval: mval.Value(i)})
+		}
+	}
+	if err := rr.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	sort.Strings(routeOrder)
+
+	var issues []validateIssue
+	for _, routeID := range routeOrder {
+		issues = append(issues, validateRouteVertices(routeID, vertices[routeID])...)
+	}
+
+	return issues, routeOrder, nil
+}
+
+// validateRouteVertices checks one route's vertices, in file order, for a
+// dense monotonic VERTEX_SEQ (each step exactly +1 from the last) and a
+// monotonic non-decreasing MVAL, returning one issue per offending step.
+func validateRouteVertices(routeID string, vs []routeVertex) []validateIssue {
+	var issues []validateIssue
+
+	for i := 1; i < len(vs); i++ {
+		prev, cur := vs[i-1], vs[i]
+
+		switch {
+		case cur.seq <= prev.seq:
+			issues = append(issues, validateIssue{
+				RouteID: routeID, Kind: "vertex_seq_not_monotonic",
+				Detail:  fmt.Sprintf("VERTEX_SEQ %v is not greater than the preceding %v", cur.seq, prev.seq),
+				FromSeq: prev.seq, ToSeq: cur.seq,
+			})
+		case cur.seq != prev.seq+1:
+			issues = append(issues, validateIssue{
+				RouteID: routeID, Kind: "vertex_seq_gap",
+				Detail:  fmt.Sprintf("VERTEX_SEQ jumps from %v to %v, expected %v", prev.seq, cur.seq, prev.seq+1),
+				FromSeq: prev.seq, ToSeq: cur.seq,
+			})
+		}
+
+		if cur.mval < prev.mval {
+			issues = append(issues, validateIssue{
+				RouteID: routeID, Kind: "mval_not_monotonic",
+				Detail:  fmt.Sprintf("MVAL decreases from %v to %v between vertex %v and %v", prev.mval, cur.mval, prev.seq, cur.seq),
+				FromSeq: prev.seq, ToSeq: cur.seq,
+			})
+		}
+	}
+
+	return issues
+}
+
+// seqValueFunc returns a function reading col[i] as a float64, supporting
+// the column types VERTEX_SEQ is seen with across this repo (Int32 when
+// built from ESRI GeoJSON/CSV, Float64 elsewhere).
+func seqValueFunc(col arrow.Array) (func(i int) float64, error) {
+	switch c := col.(type) {
+	case *array.Float64:
+		return c.Value, nil
+	case *array.Int32:
+		return func(i int) float64 { return float64(c.Value(i)) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", col)
+	}
+}
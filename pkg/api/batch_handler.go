@@ -0,0 +1,209 @@
+package api
+
+import (
+	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/mvalue"
+	"bm-lrs/pkg/route_event"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// mvalueErrorProperty is the feature property used to report a per-feature
+// failure in partial-success mode, instead of failing the whole request.
+const mvalueErrorProperty = "mvalue_error"
+
+// batchFeatureCollection is a loosely-typed GeoJSON FeatureCollection used
+// to group incoming features by ROUTEID while preserving every other
+// property verbatim.
+type batchFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []map[string]any `json:"features"`
+}
+
+// BatchMValueHandler handles POST requests to calculate M-Value for a
+// GeoJSON FeatureCollection containing points from multiple routes. Features
+// are grouped by their ROUTEID property and processed concurrently, bounded
+// by h.batchWorkers. A feature whose route can't be resolved or whose
+// m-value can't be calculated is returned with its original geometry and an
+// mvalue_error property instead of failing the whole request.
+func (h *APIHandler) BatchMValueHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "only POST method is allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("failed to read request body: %v", err))
+		return
+	}
+	defer r.Body.Close()
+
+	crsParam := r.URL.Query().Get("crs")
+	if crsParam == "" {
+		crsParam = "EPSG:4326"
+	}
+	crsHandle, err := h.registry.Resolve(crsParam)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid crs query parameter: %v", err))
+		return
+	}
+	crs := crsHandle.String()
+
+	if err := h.validateGeoJSON(body); err != nil {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid GeoJSON: %v", err))
+		return
+	}
+
+	var fc batchFeatureCollection
+	if err := json.Unmarshal(body, &fc); err != nil {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse GeoJSON: %v", err))
+		return
+	}
+
+	// Group feature indices by ROUTEID; features without a usable ROUTEID
+	// are reported as errors directly, without a group to process.
+	groups := make(map[string][]int)
+	var ungrouped []int
+	for i, f := range fc.Features {
+		routeID, ok := f["properties"].(map[string]any)["ROUTEID"].(string)
+		if !ok || routeID == "" {
+			ungrouped = append(ungrouped, i)
+			continue
+		}
+		groups[routeID] = append(groups[routeID], i)
+	}
+
+	result := make([]map[string]any, len(fc.Features))
+	for _, i := range ungrouped {
+		result[i] = withError(fc.Features[i], "missing or invalid ROUTEID property")
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, h.batchWorkers)
+
+	for routeID, indices := range groups {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(routeID string, indices []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			features, err := h.processRouteGroup(r.Context(), routeID, fc.Features, indices, crs)
+
+			mu.Lock()
+			defer mu.Unlock()
+			for j, idx := range indices {
+				if err != nil {
+					result[idx] = withError(fc.Features[idx], err.Error())
+					continue
+				}
+				result[idx] = features[j]
+			}
+		}(routeID, indices)
+	}
+	wg.Wait()
+
+	geojsonBytes, err := json.Marshal(map[string]any{
+		"type":     "FeatureCollection",
+		"features": result,
+	})
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, fmt.Sprintf("failed to serialize result to GeoJSON: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(geojsonBytes)
+}
+
+// processRouteGroup runs the single-route M-Value pipeline (the same one
+// CalculateMValueHandler uses) against the subset of features belonging to
+// routeID, returning them in the same order as indices.
+func (h *APIHandler) processRouteGroup(ctx context.Context, routeID string, all []map[string]any, indices []int, crs string) ([]map[string]any, error) {
+	features := make([]map[string]any, len(indices))
+	for j, idx := range indices {
+		features[j] = all[idx]
+	}
+
+	groupBytes, err := json.Marshal(map[string]any{
+		"type":     "FeatureCollection",
+		"features": features,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build per-route FeatureCollection: %w", err)
+	}
+
+	events, err := route_event.NewLRSEventsFromGeoJSON(groupBytes, crs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GeoJSON: %w", err)
+	}
+	defer events.Release()
+	events.SetCRSRegistry(h.registry)
+
+	processedEvents := events
+	if events.GetCRS() != geom.LAMBERT_WKT {
+		transformedGeom, err := events.Registry().Transform(ctx, events, geom.LAMBERT_WKT, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transform projection: %w", err)
+		}
+		defer transformedGeom.Release()
+
+		processedEvents, err = route_event.NewLRSEvents(transformedGeom.GetRecords(), geom.LAMBERT_WKT)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create LRSEvents after transformation: %w", err)
+		}
+	}
+
+	lrs, err := h.repo.GetLatest(ctx, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LRS route for %s: %w", routeID, err)
+	}
+	defer lrs.Release()
+
+	resultEvents, err := mvalue.CalculatePointsMValue(ctx, lrs, *processedEvents, mvalue.WithEngine(h.engine))
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate m-values: %w", err)
+	}
+	defer resultEvents.Release()
+
+	resultBytes, err := resultEvents.ToGeoJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize result to GeoJSON: %w", err)
+	}
+
+	var resultFC batchFeatureCollection
+	if err := json.Unmarshal(resultBytes, &resultFC); err != nil {
+		return nil, fmt.Errorf("failed to parse result GeoJSON: %w", err)
+	}
+	if len(resultFC.Features) != len(features) {
+		return nil, fmt.Errorf("result feature count %d does not match input count %d", len(resultFC.Features), len(features))
+	}
+
+	return resultFC.Features, nil
+}
+
+// withError returns a copy of feature with mvalueErrorProperty set in its
+// properties, leaving geometry and every other property untouched.
+func withError(feature map[string]any, msg string) map[string]any {
+	out := make(map[string]any, len(feature))
+	for k, v := range feature {
+		out[k] = v
+	}
+
+	props, _ := feature["properties"].(map[string]any)
+	newProps := make(map[string]any, len(props)+1)
+	for k, v := range props {
+		newProps[k] = v
+	}
+	newProps[mvalueErrorProperty] = msg
+	out["properties"] = newProps
+
+	return out
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// requestDeadline derives a context bounding a REST request, honoring an
+// X-Request-Deadline header (an RFC3339 timestamp) or, failing that, a
+// ?timeout= query parameter (a Go duration string such as "30s"). If
+// neither is set, ctx is r.Context() unmodified and cancel is a no-op.
+// The header takes precedence, mirroring DoExchange's own deadline_unix_ms
+// AppMetadata field taking precedence over the server's configured
+// timeouts on the Flight side.
+func requestDeadline(r *http.Request) (ctx context.Context, cancel context.CancelFunc, err error) {
+	if v := r.Header.Get("X-Request-Deadline"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid X-Request-Deadline header: %v", err)
+		}
+		ctx, cancel := context.WithDeadline(r.Context(), t)
+		return ctx, cancel, nil
+	}
+
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid timeout query parameter: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		return ctx, cancel, nil
+	}
+
+	return r.Context(), func() {}, nil
+}
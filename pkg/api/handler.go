@@ -2,6 +2,7 @@ package api
 
 import (
 	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/jobs"
 	"bm-lrs/pkg/mvalue"
 	"bm-lrs/pkg/projection"
 	"bm-lrs/pkg/route"
@@ -9,19 +10,79 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"sync"
 )
 
+// defaultBatchWorkers is the number of routes processed concurrently by
+// BatchMValueHandler when no WithBatchWorkers option is supplied.
+const defaultBatchWorkers = 4
+
 // APIHandler handles REST API requests for M-Value calculation
 type APIHandler struct {
-	repo *route.LRSRouteRepository
+	repo         *route.LRSRouteRepository
+	batchWorkers int
+	registry     *projection.Registry
+	engine       mvalue.Engine
+
+	jobsDir      string
+	jobQueueOnce sync.Once
+	jobs         *jobs.Queue
+	jobQueueErr  error
+}
+
+// APIHandlerOption configures optional behavior of APIHandler.
+type APIHandlerOption func(*APIHandler)
+
+// WithBatchWorkers sets the number of routes BatchMValueHandler processes
+// concurrently. Defaults to defaultBatchWorkers.
+func WithBatchWorkers(n int) APIHandlerOption {
+	return func(h *APIHandler) {
+		h.batchWorkers = n
+	}
+}
+
+// WithProjectionRegistry sets the CRS registry used to resolve the crs
+// query parameter and any PROJJSON carried in request bodies. Defaults to
+// projection.DefaultRegistry.
+func WithProjectionRegistry(r *projection.Registry) APIHandlerOption {
+	return func(h *APIHandler) {
+		h.registry = r
+	}
+}
+
+// WithEngine selects the mvalue.Engine used to calculate M-Values.
+// Defaults to mvalue.EngineDuckDB; pass mvalue.EngineGeodesic where the
+// spatial extension can't be installed.
+func WithEngine(e mvalue.Engine) APIHandlerOption {
+	return func(h *APIHandler) {
+		h.engine = e
+	}
+}
+
+// WithJobsDir makes the handler persist job metadata under dir instead of
+// a process-lifetime temporary directory, so jobs submitted via
+// CreateMValueJobHandler survive a restart.
+func WithJobsDir(dir string) APIHandlerOption {
+	return func(h *APIHandler) {
+		h.jobsDir = dir
+	}
 }
 
 // NewAPIHandler creates a new APIHandler
-func NewAPIHandler(repo *route.LRSRouteRepository) *APIHandler {
-	return &APIHandler{
-		repo: repo,
+func NewAPIHandler(repo *route.LRSRouteRepository, opts ...APIHandlerOption) *APIHandler {
+	h := &APIHandler{
+		repo:         repo,
+		batchWorkers: defaultBatchWorkers,
+		registry:     projection.DefaultRegistry,
+		engine:       mvalue.EngineDuckDB,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // CalculateMValueRequest represents the request for M-Value calculation
@@ -41,6 +102,18 @@ func (h *APIHandler) CalculateMValueHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// A per-request deadline, set via the X-Request-Deadline header (an
+	// RFC3339 timestamp) or a ?timeout= query parameter (a Go duration
+	// string such as "30s"), bounds repo.GetLatest, the CRS transform and
+	// the M-Value calculation below, so a client isn't stuck waiting
+	// indefinitely on a batch that's too large to finish in time.
+	ctx, cancel, err := requestDeadline(r)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer cancel()
+
 	// Read request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -49,10 +122,31 @@ func (h *APIHandler) CalculateMValueHandler(w http.ResponseWriter, r *http.Reque
 	}
 	defer r.Body.Close()
 
-	// Get CRS from query parameter, default to EPSG:4326
-	crs := r.URL.Query().Get("crs")
-	if crs == "" {
-		crs = "EPSG:4326"
+	// Get CRS from query parameter, default to EPSG:4326. The registry
+	// accepts bare EPSG codes, OGC URNs, WKT2, and PROJJSON alike; a
+	// request body that itself declares a "crs" member (including as
+	// PROJJSON) takes precedence over this default once parsed below.
+	crsParam := r.URL.Query().Get("crs")
+	if crsParam == "" {
+		crsParam = "EPSG:4326"
+	}
+	crsHandle, err := h.registry.Resolve(crsParam)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid crs query parameter: %v", err))
+		return
+	}
+	crs := crsHandle.String()
+
+	// Detect whether the body is ESRI JSON or RFC 7946 GeoJSON so callers
+	// can POST either dialect without special-casing the request.
+	dialect, err := route.DetectGeoJSONDialect(body)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("failed to detect GeoJSON dialect: %v", err))
+		return
+	}
+	if dialect == route.DialectESRI {
+		h.sendError(w, http.StatusBadRequest, "ESRI JSON points are not supported by this endpoint, submit a RFC 7946 FeatureCollection")
+		return
 	}
 
 	// Validate GeoJSON structure
@@ -68,20 +162,49 @@ func (h *APIHandler) CalculateMValueHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	defer events.Release()
+	events.SetCRSRegistry(h.registry)
+
+	// ?progress=1 interleaves NDJSON progress frames ahead of the final
+	// GeoJSON payload as successive parts of a multipart/mixed response,
+	// so a client can render progress instead of waiting silently for the
+	// whole calculation to finish. Past this point errors can no longer
+	// use h.sendError, since the response status and multipart headers
+	// are already committed; they're reported as a trailing error part.
+	var mw *multipart.Writer
+	var prog *ndjsonProgress
+	if r.URL.Query().Get("progress") == "1" {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			h.sendError(w, http.StatusInternalServerError, "streaming progress requires a flushable response writer")
+			return
+		}
+
+		mw = multipart.NewWriter(w)
+		w.Header().Set("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%s", mw.Boundary()))
+		w.WriteHeader(http.StatusOK)
+		defer mw.Close()
+
+		progPart, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/x-ndjson"}})
+		if err != nil {
+			return
+		}
+		prog = newNDJSONProgress(progPart, flusher)
+	}
 
 	// Transform to Lambert CRS if needed
+	prog.SetStage("transforming")
 	var processedEvents *route_event.LRSEvents
 	if events.GetCRS() != geom.LAMBERT_WKT {
-		transformedGeom, err := projection.Transform(events, geom.LAMBERT_WKT, false)
+		transformedGeom, err := events.Registry().Transform(ctx, events, geom.LAMBERT_WKT, false)
 		if err != nil {
-			h.sendError(w, http.StatusInternalServerError, fmt.Sprintf("failed to transform projection: %v", err))
+			h.calculateMValueError(w, mw, http.StatusInternalServerError, fmt.Sprintf("failed to transform projection: %v", err))
 			return
 		}
 		defer transformedGeom.Release()
 
 		processedEvents, err = route_event.NewLRSEvents(transformedGeom.GetRecords(), geom.LAMBERT_WKT)
 		if err != nil {
-			h.sendError(w, http.StatusInternalServerError, fmt.Sprintf("failed to create LRSEvents after transformation: %v", err))
+			h.calculateMValueError(w, mw, http.StatusInternalServerError, fmt.Sprintf("failed to create LRSEvents after transformation: %v", err))
 			return
 		}
 	} else {
@@ -91,40 +214,72 @@ func (h *APIHandler) CalculateMValueHandler(w http.ResponseWriter, r *http.Reque
 	// Get Route IDs from events
 	routeIDs := processedEvents.GetRouteIDs()
 	if len(routeIDs) == 0 {
-		h.sendError(w, http.StatusBadRequest, "no ROUTEID found in GeoJSON properties")
+		h.calculateMValueError(w, mw, http.StatusBadRequest, "no ROUTEID found in GeoJSON properties")
 		return
 	}
 
 	// For now, process the first route (could be extended to handle multiple routes)
+	prog.SetStage("loading_route")
 	routeID := routeIDs[0]
-	lrs, err := h.repo.GetLatest(r.Context(), routeID)
+	lrs, err := h.repo.GetLatest(ctx, routeID)
 	if err != nil {
-		h.sendError(w, http.StatusNotFound, fmt.Sprintf("failed to get LRS route for %s: %v", routeID, err))
+		h.calculateMValueError(w, mw, http.StatusNotFound, fmt.Sprintf("failed to get LRS route for %s: %v", routeID, err))
 		return
 	}
 	defer lrs.Release()
+	prog.Add(1)
 
 	// Calculate M-Values
-	resultEvents, err := mvalue.CalculatePointsMValue(r.Context(), lrs, *processedEvents)
+	prog.SetStage("calculating")
+	resultEvents, err := mvalue.CalculatePointsMValue(ctx, lrs, *processedEvents, mvalue.WithEngine(h.engine), mvalue.WithProgress(prog.asProgress()))
 	if err != nil {
-		h.sendError(w, http.StatusInternalServerError, fmt.Sprintf("failed to calculate m-values: %v", err))
+		h.calculateMValueError(w, mw, http.StatusInternalServerError, fmt.Sprintf("failed to calculate m-values: %v", err))
 		return
 	}
 	defer resultEvents.Release()
 
 	// Convert result to GeoJSON
+	prog.SetStage("serializing")
 	geojsonBytes, err := resultEvents.ToGeoJSON()
 	if err != nil {
-		h.sendError(w, http.StatusInternalServerError, fmt.Sprintf("failed to serialize result to GeoJSON: %v", err))
+		h.calculateMValueError(w, mw, http.StatusInternalServerError, fmt.Sprintf("failed to serialize result to GeoJSON: %v", err))
 		return
 	}
 
 	// Send response
+	if mw != nil {
+		resultPart, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+		if err != nil {
+			return
+		}
+		resultPart.Write(geojsonBytes)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(geojsonBytes)
 }
 
+// calculateMValueError reports an error from CalculateMValueHandler. In
+// the ordinary (non-streaming) path it behaves like h.sendError; once a
+// progress=1 response has started, the status and Content-Type are
+// already committed, so the error is instead reported as a trailing
+// "application/json" part carrying an ErrorResponse, mirroring the
+// final-payload part's shape.
+func (h *APIHandler) calculateMValueError(w http.ResponseWriter, mw *multipart.Writer, statusCode int, message string) {
+	if mw == nil {
+		h.sendError(w, statusCode, message)
+		return
+	}
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+	if err != nil {
+		return
+	}
+	json.NewEncoder(part).Encode(ErrorResponse{Error: message})
+}
+
 // validateGeoJSON validates the basic GeoJSON structure
 func (h *APIHandler) validateGeoJSON(data []byte) error {
 	var fc struct {
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/jobs"
+	"bm-lrs/pkg/mvalue"
+	"bm-lrs/pkg/route_event"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// jobQueue lazily creates h's jobs.Queue on first use, defaulting to a
+// fresh temporary directory when WithJobsDir wasn't supplied.
+func (h *APIHandler) jobQueue() (*jobs.Queue, error) {
+	h.jobQueueOnce.Do(func() {
+		dir := h.jobsDir
+		if dir == "" {
+			var err error
+			dir, err = os.MkdirTemp("", "lrs_api_jobs_*")
+			if err != nil {
+				h.jobQueueErr = fmt.Errorf("failed to create jobs directory: %w", err)
+				return
+			}
+		}
+		h.jobs, h.jobQueueErr = jobs.NewQueue(dir)
+	})
+	return h.jobs, h.jobQueueErr
+}
+
+// CreateMValueJobHandler handles POST requests that submit an M-Value
+// calculation as an asynchronous job instead of computing it inline, for
+// request bodies too large to comfortably hold open one HTTP connection
+// for. It accepts the same GeoJSON FeatureCollection body as
+// CalculateMValueHandler and responds with a job ID immediately; poll
+// GET /api/v1/jobs/{id} (JobsHandler) for progress and fetch
+// GET /api/v1/jobs/{id}/result once State is jobs.StateDone.
+func (h *APIHandler) CreateMValueJobHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "only POST method is allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("failed to read request body: %v", err))
+		return
+	}
+	defer r.Body.Close()
+
+	crsParam := r.URL.Query().Get("crs")
+	if crsParam == "" {
+		crsParam = "EPSG:4326"
+	}
+	crsHandle, err := h.registry.Resolve(crsParam)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid crs query parameter: %v", err))
+		return
+	}
+	crs := crsHandle.String()
+
+	if err := h.validateGeoJSON(body); err != nil {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid GeoJSON: %v", err))
+		return
+	}
+
+	events, err := route_event.NewLRSEventsFromGeoJSON(body, crs)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse GeoJSON: %v", err))
+		return
+	}
+	events.SetCRSRegistry(h.registry)
+
+	processedEvents := events
+	if events.GetCRS() != geom.LAMBERT_WKT {
+		transformedGeom, err := events.Registry().Transform(r.Context(), events, geom.LAMBERT_WKT, false)
+		if err != nil {
+			events.Release()
+			h.sendError(w, http.StatusInternalServerError, fmt.Sprintf("failed to transform projection: %v", err))
+			return
+		}
+		defer transformedGeom.Release()
+		events.Release()
+
+		processedEvents, err = route_event.NewLRSEvents(transformedGeom.GetRecords(), geom.LAMBERT_WKT)
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, fmt.Sprintf("failed to create LRSEvents after transformation: %v", err))
+			return
+		}
+	}
+
+	routeIDs := processedEvents.GetRouteIDs()
+	if len(routeIDs) == 0 {
+		processedEvents.Release()
+		h.sendError(w, http.StatusBadRequest, "no ROUTEID found in GeoJSON properties")
+		return
+	}
+
+	routeID := routeIDs[0]
+	lrs, err := h.repo.GetLatest(r.Context(), routeID)
+	if err != nil {
+		processedEvents.Release()
+		h.sendError(w, http.StatusNotFound, fmt.Sprintf("failed to get LRS route for %s: %v", routeID, err))
+		return
+	}
+
+	queue, err := h.jobQueue()
+	if err != nil {
+		lrs.Release()
+		processedEvents.Release()
+		h.sendError(w, http.StatusInternalServerError, fmt.Sprintf("failed to initialize job queue: %v", err))
+		return
+	}
+
+	engine := h.engine
+	task := func(ctx context.Context, report func(done, total int64)) (string, error) {
+		defer lrs.Release()
+		defer processedEvents.Release()
+
+		resultEvents, err := mvalue.CalculatePointsMValue(ctx, lrs, *processedEvents, mvalue.WithEngine(engine))
+		if err != nil {
+			return "", fmt.Errorf("failed to calculate m-values: %w", err)
+		}
+
+		if err := resultEvents.Sink(); err != nil {
+			resultEvents.Release()
+			return "", fmt.Errorf("failed to materialize result: %w", err)
+		}
+		uri := resultEvents.GetSourceFile()
+		if uri == nil {
+			resultEvents.Release()
+			return "", fmt.Errorf("sink produced no source file")
+		}
+
+		return *uri, nil
+	}
+
+	jobID, err := queue.Submit(task)
+	if err != nil {
+		lrs.Release()
+		processedEvents.Release()
+		h.sendError(w, http.StatusInternalServerError, fmt.Sprintf("failed to submit job: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"job_id": jobID})
+}
+
+// JobsHandler dispatches GET /api/v1/jobs/{id} and
+// GET /api/v1/jobs/{id}/result, since mux.HandleFunc only matches literal
+// or prefix paths and the repo has no path-parameter router.
+func (h *APIHandler) JobsHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/v1/jobs/")
+	if rest == "" || rest == r.URL.Path {
+		h.sendError(w, http.StatusNotFound, "missing job id")
+		return
+	}
+
+	parts := strings.SplitN(rest, "/", 2)
+	id := parts[0]
+	switch {
+	case len(parts) == 1:
+		h.jobStatus(w, r, id)
+	case parts[1] == "result":
+		h.jobResult(w, r, id)
+	default:
+		h.sendError(w, http.StatusNotFound, "unknown jobs sub-route")
+	}
+}
+
+// jobStatus writes the JSON-encoded jobs.Job for id.
+func (h *APIHandler) jobStatus(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, http.StatusMethodNotAllowed, "only GET method is allowed")
+		return
+	}
+
+	queue, err := h.jobQueue()
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, fmt.Sprintf("failed to initialize job queue: %v", err))
+		return
+	}
+
+	job, err := queue.Get(id)
+	if err != nil {
+		h.sendError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// jobResult streams the job's materialized Parquet result once it's done.
+func (h *APIHandler) jobResult(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, http.StatusMethodNotAllowed, "only GET method is allowed")
+		return
+	}
+
+	queue, err := h.jobQueue()
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, fmt.Sprintf("failed to initialize job queue: %v", err))
+		return
+	}
+
+	job, err := queue.Get(id)
+	if err != nil {
+		h.sendError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if job.State != jobs.StateDone {
+		h.sendError(w, http.StatusConflict, fmt.Sprintf("job %q is %s, not done", job.ID, job.State))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apache.parquet")
+	http.ServeFile(w, r, job.ResultURI)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bm-lrs/pkg/progress"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// ndjsonProgress reports progress.Progress updates as newline-delimited
+// JSON objects written to a streaming multipart part (see
+// CalculateMValueHandler's progress=1 mode), flushing after every line so
+// a Transfer-Encoding: chunked client sees each stage as it happens rather
+// than buffered until the final payload part.
+type ndjsonProgress struct {
+	w           io.Writer
+	flusher     http.Flusher
+	stage       string
+	done, total int64
+}
+
+// newNDJSONProgress reports progress as NDJSON lines written to w,
+// flushed via flusher after each line.
+func newNDJSONProgress(w io.Writer, flusher http.Flusher) *ndjsonProgress {
+	return &ndjsonProgress{w: w, flusher: flusher}
+}
+
+// SetStage moves to a new named phase, resetting the done/total counters,
+// and reports it immediately. A nil receiver is a no-op, so callers can
+// hold a *ndjsonProgress that's nil when progress=1 wasn't requested.
+func (p *ndjsonProgress) SetStage(stage string) {
+	if p == nil {
+		return
+	}
+	p.stage, p.done, p.total = stage, 0, 0
+	p.send()
+}
+
+func (p *ndjsonProgress) Start(total int64) {
+	if p == nil {
+		return
+	}
+	p.total, p.done = total, 0
+	p.send()
+}
+
+func (p *ndjsonProgress) Add(n int64) {
+	if p == nil {
+		return
+	}
+	p.done += n
+	p.send()
+}
+
+func (p *ndjsonProgress) Finish() {
+	if p == nil {
+		return
+	}
+	p.send()
+}
+
+// asProgress returns p as a progress.Progress, substituting progress.NoOp
+// when p is nil so callers don't need to branch on progress=1 themselves.
+func (p *ndjsonProgress) asProgress() progress.Progress {
+	if p == nil {
+		return progress.NoOp
+	}
+	return p
+}
+
+func (p *ndjsonProgress) send() {
+	line, err := json.Marshal(struct {
+		Stage string `json:"stage"`
+		Done  int64  `json:"done"`
+		Total int64  `json:"total"`
+	}{p.stage, p.done, p.total})
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+	if _, err := p.w.Write(line); err != nil {
+		return
+	}
+	if p.flusher != nil {
+		p.flusher.Flush()
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bm-lrs/pkg/mvalue"
 	"bm-lrs/pkg/route"
 	"fmt"
 	"log"
@@ -12,30 +13,53 @@ type APIServer struct {
 	repo   *route.LRSRouteRepository
 	port   int
 	server *http.Server
+	engine mvalue.Engine
+}
+
+// APIServerOption configures optional behavior of APIServer.
+type APIServerOption func(*APIServer)
+
+// WithServerEngine selects the mvalue.Engine the server's APIHandler uses
+// to calculate M-Values. Defaults to mvalue.EngineDuckDB; pass
+// mvalue.EngineGeodesic where the spatial extension can't be installed.
+func WithServerEngine(e mvalue.Engine) APIServerOption {
+	return func(s *APIServer) {
+		s.engine = e
+	}
 }
 
 // NewAPIServer creates a new API server instance
-func NewAPIServer(repo *route.LRSRouteRepository, port int) *APIServer {
-	return &APIServer{
-		repo: repo,
-		port: port,
+func NewAPIServer(repo *route.LRSRouteRepository, port int, opts ...APIServerOption) *APIServer {
+	s := &APIServer{
+		repo:   repo,
+		port:   port,
+		engine: mvalue.EngineDuckDB,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 // Start starts the REST API server
 func (s *APIServer) Start() error {
-	handler := NewAPIHandler(s.repo)
+	handler := NewAPIHandler(s.repo, WithEngine(s.engine))
 
 	mux := http.NewServeMux()
 
 	// Register routes
 	mux.HandleFunc("/api/v1/calculate-mvalue", handler.CalculateMValueHandler)
+	mux.HandleFunc("/api/v1/mvalue/batch", handler.BatchMValueHandler)
+
+	// Async job submission/polling for large M-Value requests; jobs.* is a
+	// literal path so it wins over the /api/v1/jobs/ prefix below.
+	mux.HandleFunc("/api/v1/jobs/calculate-mvalue", handler.CreateMValueJobHandler)
+	mux.HandleFunc("/api/v1/jobs/", handler.JobsHandler)
 
-	// Health check endpoint
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok"}`))
-	})
+	// Liveness/readiness endpoints, backed by route.HealthChecker.
+	health := route.HealthHandler(s.repo)
+	mux.Handle("/healthz/live", health)
+	mux.Handle("/healthz/ready", health)
 
 	s.server = &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.port),
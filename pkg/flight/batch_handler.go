@@ -1,6 +1,7 @@
 package flight
 
 import (
+	"bm-lrs/pkg/progress"
 	"context"
 	"fmt"
 	"log"
@@ -23,20 +24,84 @@ type ParquetBatchHandler struct {
 	schema       *arrow.Schema
 	totalSize    int64
 	batchIndex   int
+
+	geoParquet bool
+	latCol     string
+	lonCol     string
+	bounds     bbox
+
+	progress progress.Progress
+}
+
+// bbox accumulates a bounding box across AddRecordBatch calls.
+type bbox struct {
+	minX, minY, maxX, maxY float64
+	set                    bool
+}
+
+func (b *bbox) extend(x, y float64) {
+	if !b.set {
+		b.minX, b.maxX = x, x
+		b.minY, b.maxY = y, y
+		b.set = true
+		return
+	}
+	if x < b.minX {
+		b.minX = x
+	}
+	if x > b.maxX {
+		b.maxX = x
+	}
+	if y < b.minY {
+		b.minY = y
+	}
+	if y > b.maxY {
+		b.maxY = y
+	}
+}
+
+// BatchHandlerOption configures optional behavior of ParquetBatchHandler.
+type BatchHandlerOption func(*ParquetBatchHandler)
+
+// WithGeoParquetMetadata enables accumulating a bbox across AddRecordBatch
+// calls and writing the standard GeoParquet "geo" file-level key/value
+// metadata into the file produced by MergeParquetFiles. latCol/lonCol name
+// the columns used to compute the bbox.
+func WithGeoParquetMetadata(latCol, lonCol string) BatchHandlerOption {
+	return func(h *ParquetBatchHandler) {
+		h.geoParquet = true
+		h.latCol = latCol
+		h.lonCol = lonCol
+	}
+}
+
+// WithProgress reports row counts written via AddRecordBatch and file
+// counts merged via MergeParquetFiles to p. Defaults to progress.NoOp.
+func WithProgress(p progress.Progress) BatchHandlerOption {
+	return func(h *ParquetBatchHandler) {
+		h.progress = p
+	}
 }
 
 // NewParquetBatchHandler creates a new handler for managing parquet file batches
-func NewParquetBatchHandler() (*ParquetBatchHandler, error) {
+func NewParquetBatchHandler(opts ...BatchHandlerOption) (*ParquetBatchHandler, error) {
 	tempDir, err := os.MkdirTemp("", "lrs_flight_batch_*")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temporary directory: %v", err)
 	}
 
-	return &ParquetBatchHandler{
+	h := &ParquetBatchHandler{
 		tempDir:    tempDir,
 		totalSize:  0,
 		batchIndex: 0,
-	}, nil
+		progress:   progress.NoOp,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h, nil
 }
 
 // AddRecordBatch adds a record batch to the handler. If the total size exceeds 1GB,
@@ -53,6 +118,12 @@ func (h *ParquetBatchHandler) AddRecordBatch(rec arrow.RecordBatch) error {
 	batchSize := rec.NumRows()
 	h.totalSize += batchSize
 
+	if h.geoParquet {
+		if err := h.extendBounds(rec); err != nil {
+			return fmt.Errorf("failed to accumulate geo bbox: %v", err)
+		}
+	}
+
 	// Create a temporary file for this batch
 	h.batchIndex++
 	filePath := filepath.Join(h.tempDir, fmt.Sprintf("batch_%d.parquet", h.batchIndex))
@@ -78,6 +149,7 @@ func (h *ParquetBatchHandler) AddRecordBatch(rec arrow.RecordBatch) error {
 		return fmt.Errorf("failed to write record batch: %v", err)
 	}
 	log.Printf("Wrote batch %d with %d rows to %s", h.batchIndex, batchSize, filePath)
+	h.progress.Add(batchSize)
 
 	h.currentFiles = append(h.currentFiles, filePath)
 
@@ -124,9 +196,19 @@ func (h *ParquetBatchHandler) MergeParquetFiles() (string, error) {
 		break
 	}
 
+	mergedSchema := h.schema
+	if h.geoParquet {
+		meta, err := buildGeoParquetMeta(h.bounds)
+		if err != nil {
+			return "", fmt.Errorf("failed to build geo metadata: %v", err)
+		}
+		fields := append(append([]arrow.Field{}, h.schema.Fields()...), arrow.Field{Name: geometryColumn, Type: arrow.BinaryTypes.Binary})
+		mergedSchema = arrow.NewSchema(fields, meta)
+	}
+
 	// Create writer for merged file using the schema from the first file (with metadata)
 	mergedWriter, err := pqarrow.NewFileWriter(
-		h.schema,
+		mergedSchema,
 		mergedFile,
 		parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy)),
 		pqarrow.DefaultWriterProps(),
@@ -136,6 +218,8 @@ func (h *ParquetBatchHandler) MergeParquetFiles() (string, error) {
 	}
 	defer mergedWriter.Close()
 
+	h.progress.Start(int64(len(h.currentFiles)))
+
 	// Read and write all batches from all files
 	for _, filePath := range h.currentFiles {
 		// Use file.OpenParquetFile to open the parquet file
@@ -153,6 +237,15 @@ func (h *ParquetBatchHandler) MergeParquetFiles() (string, error) {
 			return "", fmt.Errorf("failed to create arrow reader for %s: %v", filePath, err)
 		}
 
+		if h.geoParquet {
+			if fileSchema, _ := reader.Schema(); fileSchema != nil {
+				if err := checkGeoCompat(mergedSchema, fileSchema); err != nil {
+					pf.Close()
+					return "", err
+				}
+			}
+		}
+
 		// Create a record reader for this file
 		recordReader, err := reader.GetRecordReader(context.Background(), nil, nil)
 		if err != nil {
@@ -163,7 +256,21 @@ func (h *ParquetBatchHandler) MergeParquetFiles() (string, error) {
 		// Read all records from this file and write to merged file
 		for recordReader.Next() {
 			rec := recordReader.RecordBatch()
-			if err := mergedWriter.WriteBuffered(rec); err != nil {
+			toWrite := rec
+			if h.geoParquet {
+				geomRec, err := appendGeometryColumn(rec, mergedSchema, h.latCol, h.lonCol)
+				if err != nil {
+					recordReader.Release()
+					pf.Close()
+					return "", fmt.Errorf("failed to build geometry column for %s: %v", filePath, err)
+				}
+				toWrite = geomRec
+			}
+			err := mergedWriter.WriteBuffered(toWrite)
+			if toWrite != rec {
+				toWrite.Release()
+			}
+			if err != nil {
 				recordReader.Release()
 				pf.Close()
 				return "", fmt.Errorf("failed to write record to merged file: %v", err)
@@ -178,8 +285,11 @@ func (h *ParquetBatchHandler) MergeParquetFiles() (string, error) {
 
 		recordReader.Release()
 		pf.Close()
+		h.progress.Add(1)
 	}
 
+	h.progress.Finish()
+
 	return mergedPath, nil
 }
 
@@ -0,0 +1,184 @@
+package flight
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DeadlineOptions configures per-direction read/write deadlines applied to
+// every DoExchange stream handled by LRSFlightServer.
+type DeadlineOptions struct {
+	// ReadTimeout bounds how long a single Recv on the stream may block.
+	// Zero means no deadline.
+	ReadTimeout time.Duration
+	// WriteTimeout bounds how long a single Send on the stream may block.
+	// Zero means no deadline.
+	WriteTimeout time.Duration
+}
+
+// DefaultDeadlineOptions reads LRS_FLIGHT_READ_TIMEOUT and
+// LRS_FLIGHT_WRITE_TIMEOUT (seconds) from the environment, defaulting to no
+// deadline when unset or invalid.
+func DefaultDeadlineOptions() DeadlineOptions {
+	return DeadlineOptions{
+		ReadTimeout:  envSeconds("LRS_FLIGHT_READ_TIMEOUT"),
+		WriteTimeout: envSeconds("LRS_FLIGHT_WRITE_TIMEOUT"),
+	}
+}
+
+func envSeconds(name string) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0
+	}
+	secs, err := strconv.Atoi(v)
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// deadlineTimer tracks a single direction's deadline, following the pattern
+// used by google/netstack's gonet.deadlineTimer: a cancel channel that is
+// closed once the deadline fires, and is swapped out for a fresh channel
+// when the deadline is reset after already firing.
+type deadlineTimer struct {
+	mu       sync.Mutex
+	timer    *time.Timer
+	cancel   chan struct{}
+	deadline time.Time
+}
+
+func newDeadlineTimer() *deadlineTimer {
+	return &deadlineTimer{cancel: make(chan struct{})}
+}
+
+// set arms (or disarms, for a zero time) the deadline.
+func (d *deadlineTimer) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.deadline = t
+
+	select {
+	case <-d.cancel:
+		// Already fired; replace so future waiters don't see a stale close.
+		d.cancel = make(chan struct{})
+	default:
+	}
+
+	if t.IsZero() {
+		return
+	}
+
+	dur := time.Until(t)
+	if dur <= 0 {
+		close(d.cancel)
+		return
+	}
+
+	cancel := d.cancel
+	d.timer = time.AfterFunc(dur, func() {
+		close(cancel)
+	})
+}
+
+// chanCancel returns the current cancel channel; it is closed once the deadline
+// fires.
+func (d *deadlineTimer) chanCancel() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cancel
+}
+
+// deadlineAction carries an optional explicit deadline negotiated in the
+// first AppMetadata message of a DoExchange stream.
+type deadlineAction struct {
+	DeadlineUnixMs int64 `json:"deadline_unix_ms"`
+}
+
+// deadlineExchangeStream wraps a DoExchange server stream with per-direction
+// deadlines. Every Recv/Send races against the relevant cancel channel and
+// returns a context.DeadlineExceeded-derived gRPC status on timeout.
+type deadlineExchangeStream struct {
+	flight.FlightService_DoExchangeServer
+	readDeadline  *deadlineTimer
+	writeDeadline *deadlineTimer
+}
+
+func newDeadlineExchangeStream(stream flight.FlightService_DoExchangeServer, opts DeadlineOptions) *deadlineExchangeStream {
+	s := &deadlineExchangeStream{
+		FlightService_DoExchangeServer: stream,
+		readDeadline:                   newDeadlineTimer(),
+		writeDeadline:                  newDeadlineTimer(),
+	}
+	if opts.ReadTimeout > 0 {
+		s.SetReadDeadline(time.Now().Add(opts.ReadTimeout))
+	}
+	if opts.WriteTimeout > 0 {
+		s.SetWriteDeadline(time.Now().Add(opts.WriteTimeout))
+	}
+	return s
+}
+
+// SetReadDeadline arms the read-side deadline; a zero time disarms it.
+func (s *deadlineExchangeStream) SetReadDeadline(t time.Time) {
+	s.readDeadline.set(t)
+}
+
+// SetWriteDeadline arms the write-side deadline; a zero time disarms it.
+func (s *deadlineExchangeStream) SetWriteDeadline(t time.Time) {
+	s.writeDeadline.set(t)
+}
+
+func deadlineExceeded() error {
+	return status.Error(codes.DeadlineExceeded, context.DeadlineExceeded.Error())
+}
+
+// Recv blocks on the underlying stream's Recv in a goroutine and races it
+// against the read deadline's cancel channel.
+func (s *deadlineExchangeStream) Recv() (*flight.FlightData, error) {
+	type result struct {
+		data *flight.FlightData
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := s.FlightService_DoExchangeServer.Recv()
+		done <- result{data, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.data, r.err
+	case <-s.readDeadline.chanCancel():
+		return nil, deadlineExceeded()
+	}
+}
+
+// Send blocks on the underlying stream's Send in a goroutine and races it
+// against the write deadline's cancel channel.
+func (s *deadlineExchangeStream) Send(data *flight.FlightData) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.FlightService_DoExchangeServer.Send(data)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-s.writeDeadline.chanCancel():
+		return deadlineExceeded()
+	}
+}
@@ -0,0 +1,58 @@
+package flight
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadlineTimer(t *testing.T) {
+	t.Run("zero time leaves the cancel channel open", func(t *testing.T) {
+		d := newDeadlineTimer()
+		d.set(time.Time{})
+
+		select {
+		case <-d.chanCancel():
+			t.Fatal("cancel channel closed with no deadline set")
+		default:
+		}
+	})
+
+	t.Run("past deadline closes the cancel channel immediately", func(t *testing.T) {
+		d := newDeadlineTimer()
+		d.set(time.Now().Add(-time.Second))
+
+		select {
+		case <-d.chanCancel():
+		default:
+			t.Fatal("cancel channel not closed for a deadline in the past")
+		}
+	})
+
+	t.Run("deadline fires after the configured duration", func(t *testing.T) {
+		d := newDeadlineTimer()
+		d.set(time.Now().Add(20 * time.Millisecond))
+
+		select {
+		case <-d.chanCancel():
+			t.Fatal("cancel channel closed before the deadline")
+		default:
+		}
+
+		select {
+		case <-d.chanCancel():
+		case <-time.After(200 * time.Millisecond):
+			t.Fatal("cancel channel was not closed after the deadline elapsed")
+		}
+	})
+
+	t.Run("resetting after firing replaces the channel", func(t *testing.T) {
+		d := newDeadlineTimer()
+		d.set(time.Now().Add(-time.Second))
+		fired := d.chanCancel()
+
+		d.set(time.Time{})
+		assert.NotEqual(t, fired, d.chanCancel())
+	})
+}
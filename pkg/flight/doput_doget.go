@@ -0,0 +1,281 @@
+package flight
+
+import (
+	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/mvalue"
+	"bm-lrs/pkg/projection"
+	"bm-lrs/pkg/route"
+	"bm-lrs/pkg/route_event"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// putAction describes the JSON envelope carried in the first DoPut
+// message's FlightDescriptor, mirroring the action envelope DoExchange
+// parses from its own first message.
+type putAction struct {
+	CRS   string `json:"crs"`
+	Async bool   `json:"async"`
+}
+
+// DoPut accepts a stream of raw event RecordBatches (LAT/LON/ROUTEID, ...),
+// builds route_event.LRSEvents directly from them (no GeoJSON round-trip),
+// calculates M-Values, and stores the result under a ticket addressable via
+// DoGet until the client releases it with DoAction("release_ticket").
+//
+// If the action envelope sets "async", the M-Value calculation isn't run
+// on this connection at all: the raw events are stashed under a ticket
+// and the client is expected to hand that ticket to
+// DoAction("submit_job", ...) to run it on the job queue instead, polling
+// DoAction("get_status", ...) and fetching the result with
+// DoAction("fetch_result", ...) once it's done.
+func (s *LRSFlightServer) DoPut(stream flight.FlightService_DoPutServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	crs := "EPSG:4326"
+	var action putAction
+	if first.FlightDescriptor != nil && len(first.FlightDescriptor.Cmd) > 0 {
+		if err := json.Unmarshal(first.FlightDescriptor.Cmd, &action); err == nil && action.CRS != "" {
+			crs = action.CRS
+		}
+	}
+
+	reader, err := flight.NewRecordReader(stream)
+	if err != nil {
+		return err
+	}
+	defer reader.Release()
+
+	var records []arrow.RecordBatch
+	for reader.Next() {
+		rec := reader.RecordBatch()
+		rec.Retain()
+		records = append(records, rec)
+	}
+	if err := reader.Err(); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return status.Error(codes.InvalidArgument, "no record batches received")
+	}
+
+	events, err := route_event.NewLRSEvents(records, crs)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to build LRSEvents from stream: %v", err)
+	}
+
+	if action.Async {
+		ticketID := s.tickets.put(events)
+		return stream.Send(&flight.PutResult{AppMetadata: []byte(ticketID)})
+	}
+
+	resultEvents, err := s.calculateMValueForEvents(stream.Context(), events)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to calculate m-values: %v", err)
+	}
+
+	ticketID := s.tickets.put(resultEvents)
+
+	return stream.Send(&flight.PutResult{AppMetadata: []byte(ticketID)})
+}
+
+// DoGet streams back the RecordBatches addressed by tkt, as produced by a
+// prior DoPut call.
+func (s *LRSFlightServer) DoGet(tkt *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	ticketID := string(tkt.GetTicket())
+
+	events, ok := s.tickets.get(ticketID)
+	if !ok {
+		return status.Errorf(codes.NotFound, "unknown ticket %q", ticketID)
+	}
+
+	records := events.GetRecords()
+	if len(records) == 0 {
+		return status.Errorf(codes.NotFound, "ticket %q has no records", ticketID)
+	}
+
+	writer := flight.NewRecordWriter(stream, ipc.WithSchema(records[0].Schema()))
+	defer writer.Close()
+
+	for _, rec := range records {
+		if err := writer.Write(rec); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extendDeadlineAction is the JSON envelope carried in a
+// DoAction("extend_deadline", ...) request's Body.
+type extendDeadlineAction struct {
+	RequestID      string `json:"request_id"`
+	DeadlineUnixMs int64  `json:"deadline_unix_ms"`
+}
+
+// DoAction handles out-of-band operations. "release_ticket" frees the
+// LRSEvents stored under a DoPut ticket once the client is done with it.
+// "submit_job", "get_status" and "fetch_result" run and poll a
+// long-running M-Value calculation on the job queue instead of holding it
+// on a single streaming connection; see handleSubmitJob. "cancel_job" and
+// "extend_deadline" reach into a DoExchange request that's still running
+// on another connection, addressed by the request ID it reported up front
+// (see DoExchange and requestRegistry).
+func (s *LRSFlightServer) DoAction(action *flight.Action, stream flight.FlightService_DoActionServer) error {
+	switch action.Type {
+	case "release_ticket":
+		s.tickets.release(string(action.Body))
+		return stream.Send(&flight.Result{Body: []byte("ok")})
+	case "submit_job":
+		return s.handleSubmitJob(action, stream)
+	case "get_status":
+		return s.handleJobStatus(action, stream)
+	case "fetch_result":
+		return s.handleFetchResult(action, stream)
+	case "cancel_job":
+		requestID := string(action.Body)
+		req, ok := s.requests.lookup(requestID)
+		if !ok {
+			return status.Errorf(codes.NotFound, "unknown request %q", requestID)
+		}
+		req.Cancel()
+		return stream.Send(&flight.Result{Body: []byte("ok")})
+	case "extend_deadline":
+		var body extendDeadlineAction
+		if err := json.Unmarshal(action.Body, &body); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid extend_deadline body: %v", err)
+		}
+		req, ok := s.requests.lookup(body.RequestID)
+		if !ok {
+			return status.Errorf(codes.NotFound, "unknown request %q", body.RequestID)
+		}
+		req.SetDeadline(time.UnixMilli(body.DeadlineUnixMs))
+		return stream.Send(&flight.Result{Body: []byte("ok")})
+	default:
+		return status.Errorf(codes.Unimplemented, "unsupported action %q", action.Type)
+	}
+}
+
+// ListFlights enumerates one FlightInfo per route ID known to the
+// repository's latest catalog, so clients can discover per-route
+// partitions before fetching them.
+func (s *LRSFlightServer) ListFlights(criteria *flight.Criteria, stream flight.FlightService_ListFlightsServer) error {
+	routeIDs, err := s.repo.ListRouteIDs(stream.Context())
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to list route ids: %v", err)
+	}
+
+	for _, routeID := range routeIDs {
+		info := &flight.FlightInfo{
+			FlightDescriptor: routeFlightDescriptor(routeID),
+			Endpoint: []*flight.FlightEndpoint{
+				{Ticket: &flight.Ticket{Ticket: []byte("route:" + routeID)}},
+			},
+		}
+		if err := stream.Send(info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetFlightInfo resolves a ["routes", routeID] path descriptor into a
+// FlightInfo carrying a "route:"+routeID ticket, after checking that the
+// repository has a latest version of that route. DoGet serves only tickets
+// issued by DoPut and fetch_result, so it answers NotFound for this one.
+func (s *LRSFlightServer) GetFlightInfo(ctx context.Context, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	routeID, err := routeIDFromDescriptor(desc)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if _, err := s.repo.GetLatest(ctx, routeID); err != nil {
+		return nil, status.Errorf(codes.NotFound, "route %q not found: %v", routeID, err)
+	}
+
+	return &flight.FlightInfo{
+		FlightDescriptor: desc,
+		Endpoint: []*flight.FlightEndpoint{
+			{Ticket: &flight.Ticket{Ticket: []byte("route:" + routeID)}},
+		},
+	}, nil
+}
+
+// routeFlightDescriptor builds the ["routes", routeID] path descriptor used
+// to advertise and resolve a per-route partition.
+func routeFlightDescriptor(routeID string) *flight.FlightDescriptor {
+	return &flight.FlightDescriptor{
+		Type: flight.DescriptorPATH,
+		Path: []string{"routes", routeID},
+	}
+}
+
+// routeIDFromDescriptor extracts the routeID from a ["routes", routeID]
+// path descriptor.
+func routeIDFromDescriptor(desc *flight.FlightDescriptor) (string, error) {
+	if desc.GetType() != flight.DescriptorPATH || len(desc.GetPath()) != 2 || desc.GetPath()[0] != "routes" {
+		return "", fmt.Errorf("expected a [\"routes\", routeID] path descriptor")
+	}
+	return desc.GetPath()[1], nil
+}
+
+// calculateMValueForEvents runs the CRS-transform + route-load + M-Value
+// pipeline against events already built from a caller-supplied stream,
+// mirroring handleCalculateMValue's own steps but starting from LRSEvents
+// instead of raw Flight RecordBatches.
+func (s *LRSFlightServer) calculateMValueForEvents(ctx context.Context, events *route_event.LRSEvents) (*route_event.LRSEvents, error) {
+	if events.GetCRS() != geom.LAMBERT_WKT {
+		transformed, err := projection.Transform(ctx, events, geom.LAMBERT_WKT, false)
+		if err != nil {
+			return nil, err
+		}
+		defer transformed.Release()
+
+		var err2 error
+		events, err2 = route_event.NewLRSEvents(transformed.GetRecords(), geom.LAMBERT_WKT)
+		if err2 != nil {
+			return nil, fmt.Errorf("error creating LRSEvents after transformation: %w", err2)
+		}
+	}
+
+	routeIDs := events.GetRouteIDs()
+	if len(routeIDs) == 0 {
+		return nil, fmt.Errorf("no ROUTEID found in records")
+	}
+
+	// Load all LRS routes into a batch, mirroring handleCalculateMValue.
+	routeBatch := &route.LRSRouteBatch{}
+	var lrs *route.LRSRoute
+	routesLoaded := 0
+	for _, routeID := range routeIDs {
+		r, err := s.repo.GetLatest(ctx, routeID)
+		if err != nil {
+			continue
+		}
+		defer r.Release()
+
+		if err := routeBatch.AddRoute(*r); err != nil {
+			continue
+		}
+		lrs = r
+		routesLoaded++
+	}
+
+	if routesLoaded == 0 {
+		return nil, fmt.Errorf("failed to load any LRS routes for %d route IDs", len(routeIDs))
+	}
+
+	return mvalue.CalculatePointsMValue(ctx, lrs, *events, mvalue.WithEngine(s.engine))
+}
@@ -0,0 +1,362 @@
+package flight
+
+import (
+	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/mvalue"
+	"bm-lrs/pkg/projection"
+	"bm-lrs/pkg/route"
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// defaultSnapRadiusMeters bounds how far snap_to_route will snap a
+// candidate point to a nearby route before rejecting it, when the request
+// doesn't set a radius_meters override.
+const defaultSnapRadiusMeters = 50.0
+
+// snapCandidateRoutes bounds how many of RouteBBoxIndex's nearest routes
+// snap_to_route inspects per point before picking the true nearest by
+// segment distance, mirroring mvalue's own geodesicCandidateCount.
+const snapCandidateRoutes = 5
+
+// handleLocateMValue implements the "locate_m_value" DoExchange operation:
+// given rows of (ROUTEID, MVAL), it interpolates back to (LAT, LON) on
+// each route's linestring via route.LRSRoute.LocateMValue. It operates on
+// raw records rather than route_event.LRSEvents, since LRSEvents requires
+// LAT/LON columns this operation's input doesn't carry.
+func (s *LRSFlightServer) handleLocateMValue(ctx context.Context, stream flight.FlightService_DoExchangeServer) error {
+	streamProg := newStreamProgress(stream)
+
+	records, err := readExchangeRecords(stream, streamProg)
+	if err != nil {
+		return err
+	}
+	defer releaseRecords(records)
+
+	routeIDs, err := distinctColumnValues(records, "ROUTEID")
+	if err != nil {
+		return err
+	}
+
+	routes := make(map[string]*route.LRSRoute, len(routeIDs))
+	defer func() {
+		for _, r := range routes {
+			r.Release()
+		}
+	}()
+	for _, routeID := range routeIDs {
+		r, err := s.repo.GetLatest(ctx, routeID)
+		if err != nil {
+			continue
+		}
+		routes[routeID] = r
+	}
+
+	outRecs := make([]arrow.RecordBatch, 0, len(records))
+	defer releaseRecords(outRecs)
+	for _, rec := range records {
+		out, err := locateMValueBatch(rec, routes)
+		if err != nil {
+			return err
+		}
+		outRecs = append(outRecs, out)
+	}
+
+	return writeExchangeRecords(stream, outRecs)
+}
+
+// locateMValueBatch appends LAT, LON, and "located" columns to rec by
+// calling LocateMValue for each (ROUTEID, MVAL) row, leaving LAT/LON null
+// and located=false for routes that couldn't be loaded or M-Values outside
+// a route's range.
+func locateMValueBatch(rec arrow.RecordBatch, routes map[string]*route.LRSRoute) (arrow.RecordBatch, error) {
+	pool := memory.NewGoAllocator()
+	schema := rec.Schema()
+
+	routeIdx := schema.FieldIndices("ROUTEID")
+	mvalIdx := schema.FieldIndices("MVAL")
+	if len(routeIdx) == 0 || len(mvalIdx) == 0 {
+		return nil, fmt.Errorf("locate_m_value records missing ROUTEID or MVAL column")
+	}
+
+	routeCol, ok := rec.Column(routeIdx[0]).(*array.String)
+	if !ok {
+		return nil, fmt.Errorf("ROUTEID column is not string")
+	}
+	mvalCol, ok := rec.Column(mvalIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("MVAL column is not float64")
+	}
+
+	latOut := array.NewFloat64Builder(pool)
+	lonOut := array.NewFloat64Builder(pool)
+	locatedOut := array.NewBooleanBuilder(pool)
+	defer latOut.Release()
+	defer lonOut.Release()
+	defer locatedOut.Release()
+
+	numRows := int(rec.NumRows())
+	for i := 0; i < numRows; i++ {
+		routeID := routeCol.Value(i)
+
+		r, ok := routes[routeID]
+		if !ok || mvalCol.IsNull(i) {
+			latOut.AppendNull()
+			lonOut.AppendNull()
+			locatedOut.Append(false)
+			continue
+		}
+
+		lat, lon, err := r.LocateMValue(routeID, mvalCol.Value(i))
+		if err != nil {
+			latOut.AppendNull()
+			lonOut.AppendNull()
+			locatedOut.Append(false)
+			continue
+		}
+
+		latOut.Append(lat)
+		lonOut.Append(lon)
+		locatedOut.Append(true)
+	}
+
+	latArr := latOut.NewArray()
+	lonArr := lonOut.NewArray()
+	locatedArr := locatedOut.NewArray()
+	defer latArr.Release()
+	defer lonArr.Release()
+	defer locatedArr.Release()
+
+	fields := make([]arrow.Field, 0, schema.NumFields()+3)
+	cols := make([]arrow.Array, 0, schema.NumFields()+3)
+	for i := 0; i < schema.NumFields(); i++ {
+		fields = append(fields, schema.Field(i))
+		cols = append(cols, rec.Column(i))
+	}
+	fields = append(fields,
+		arrow.Field{Name: "LAT", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		arrow.Field{Name: "LON", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		arrow.Field{Name: "located", Type: arrow.FixedWidthTypes.Boolean},
+	)
+	cols = append(cols, latArr, lonArr, locatedArr)
+
+	outSchema := arrow.NewSchema(fields, nil)
+	return array.NewRecordBatch(outSchema, cols, int64(numRows)), nil
+}
+
+// handleSnapToRoute implements the "snap_to_route" DoExchange operation:
+// given candidate points without a ROUTEID, it finds the nearest route
+// within radiusMeters via a route.RouteBBoxIndex, then computes the
+// point's M-Value on that route.
+func (s *LRSFlightServer) handleSnapToRoute(ctx context.Context, stream flight.FlightService_DoExchangeServer, crs string, radiusMeters float64) error {
+	streamProg := newStreamProgress(stream)
+
+	records, err := readExchangeRecords(stream, streamProg)
+	if err != nil {
+		return err
+	}
+	defer releaseRecords(records)
+
+	bboxIdx, err := route.NewRouteBBoxIndex(ctx, s.repo)
+	if err != nil {
+		return fmt.Errorf("failed to build route bbox index: %v", err)
+	}
+
+	routes := make(map[string]*route.LRSRoute)
+	defer func() {
+		for _, r := range routes {
+			r.Release()
+		}
+	}()
+
+	outRecs := make([]arrow.RecordBatch, 0, len(records))
+	defer releaseRecords(outRecs)
+	for _, rec := range records {
+		out, err := s.snapToRouteBatch(ctx, rec, bboxIdx, routes, crs, radiusMeters)
+		if err != nil {
+			return err
+		}
+		outRecs = append(outRecs, out)
+	}
+
+	return writeExchangeRecords(stream, outRecs)
+}
+
+// snapToRouteBatch appends ROUTEID, MVAL, dist_to_line, and rejected
+// columns to rec by finding, for each (LAT, LON) row, the nearest route
+// among bboxIdx's snapCandidateRoutes closest routes whose segment snap
+// falls within radiusMeters. routes caches loaded LRSRoutes across calls
+// for the lifetime of the request.
+func (s *LRSFlightServer) snapToRouteBatch(ctx context.Context, rec arrow.RecordBatch, bboxIdx *route.RouteBBoxIndex, routes map[string]*route.LRSRoute, crs string, radiusMeters float64) (arrow.RecordBatch, error) {
+	pool := memory.NewGoAllocator()
+	schema := rec.Schema()
+
+	latIdx := schema.FieldIndices("LAT")
+	lonIdx := schema.FieldIndices("LON")
+	if len(latIdx) == 0 || len(lonIdx) == 0 {
+		return nil, fmt.Errorf("snap_to_route records missing LAT or LON column")
+	}
+
+	latCol, ok := rec.Column(latIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("LAT column is not float64")
+	}
+	lonCol, ok := rec.Column(lonIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("LON column is not float64")
+	}
+
+	routeOut := array.NewStringBuilder(pool)
+	mvalOut := array.NewFloat64Builder(pool)
+	distOut := array.NewFloat64Builder(pool)
+	rejectedOut := array.NewBooleanBuilder(pool)
+	defer routeOut.Release()
+	defer mvalOut.Release()
+	defer distOut.Release()
+	defer rejectedOut.Release()
+
+	numRows := int(rec.NumRows())
+	for i := 0; i < numRows; i++ {
+		lat, lon := latCol.Value(i), lonCol.Value(i)
+
+		bestRouteID := ""
+		bestMVal, bestDist := 0.0, 0.0
+		found := false
+
+		for _, candidateID := range bboxIdx.Nearest(lat, lon, snapCandidateRoutes) {
+			r, err := s.loadRouteCached(ctx, routes, candidateID)
+			if err != nil {
+				continue
+			}
+
+			idx, err := r.Index()
+			if err != nil {
+				continue
+			}
+
+			mval, dist, ok := mvalue.NearestOnRoute(idx, lon, lat, crs)
+			if !ok || (found && dist >= bestDist) {
+				continue
+			}
+
+			bestRouteID, bestMVal, bestDist, found = candidateID, mval, dist, true
+		}
+
+		if !found || bestDist > radiusMeters {
+			routeOut.AppendNull()
+			mvalOut.AppendNull()
+			if found {
+				distOut.Append(bestDist)
+			} else {
+				distOut.AppendNull()
+			}
+			rejectedOut.Append(true)
+			continue
+		}
+
+		routeOut.Append(bestRouteID)
+		mvalOut.Append(bestMVal)
+		distOut.Append(bestDist)
+		rejectedOut.Append(false)
+	}
+
+	routeArr := routeOut.NewArray()
+	mvalArr := mvalOut.NewArray()
+	distArr := distOut.NewArray()
+	rejectedArr := rejectedOut.NewArray()
+	defer routeArr.Release()
+	defer mvalArr.Release()
+	defer distArr.Release()
+	defer rejectedArr.Release()
+
+	fields := make([]arrow.Field, 0, schema.NumFields()+4)
+	cols := make([]arrow.Array, 0, schema.NumFields()+4)
+	for i := 0; i < schema.NumFields(); i++ {
+		fields = append(fields, schema.Field(i))
+		cols = append(cols, rec.Column(i))
+	}
+	fields = append(fields,
+		arrow.Field{Name: "ROUTEID", Type: arrow.BinaryTypes.String, Nullable: true},
+		arrow.Field{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		arrow.Field{Name: "dist_to_line", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		arrow.Field{Name: "rejected", Type: arrow.FixedWidthTypes.Boolean},
+	)
+	cols = append(cols, routeArr, mvalArr, distArr, rejectedArr)
+
+	outSchema := arrow.NewSchema(fields, nil)
+	return array.NewRecordBatch(outSchema, cols, int64(numRows)), nil
+}
+
+// loadRouteCached returns routes[routeID], loading and caching it via
+// s.repo.GetLatest on first use.
+func (s *LRSFlightServer) loadRouteCached(ctx context.Context, routes map[string]*route.LRSRoute, routeID string) (*route.LRSRoute, error) {
+	if r, ok := routes[routeID]; ok {
+		return r, nil
+	}
+
+	r, err := s.repo.GetLatest(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+	routes[routeID] = r
+	return r, nil
+}
+
+// handleReproject implements the "reproject" DoExchange operation: it runs
+// projection.Transform on the streamed points with no ROUTEID and no LRS
+// lookup at all, via geom.Points rather than route_event.LRSEvents, since
+// Points doesn't require a ROUTEID column.
+func (s *LRSFlightServer) handleReproject(ctx context.Context, stream flight.FlightService_DoExchangeServer, crs, targetCRS string) error {
+	streamProg := newStreamProgress(stream)
+
+	records, err := readExchangeRecords(stream, streamProg)
+	if err != nil {
+		return err
+	}
+
+	points := geom.NewPoints(records, crs)
+	defer points.Release()
+
+	transformed, err := projection.Transform(ctx, &points, targetCRS, false)
+	if err != nil {
+		return fmt.Errorf("failed to transform projection: %v", err)
+	}
+	defer transformed.Release()
+
+	return writeExchangeRecords(stream, transformed.GetRecords())
+}
+
+// distinctColumnValues collects the distinct values of col across records,
+// in first-seen order.
+func distinctColumnValues(records []arrow.RecordBatch, col string) ([]string, error) {
+	seen := make(map[string]bool)
+	var values []string
+
+	for _, rec := range records {
+		idx := rec.Schema().FieldIndices(col)
+		if len(idx) == 0 {
+			return nil, fmt.Errorf("records missing %s column", col)
+		}
+		arr, ok := rec.Column(idx[0]).(*array.String)
+		if !ok {
+			return nil, fmt.Errorf("%s column is not string", col)
+		}
+		for i := 0; i < arr.Len(); i++ {
+			if arr.IsNull(i) {
+				continue
+			}
+			v := arr.Value(i)
+			if !seen[v] {
+				seen[v] = true
+				values = append(values, v)
+			}
+		}
+	}
+
+	return values, nil
+}
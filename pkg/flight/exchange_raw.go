@@ -0,0 +1,151 @@
+package flight
+
+import (
+	"bm-lrs/pkg/progress"
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// exchangeMaxBatchRows mirrors handleCalculateMValue's spill threshold for
+// the raw-record operations (locate_m_value, snap_to_route, reproject),
+// which don't go through route_event.LRSEvents and so need their own copy
+// of the accumulate-then-spill loop.
+const exchangeMaxBatchRows = 1000 * 1000
+
+// readExchangeRecords reads every RecordBatch off stream, reporting row
+// progress via prog, and returns them fully materialized in memory. Inputs
+// large enough to cross exchangeMaxBatchRows are spilled to Parquet and
+// merged back via ParquetBatchHandler, the same plumbing
+// handleCalculateMValue uses, so large requests still work without holding
+// everything in memory at once mid-stream.
+func readExchangeRecords(stream flight.FlightService_DoExchangeServer, prog progress.Progress) ([]arrow.RecordBatch, error) {
+	reader, err := flight.NewRecordReader(stream)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+
+	handler, err := NewParquetBatchHandler(WithProgress(prog))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch handler: %v", err)
+	}
+	defer handler.Cleanup()
+
+	var records []arrow.RecordBatch
+	var totalRows int64
+	for reader.Next() {
+		rec := reader.RecordBatch()
+		rec.Retain()
+		records = append(records, rec)
+		prog.Add(rec.NumRows())
+		totalRows += rec.NumRows()
+
+		if totalRows >= exchangeMaxBatchRows {
+			if err := spillRecords(handler, records); err != nil {
+				return nil, err
+			}
+			records = nil
+			totalRows = 0
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(handler.currentFiles) == 0 {
+		if len(records) == 0 {
+			return nil, fmt.Errorf("no records received")
+		}
+		return records, nil
+	}
+
+	if err := spillRecords(handler, records); err != nil {
+		return nil, err
+	}
+
+	mergedPath, err := handler.MergeParquetFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge parquet files: %v", err)
+	}
+
+	return readParquetRecords(mergedPath)
+}
+
+// spillRecords writes records to handler's Parquet spill and releases them.
+func spillRecords(handler *ParquetBatchHandler, records []arrow.RecordBatch) error {
+	for _, r := range records {
+		if err := handler.AddRecordBatch(r); err != nil {
+			return fmt.Errorf("failed to write record batch to parquet: %v", err)
+		}
+	}
+	for _, r := range records {
+		r.Release()
+	}
+	return nil
+}
+
+// readParquetRecords reads every RecordBatch back out of the Parquet file
+// at path, materializing it fully into memory, mirroring the reader half
+// of ParquetBatchHandler.MergeParquetFiles.
+func readParquetRecords(path string) ([]arrow.RecordBatch, error) {
+	pf, err := file.OpenParquetFile(path, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open parquet file %s: %v", path, err)
+	}
+	defer pf.Close()
+
+	reader, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{BatchSize: 10000}, memory.NewGoAllocator())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create arrow reader for %s: %v", path, err)
+	}
+
+	recordReader, err := reader.GetRecordReader(context.Background(), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get record reader for %s: %v", path, err)
+	}
+	defer recordReader.Release()
+
+	var records []arrow.RecordBatch
+	for recordReader.Next() {
+		rec := recordReader.RecordBatch()
+		rec.Retain()
+		records = append(records, rec)
+	}
+	if err := recordReader.Err(); err != nil {
+		return nil, fmt.Errorf("error reading records from %s: %v", path, err)
+	}
+
+	return records, nil
+}
+
+// writeExchangeRecords streams records back to the client as the
+// DoExchange response, mirroring handleCalculateMValue's own egress.
+func writeExchangeRecords(stream flight.FlightService_DoExchangeServer, records []arrow.RecordBatch) error {
+	if len(records) == 0 {
+		return fmt.Errorf("no records to write")
+	}
+
+	writer := flight.NewRecordWriter(stream, ipc.WithSchema(records[0].Schema()))
+	defer writer.Close()
+
+	for _, rec := range records {
+		if err := writer.Write(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseRecords releases every record in records, tolerating a nil slice.
+func releaseRecords(records []arrow.RecordBatch) {
+	for _, r := range records {
+		r.Release()
+	}
+}
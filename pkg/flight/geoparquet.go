@@ -0,0 +1,196 @@
+package flight
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// geoParquetKey is the Parquet file-level key/value metadata key defined by
+// the GeoParquet spec.
+const geoParquetKey = "geo"
+
+// geometryColumn is the name of the WKB geometry column MergeParquetFiles
+// appends to the merged file when WithGeoParquetMetadata is set.
+const geometryColumn = "geometry"
+
+// geoParquetMeta mirrors the "geo" metadata object written by
+// route.LRSRoute.Sink so MergeParquetFiles can produce a compatible merged
+// file and detect incompatible inputs.
+type geoParquetMeta struct {
+	Version       string                       `json:"version"`
+	PrimaryColumn string                       `json:"primary_column"`
+	Columns       map[string]*geoParquetColumn `json:"columns"`
+}
+
+type geoParquetColumn struct {
+	Encoding      string    `json:"encoding"`
+	GeometryTypes []string  `json:"geometry_types"`
+	CRS           any       `json:"crs"`
+	Edges         string    `json:"edges,omitempty"`
+	Bbox          []float64 `json:"bbox,omitempty"`
+}
+
+// extendBounds folds the LAT/LON columns of rec into the handler's running
+// bbox, using the configured latCol/lonCol.
+func (h *ParquetBatchHandler) extendBounds(rec arrow.RecordBatch) error {
+	schema := rec.Schema()
+	latIdx := schema.FieldIndices(h.latCol)
+	lonIdx := schema.FieldIndices(h.lonCol)
+	if len(latIdx) == 0 || len(lonIdx) == 0 {
+		return fmt.Errorf("missing %s/%s column", h.latCol, h.lonCol)
+	}
+
+	lat, ok := rec.Column(latIdx[0]).(*array.Float64)
+	if !ok {
+		return fmt.Errorf("%s column is not float64", h.latCol)
+	}
+	lon, ok := rec.Column(lonIdx[0]).(*array.Float64)
+	if !ok {
+		return fmt.Errorf("%s column is not float64", h.lonCol)
+	}
+
+	for i := 0; i < lat.Len(); i++ {
+		if lat.IsNull(i) || lon.IsNull(i) {
+			continue
+		}
+		h.bounds.extend(lon.Value(i), lat.Value(i))
+	}
+
+	return nil
+}
+
+// buildGeoParquetMeta assembles the "geo" metadata for the merged file,
+// naming geometryColumn (the WKB column appendGeometryColumn derives from
+// latCol/lonCol) as the primary column, following the same layout
+// route.LRSRoute.Sink writes for point sinks.
+func buildGeoParquetMeta(box bbox) (*arrow.Metadata, error) {
+	var bboxSlice []float64
+	if box.set {
+		bboxSlice = []float64{box.minX, box.minY, box.maxX, box.maxY}
+	}
+
+	meta := geoParquetMeta{
+		Version:       "1.1.0",
+		PrimaryColumn: geometryColumn,
+		Columns: map[string]*geoParquetColumn{
+			geometryColumn: {
+				Encoding:      "WKB",
+				GeometryTypes: []string{"Point"},
+				Edges:         "planar",
+				Bbox:          bboxSlice,
+			},
+		},
+	}
+
+	blob, err := json.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal geo metadata: %v", err)
+	}
+
+	kv := arrow.NewMetadata([]string{geoParquetKey}, []string{string(blob)})
+	return &kv, nil
+}
+
+// encodeWKBPoint encodes (lon, lat) as a 2D little-endian WKB Point.
+func encodeWKBPoint(lon, lat float64) []byte {
+	buf := make([]byte, 21)
+	buf[0] = 1 // little-endian byte order
+	binary.LittleEndian.PutUint32(buf[1:5], 1 /* wkbPoint */)
+	binary.LittleEndian.PutUint64(buf[5:13], math.Float64bits(lon))
+	binary.LittleEndian.PutUint64(buf[13:21], math.Float64bits(lat))
+	return buf
+}
+
+// appendGeometryColumn returns a copy of rec under newSchema with a WKB
+// Point geometry column (named geometryColumn) appended, derived from its
+// latCol/lonCol columns.
+func appendGeometryColumn(rec arrow.RecordBatch, newSchema *arrow.Schema, latCol, lonCol string) (arrow.RecordBatch, error) {
+	schema := rec.Schema()
+	latIdx := schema.FieldIndices(latCol)
+	lonIdx := schema.FieldIndices(lonCol)
+	if len(latIdx) == 0 || len(lonIdx) == 0 {
+		return nil, fmt.Errorf("missing %s/%s column to derive geometry", latCol, lonCol)
+	}
+	lat, ok := rec.Column(latIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not float64", latCol)
+	}
+	lon, ok := rec.Column(lonIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not float64", lonCol)
+	}
+
+	pool := memory.NewGoAllocator()
+	builder := array.NewBinaryBuilder(pool, arrow.BinaryTypes.Binary)
+	defer builder.Release()
+
+	for i := 0; i < int(rec.NumRows()); i++ {
+		if lat.IsNull(i) || lon.IsNull(i) {
+			builder.AppendNull()
+			continue
+		}
+		builder.Append(encodeWKBPoint(lon.Value(i), lat.Value(i)))
+	}
+	geometry := builder.NewArray()
+	defer geometry.Release()
+
+	cols := make([]arrow.Array, int(rec.NumCols()))
+	for i := range cols {
+		cols[i] = rec.Column(i)
+	}
+	cols = append(cols, geometry)
+	return array.NewRecordBatch(newSchema, cols, rec.NumRows()), nil
+}
+
+// parseGeoParquetMeta reads the "geo" key/value metadata off a schema, if
+// present.
+func parseGeoParquetMeta(schema *arrow.Schema) (*geoParquetMeta, bool) {
+	md := schema.Metadata()
+	for i, k := range md.Keys() {
+		if k != geoParquetKey {
+			continue
+		}
+		var out geoParquetMeta
+		if err := json.Unmarshal([]byte(md.Values()[i]), &out); err != nil {
+			return nil, false
+		}
+		return &out, true
+	}
+	return nil, false
+}
+
+// checkGeoCompat rejects merging a source file whose "geo" metadata
+// disagrees with the merged schema's primary column or CRS, instead of
+// silently concatenating incompatible geometries.
+func checkGeoCompat(mergedSchema, fileSchema *arrow.Schema) error {
+	mergedMeta, ok := parseGeoParquetMeta(mergedSchema)
+	if !ok {
+		return nil
+	}
+	fileMeta, ok := parseGeoParquetMeta(fileSchema)
+	if !ok {
+		return nil
+	}
+
+	if fileMeta.PrimaryColumn != mergedMeta.PrimaryColumn {
+		return fmt.Errorf("incompatible geo metadata: primary_column %q != %q", fileMeta.PrimaryColumn, mergedMeta.PrimaryColumn)
+	}
+
+	mergedCol := mergedMeta.Columns[mergedMeta.PrimaryColumn]
+	fileCol := fileMeta.Columns[fileMeta.PrimaryColumn]
+	if mergedCol != nil && fileCol != nil {
+		mergedCRS, _ := json.Marshal(mergedCol.CRS)
+		fileCRS, _ := json.Marshal(fileCol.CRS)
+		if string(mergedCRS) != string(fileCRS) {
+			return fmt.Errorf("incompatible geo metadata: mismatched CRS")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package flight
+
+import (
+	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/jobs"
+	"bm-lrs/pkg/route_event"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultJobQueueConcurrency is the worker count of the jobs.Queue lazily
+// created by jobQueue, whether or not WithJobsDir picks its directory.
+const defaultJobQueueConcurrency = jobs.DefaultConcurrency
+
+// WithJobsDir makes the server persist job metadata under dir instead of a
+// process-lifetime temporary directory, so jobs survive a restart.
+func WithJobsDir(dir string) FlightServerOption {
+	return func(s *LRSFlightServer) {
+		s.jobsDir = dir
+	}
+}
+
+// jobQueue lazily creates s's jobs.Queue on first use, defaulting to a
+// fresh temporary directory when WithJobsDir wasn't supplied.
+func (s *LRSFlightServer) jobQueue() (*jobs.Queue, error) {
+	s.jobQueueOnce.Do(func() {
+		dir := s.jobsDir
+		if dir == "" {
+			var err error
+			dir, err = os.MkdirTemp("", "lrs_flight_jobs_*")
+			if err != nil {
+				s.jobQueueErr = fmt.Errorf("failed to create jobs directory: %w", err)
+				return
+			}
+		}
+		s.jobs, s.jobQueueErr = jobs.NewQueue(dir, jobs.WithConcurrency(defaultJobQueueConcurrency))
+	})
+	return s.jobs, s.jobQueueErr
+}
+
+// handleSubmitJob implements the "submit_job" DoAction: action.Body is the
+// ticket ID of raw events a prior async DoPut staged via the ticketStore.
+// It submits a Task that runs the same route-load + M-Value pipeline
+// DoPut uses synchronously, retaining the result as a Parquet file so
+// fetch_result/DoGet can resume after a disconnect, and returns the new
+// job ID.
+func (s *LRSFlightServer) handleSubmitJob(action *flight.Action, stream flight.FlightService_DoActionServer) error {
+	ticketID := string(action.Body)
+
+	events, ok := s.tickets.take(ticketID)
+	if !ok {
+		return status.Errorf(codes.NotFound, "unknown ticket %q", ticketID)
+	}
+
+	queue, err := s.jobQueue()
+	if err != nil {
+		events.Release()
+		return status.Errorf(codes.Internal, "failed to initialize job queue: %v", err)
+	}
+
+	task := func(ctx context.Context, report func(done, total int64)) (string, error) {
+		defer events.Release()
+
+		resultEvents, err := s.calculateMValueForEvents(ctx, events)
+		if err != nil {
+			return "", err
+		}
+
+		if err := resultEvents.Sink(); err != nil {
+			resultEvents.Release()
+			return "", fmt.Errorf("failed to materialize result: %w", err)
+		}
+		uri := resultEvents.GetSourceFile()
+		if uri == nil {
+			resultEvents.Release()
+			return "", fmt.Errorf("sink produced no source file")
+		}
+
+		return *uri, nil
+	}
+
+	jobID, err := queue.Submit(task)
+	if err != nil {
+		events.Release()
+		return status.Errorf(codes.Internal, "failed to submit job: %v", err)
+	}
+
+	return stream.Send(&flight.Result{Body: []byte(jobID)})
+}
+
+// handleJobStatus implements the "get_status" DoAction: action.Body is a
+// job ID, and the response body is the JSON encoding of its jobs.Job.
+func (s *LRSFlightServer) handleJobStatus(action *flight.Action, stream flight.FlightService_DoActionServer) error {
+	queue, err := s.jobQueue()
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to initialize job queue: %v", err)
+	}
+
+	job, err := queue.Get(string(action.Body))
+	if err != nil {
+		return status.Errorf(codes.NotFound, "%v", err)
+	}
+
+	body, err := json.Marshal(job)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to marshal job status: %v", err)
+	}
+
+	return stream.Send(&flight.Result{Body: body})
+}
+
+// handleFetchResult implements the "fetch_result" DoAction: action.Body is
+// a job ID. Once the job is done, its materialized Parquet result is
+// loaded and stashed under a new ticket, the same handoff DoPut uses, so
+// the client streams it back via the existing DoGet/ticketStore path
+// instead of a bespoke result RPC.
+func (s *LRSFlightServer) handleFetchResult(action *flight.Action, stream flight.FlightService_DoActionServer) error {
+	queue, err := s.jobQueue()
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to initialize job queue: %v", err)
+	}
+
+	job, err := queue.Get(string(action.Body))
+	if err != nil {
+		return status.Errorf(codes.NotFound, "%v", err)
+	}
+	if job.State != jobs.StateDone {
+		return status.Errorf(codes.FailedPrecondition, "job %q is %s, not done", job.ID, job.State)
+	}
+
+	events, err := route_event.NewLRSEventsFromFile(job.ResultURI, geom.LAMBERT_WKT)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to load job result: %v", err)
+	}
+	if err := events.LoadToBuffer(stream.Context()); err != nil {
+		events.Release()
+		return status.Errorf(codes.Internal, "failed to load job result into memory: %v", err)
+	}
+
+	ticketID := s.tickets.put(events)
+
+	return stream.Send(&flight.Result{Body: []byte(ticketID)})
+}
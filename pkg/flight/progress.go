@@ -0,0 +1,58 @@
+package flight
+
+import (
+	"bm-lrs/pkg/progress"
+	"encoding/json"
+	"log"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+)
+
+// progressMessage is the AppMetadata envelope sent on a DoExchange stream to
+// report progress on a long-running operation, so a remote client can drive
+// a progress bar without polling.
+type progressMessage struct {
+	Kind  string `json:"kind"`
+	Done  int64  `json:"done"`
+	Total int64  `json:"total"`
+}
+
+// streamProgress reports progress.Progress updates by sending FlightData
+// messages carrying a progressMessage AppMetadata envelope on a DoExchange
+// stream. Send errors are logged and otherwise ignored: progress reporting
+// must never fail the underlying operation.
+type streamProgress struct {
+	stream flight.FlightService_DoExchangeServer
+	total  int64
+	done   int64
+}
+
+// newStreamProgress reports progress over stream.
+func newStreamProgress(stream flight.FlightService_DoExchangeServer) progress.Progress {
+	return &streamProgress{stream: stream}
+}
+
+func (p *streamProgress) Start(total int64) {
+	p.total = total
+	p.done = 0
+	p.send()
+}
+
+func (p *streamProgress) Add(n int64) {
+	p.done += n
+	p.send()
+}
+
+func (p *streamProgress) Finish() {
+	p.send()
+}
+
+func (p *streamProgress) send() {
+	payload, err := json.Marshal(progressMessage{Kind: "progress", Done: p.done, Total: p.total})
+	if err != nil {
+		return
+	}
+	if err := p.stream.Send(&flight.FlightData{AppMetadata: payload}); err != nil {
+		log.Printf("failed to send progress update: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package flight
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// requestContext is a context.Context whose deadline can be moved after
+// creation, reusing deadline.go's gonet-style deadlineTimer so a request
+// already in flight can have its deadline extended, or be cancelled
+// outright, from a sibling DoAction call (see LRSRequest). Done() also
+// fires once parent itself is done, so an ordinary client disconnect
+// still tears the request down.
+type requestContext struct {
+	context.Context
+	timer    *deadlineTimer
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+func newRequestContext(parent context.Context, deadline time.Time) *requestContext {
+	c := &requestContext{Context: parent, timer: newDeadlineTimer()}
+	c.setDeadline(deadline)
+
+	go func() {
+		<-parent.Done()
+		c.timer.set(time.Now())
+	}()
+
+	return c
+}
+
+func (c *requestContext) setDeadline(t time.Time) {
+	c.mu.Lock()
+	c.deadline = t
+	c.mu.Unlock()
+	c.timer.set(t)
+}
+
+func (c *requestContext) cancel() {
+	c.setDeadline(time.Now())
+}
+
+func (c *requestContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadline, !c.deadline.IsZero()
+}
+
+func (c *requestContext) Done() <-chan struct{} {
+	return c.timer.chanCancel()
+}
+
+func (c *requestContext) Err() error {
+	select {
+	case <-c.timer.chanCancel():
+		if err := c.Context.Err(); err != nil {
+			return err
+		}
+		return context.DeadlineExceeded
+	default:
+		return nil
+	}
+}
+
+// LRSRequest is a handle onto one in-flight DoExchange request's
+// deadline, kept in a requestRegistry under its request ID so a sibling
+// call -- DoAction("cancel_job"/"extend_deadline") on Flight -- can reach
+// back into a request that's already running a DuckDB query or streaming
+// results, which otherwise has no way to be told about after the fact.
+type LRSRequest struct {
+	ctx *requestContext
+}
+
+// SetDeadline moves the request's deadline, extending or shortening it.
+// Once the request has finished this has no observable effect.
+func (r *LRSRequest) SetDeadline(t time.Time) {
+	r.ctx.setDeadline(t)
+}
+
+// Cancel ends the request immediately, as if its deadline had already
+// passed.
+func (r *LRSRequest) Cancel() {
+	r.ctx.cancel()
+}
+
+// requestRegistry tracks every in-flight request's LRSRequest handle by
+// ID, the same opaque-handle pattern ticketStore uses for DoPut results.
+type requestRegistry struct {
+	mu       sync.Mutex
+	requests map[string]*LRSRequest
+}
+
+func newRequestRegistry() *requestRegistry {
+	return &requestRegistry{requests: make(map[string]*LRSRequest)}
+}
+
+// register wraps parent with deadline (zero means none), returning the
+// request ID it's filed under, the derived context to use for the
+// request's work, and a release func the caller must defer to unregister
+// it once the request finishes.
+func (reg *requestRegistry) register(parent context.Context, deadline time.Time) (id string, ctx context.Context, release func()) {
+	rc := newRequestContext(parent, deadline)
+	req := &LRSRequest{ctx: rc}
+	id = newRequestID()
+
+	reg.mu.Lock()
+	reg.requests[id] = req
+	reg.mu.Unlock()
+
+	release = func() {
+		reg.mu.Lock()
+		delete(reg.requests, id)
+		reg.mu.Unlock()
+	}
+
+	return id, rc, release
+}
+
+// lookup returns the LRSRequest registered under id, if it's still running.
+func (reg *requestRegistry) lookup(id string) (*LRSRequest, bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	req, ok := reg.requests[id]
+	return req, ok
+}
+
+// newRequestID returns a random opaque request handle.
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("req-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
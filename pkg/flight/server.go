@@ -2,14 +2,18 @@ package flight
 
 import (
 	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/jobs"
 	"bm-lrs/pkg/mvalue"
 	"bm-lrs/pkg/projection"
 	"bm-lrs/pkg/route"
 	"bm-lrs/pkg/route_event"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/flight"
@@ -18,17 +22,58 @@ import (
 
 type LRSFlightServer struct {
 	flight.BaseFlightServer
-	repo *route.LRSRouteRepository
+	repo     *route.LRSRouteRepository
+	deadline DeadlineOptions
+	tickets  *ticketStore
+	requests *requestRegistry
+	engine   mvalue.Engine
+
+	jobsDir      string
+	jobQueueOnce sync.Once
+	jobs         *jobs.Queue
+	jobQueueErr  error
 }
 
-func NewLRSFlightServer(repo *route.LRSRouteRepository) *LRSFlightServer {
-	return &LRSFlightServer{
-		repo: repo,
+// FlightServerOption configures optional behavior of LRSFlightServer.
+type FlightServerOption func(*LRSFlightServer)
+
+// WithDeadlineOptions overrides the per-direction read/write timeouts
+// applied to every DoExchange stream. Defaults to DefaultDeadlineOptions()
+// (LRS_FLIGHT_READ_TIMEOUT / LRS_FLIGHT_WRITE_TIMEOUT env vars).
+func WithDeadlineOptions(opts DeadlineOptions) FlightServerOption {
+	return func(s *LRSFlightServer) {
+		s.deadline = opts
+	}
+}
+
+// WithEngine selects the mvalue.Engine used to calculate M-Values for
+// every DoExchange/DoPut request this server handles. Defaults to
+// mvalue.EngineDuckDB; pass mvalue.EngineGeodesic where the spatial
+// extension can't be installed.
+func WithEngine(e mvalue.Engine) FlightServerOption {
+	return func(s *LRSFlightServer) {
+		s.engine = e
+	}
+}
+
+func NewLRSFlightServer(repo *route.LRSRouteRepository, opts ...FlightServerOption) *LRSFlightServer {
+	s := &LRSFlightServer{
+		repo:     repo,
+		deadline: DefaultDeadlineOptions(),
+		tickets:  newTicketStore(),
+		requests: newRequestRegistry(),
+		engine:   mvalue.EngineDuckDB,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 func (s *LRSFlightServer) DoExchange(stream flight.FlightService_DoExchangeServer) error {
-	desc, err := stream.Recv()
+	deadlineStream := newDeadlineExchangeStream(stream, s.deadline)
+
+	desc, err := deadlineStream.Recv()
 	if err != nil {
 		if err == io.EOF {
 			return nil
@@ -42,8 +87,11 @@ func (s *LRSFlightServer) DoExchange(stream flight.FlightService_DoExchangeServe
 
 	// Define a struct for the expected JSON metadata
 	type Action struct {
-		Operation string `json:"operation"`
-		CRS       string `json:"crs"`
+		Operation      string  `json:"operation"`
+		CRS            string  `json:"crs"`
+		DeadlineUnixMs int64   `json:"deadline_unix_ms"`
+		TargetCRS      string  `json:"target_crs"`
+		RadiusMeters   float64 `json:"radius_meters"`
 	}
 
 	var action Action
@@ -70,19 +118,54 @@ func (s *LRSFlightServer) DoExchange(stream flight.FlightService_DoExchangeServe
 		}
 	}
 
-	fmt.Printf("Operation: %s, CRS: %s\n", operation, crs)
+	// The client may negotiate an explicit wall-clock deadline for both
+	// directions via the first message instead of relying on the server's
+	// configured timeouts. The same deadline also bounds the request's
+	// context, so it's enforced inside repo.GetLatest, projection.Transform
+	// and mvalue.CalculatePointsMValue, not just on the stream's own
+	// Recv/Send calls.
+	var deadline time.Time
+	if action.DeadlineUnixMs > 0 {
+		deadline = time.UnixMilli(action.DeadlineUnixMs)
+		deadlineStream.SetReadDeadline(deadline)
+		deadlineStream.SetWriteDeadline(deadline)
+	}
+
+	requestID, ctx, release := s.requests.register(stream.Context(), deadline)
+	defer release()
+
+	// Report the request ID up front so the client can extend or cancel
+	// it from a sibling DoAction("extend_deadline"/"cancel_job") call
+	// while this exchange is still running.
+	if err := deadlineStream.Send(&flight.FlightData{AppMetadata: []byte(fmt.Sprintf(`{"request_id":%q}`, requestID))}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Operation: %s, CRS: %s, Request: %s\n", operation, crs, requestID)
 
 	switch operation {
 	case "calculate_m_value":
-		return s.handleCalculateMValue(stream, desc, crs)
+		return s.handleCalculateMValue(ctx, deadlineStream, desc, crs)
+	case "locate_m_value":
+		return s.handleLocateMValue(ctx, deadlineStream)
+	case "snap_to_route":
+		radius := action.RadiusMeters
+		if radius <= 0 {
+			radius = defaultSnapRadiusMeters
+		}
+		return s.handleSnapToRoute(ctx, deadlineStream, crs, radius)
+	case "reproject":
+		targetCRS := action.TargetCRS
+		if targetCRS == "" {
+			targetCRS = geom.LAMBERT_WKT
+		}
+		return s.handleReproject(ctx, deadlineStream, crs, targetCRS)
 	default:
 		return fmt.Errorf("unsupported operation: %s", operation)
 	}
 }
 
-func (s *LRSFlightServer) handleCalculateMValue(stream flight.FlightService_DoExchangeServer, firstData *flight.FlightData, crs string) error {
-	ctx := stream.Context()
-
+func (s *LRSFlightServer) handleCalculateMValue(ctx context.Context, stream flight.FlightService_DoExchangeServer, firstData *flight.FlightData, crs string) error {
 	// Implementation note: Arrow Flight RecordReader is usually the way to go
 	reader, err := flight.NewRecordReader(stream)
 	if err != nil {
@@ -90,8 +173,14 @@ func (s *LRSFlightServer) handleCalculateMValue(stream flight.FlightService_DoEx
 	}
 	defer reader.Release()
 
+	// Report progress back to the client via periodic AppMetadata frames
+	// on the same stream, so it can drive a progress UI instead of
+	// waiting silently until the result record batches start arriving.
+	stageProg := newMValueStageReporter(stream)
+	stageProg.SetStage("reading_input")
+
 	// Create batch handler for large record batches
-	handler, err := NewParquetBatchHandler()
+	handler, err := NewParquetBatchHandler(WithProgress(stageProg.subProgress("spilling", stageProg.AddBytesSpilled)))
 	if err != nil {
 		return fmt.Errorf("failed to create batch handler: %v", err)
 	}
@@ -108,6 +197,7 @@ func (s *LRSFlightServer) handleCalculateMValue(stream flight.FlightService_DoEx
 		records = append(records, rec)
 
 		log.Printf("Received record batch with size of %d", rec.NumRows())
+		stageProg.AddRowsRead(rec.NumRows())
 
 		// Calculate size of this record batch (approximate by row count)
 		totalSize += rec.NumRows()
@@ -179,14 +269,14 @@ func (s *LRSFlightServer) handleCalculateMValue(stream flight.FlightService_DoEx
 
 	// Check if events need to be materialized (loaded from file)
 	if events.IsMaterialized() {
-		if err := events.LoadToBuffer(); err != nil {
+		if err := events.LoadToBuffer(ctx); err != nil {
 			return fmt.Errorf("failed to materialize events from file: %v", err)
 		}
 	}
 
 	// Check the Events CRS
 	if events.GetCRS() != geom.LAMBERT_WKT {
-		transformedEvents, err := projection.Transform(events, geom.LAMBERT_WKT, false)
+		transformedEvents, err := projection.Transform(ctx, events, geom.LAMBERT_WKT, false)
 		if err != nil {
 			return err
 		}
@@ -211,6 +301,7 @@ func (s *LRSFlightServer) handleCalculateMValue(stream flight.FlightService_DoEx
 	routesLoaded := 0
 
 	// Load all LRS routes
+	stageProg.SetStage("loading_routes")
 	for _, routeID := range routeIDs {
 		lrs, err := s.repo.GetLatest(ctx, routeID)
 		if err != nil {
@@ -225,6 +316,7 @@ func (s *LRSFlightServer) handleCalculateMValue(stream flight.FlightService_DoEx
 			continue
 		}
 		routesLoaded++
+		stageProg.AddRoutesLoaded(1)
 	}
 
 	if routesLoaded == 0 {
@@ -234,7 +326,8 @@ func (s *LRSFlightServer) handleCalculateMValue(stream flight.FlightService_DoEx
 	fmt.Printf("Successfully loaded %d LRS routes into batch\n", routesLoaded)
 
 	// Calculate M-Values
-	resultEvents, err := mvalue.CalculatePointsMValue(ctx, lrs, *events)
+	stageProg.SetStage("calculating")
+	resultEvents, err := mvalue.CalculatePointsMValue(ctx, routeBatch, *events, mvalue.WithEngine(s.engine), mvalue.WithProgress(stageProg.subProgress("calculating", stageProg.AddRowsWritten)))
 	if err != nil {
 		return fmt.Errorf("failed to calculate m-values: %v", err)
 	}
@@ -242,6 +335,7 @@ func (s *LRSFlightServer) handleCalculateMValue(stream flight.FlightService_DoEx
 
 	// Stream back the results
 	// Use flight.Writer to handle the complexity of Arrow Flight data framing
+	stageProg.SetStage("streaming_results")
 	writer := flight.NewRecordWriter(stream, ipc.WithSchema(resultEvents.GetRecords()[0].Schema()))
 	defer writer.Close()
 
@@ -249,6 +343,7 @@ func (s *LRSFlightServer) handleCalculateMValue(stream flight.FlightService_DoEx
 		if err := writer.Write(rec); err != nil {
 			return err
 		}
+		stageProg.AddRowsWritten(rec.NumRows())
 	}
 
 	return nil
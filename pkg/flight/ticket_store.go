@@ -0,0 +1,78 @@
+package flight
+
+import (
+	"bm-lrs/pkg/route_event"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ticketStore keeps DoPut-computed LRSEvents addressable by an opaque
+// ticket handle so a client can fetch them via DoGet, until it explicitly
+// frees them with DoAction("release_ticket").
+type ticketStore struct {
+	mu      sync.Mutex
+	results map[string]*route_event.LRSEvents
+}
+
+func newTicketStore() *ticketStore {
+	return &ticketStore{results: make(map[string]*route_event.LRSEvents)}
+}
+
+// put stores events under a new ticket ID and returns it.
+func (t *ticketStore) put(events *route_event.LRSEvents) string {
+	ticketID := newTicketID()
+
+	t.mu.Lock()
+	t.results[ticketID] = events
+	t.mu.Unlock()
+
+	return ticketID
+}
+
+// get returns the events stored under ticketID, if any.
+func (t *ticketStore) get(ticketID string) (*route_event.LRSEvents, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	events, ok := t.results[ticketID]
+	return events, ok
+}
+
+// take removes and returns the events stored under ticketID without
+// releasing them, handing ownership to the caller. Used to promote a
+// DoPut-staged raw-input ticket into a job, whose Task releases the
+// events itself once it's done reading them.
+func (t *ticketStore) take(ticketID string) (*route_event.LRSEvents, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	events, ok := t.results[ticketID]
+	if ok {
+		delete(t.results, ticketID)
+	}
+	return events, ok
+}
+
+// release removes and releases the events stored under ticketID, if any.
+func (t *ticketStore) release(ticketID string) {
+	t.mu.Lock()
+	events, ok := t.results[ticketID]
+	delete(t.results, ticketID)
+	t.mu.Unlock()
+
+	if ok {
+		events.Release()
+	}
+}
+
+// newTicketID returns a random opaque ticket handle.
+func newTicketID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("ticket-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,202 @@
+package geom
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// pointsMValuePropertyKey is the default feature property carrying M
+// values when the geometry itself has none, mirroring the route package's
+// "m_values" convention.
+const pointsMValuePropertyKey = "m_values"
+
+// defaultPointsCRS is the CRS assumed by NewPointsFromGeoJSON when neither
+// a crsWKT argument nor a GeoJSON "crs" member is present, per the RFC 7946
+// default of WGS 84 longitude/latitude.
+const defaultPointsCRS = "EPSG:4326"
+
+type pointsFeatureCollection struct {
+	Type     string          `json:"type"`
+	Features []pointsFeature `json:"features"`
+	CRS      *pointsNamedCRS `json:"crs"`
+}
+
+type pointsNamedCRS struct {
+	Type       string `json:"type"`
+	Properties struct {
+		Name string `json:"name"`
+	} `json:"properties"`
+}
+
+type pointsFeature struct {
+	Type       string         `json:"type"`
+	Geometry   pointsGeometry `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type pointsGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Measures    json.RawMessage `json:"measures"`
+}
+
+// NewPointsFromGeoJSON creates Points from a standard RFC 7946 GeoJSON
+// FeatureCollection, flattening every Point and MultiPoint feature's
+// vertices into a single Points. M values are read, in order of
+// preference, from a 4-member coordinate ([lon, lat, z, m]), the
+// geometry's "measures" member (CRS-WKT M-values extension, a single value
+// for Point or one value per position for MultiPoint), or the feature's
+// "m_values" property, falling back to 0 when none are present. The CRS is
+// resolved from crsWKT if non-empty, else from the document's deprecated
+// "crs" member, else defaults to EPSG:4326.
+func NewPointsFromGeoJSON(data []byte, crsWKT string) (Points, error) {
+	var fc pointsFeatureCollection
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return Points{}, fmt.Errorf("failed to unmarshal geojson: %w", err)
+	}
+
+	crs := crsWKT
+	if crs == "" {
+		if fc.CRS != nil && fc.CRS.Properties.Name != "" {
+			crs = fc.CRS.Properties.Name
+		} else {
+			crs = defaultPointsCRS
+		}
+	}
+
+	pool := memory.NewGoAllocator()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+		},
+		nil,
+	)
+
+	lat_builder := array.NewFloat64Builder(pool)
+	long_builder := array.NewFloat64Builder(pool)
+	mval_builder := array.NewFloat64Builder(pool)
+
+	defer lat_builder.Release()
+	defer long_builder.Release()
+	defer mval_builder.Release()
+
+	for featureIdx, feature := range fc.Features {
+		coords, err := pointsFromGeometry(feature.Geometry)
+		if err != nil {
+			return Points{}, fmt.Errorf("feature %d: %w", featureIdx, err)
+		}
+
+		mValues, err := measuresForPointsFeature(feature, coords)
+		if err != nil {
+			return Points{}, fmt.Errorf("feature %d: %w", featureIdx, err)
+		}
+
+		for ptIdx, coord := range coords {
+			if len(coord) < 2 {
+				return Points{}, fmt.Errorf("feature %d: coordinate %d has fewer than 2 members", featureIdx, ptIdx)
+			}
+			long_builder.Append(coord[0])
+			lat_builder.Append(coord[1])
+			mval_builder.Append(mValues[ptIdx])
+		}
+	}
+
+	lat_arr := lat_builder.NewArray()
+	long_arr := long_builder.NewArray()
+	mval_arr := mval_builder.NewArray()
+
+	defer lat_arr.Release()
+	defer long_arr.Release()
+	defer mval_arr.Release()
+
+	rec := array.NewRecordBatch(
+		schema,
+		[]arrow.Array{lat_arr, long_arr, mval_arr},
+		int64(lat_arr.Len()),
+	)
+
+	return NewPoints([]arrow.RecordBatch{rec}, crs), nil
+}
+
+// pointsFromGeometry normalizes Point and MultiPoint geometries into a
+// slice of coordinates.
+func pointsFromGeometry(geometry pointsGeometry) ([][]float64, error) {
+	switch geometry.Type {
+	case "Point":
+		var coord []float64
+		if err := json.Unmarshal(geometry.Coordinates, &coord); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal Point coordinates: %w", err)
+		}
+		return [][]float64{coord}, nil
+	case "MultiPoint":
+		var coords [][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal MultiPoint coordinates: %w", err)
+		}
+		return coords, nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q, expected Point or MultiPoint", geometry.Type)
+	}
+}
+
+// measuresForPointsFeature resolves the M value for every coordinate in
+// coords: from a 4-member coordinate ([lon, lat, z, m]) if every vertex
+// carries one, else the geometry's "measures" member if present, else the
+// feature's "m_values" property, else 0.
+func measuresForPointsFeature(feature pointsFeature, coords [][]float64) ([]float64, error) {
+	embedded := true
+	for _, coord := range coords {
+		if len(coord) < 4 {
+			embedded = false
+			break
+		}
+	}
+	if embedded {
+		out := make([]float64, len(coords))
+		for i, coord := range coords {
+			out[i] = coord[3]
+		}
+		return out, nil
+	}
+
+	if len(feature.Geometry.Measures) > 0 {
+		var measures []float64
+		if err := json.Unmarshal(feature.Geometry.Measures, &measures); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal measures: %w", err)
+		}
+		if len(measures) != len(coords) {
+			return nil, fmt.Errorf("measures has %d values, expected %d", len(measures), len(coords))
+		}
+		return measures, nil
+	}
+
+	raw, ok := feature.Properties[pointsMValuePropertyKey]
+	if !ok {
+		return make([]float64, len(coords)), nil
+	}
+
+	flat, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%q property must be an array", pointsMValuePropertyKey)
+	}
+	if len(flat) != len(coords) {
+		return nil, fmt.Errorf("%q has %d values, expected %d", pointsMValuePropertyKey, len(flat), len(coords))
+	}
+
+	out := make([]float64, len(flat))
+	for i, v := range flat {
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%q[%d] is not a number", pointsMValuePropertyKey, i)
+		}
+		out[i] = f
+	}
+	return out, nil
+}
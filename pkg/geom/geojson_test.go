@@ -0,0 +1,71 @@
+package geom
+
+import (
+	"testing"
+)
+
+func TestNewPointsFromGeoJSON(t *testing.T) {
+	t.Run(
+		"Point and MultiPoint features with embedded M", func(t *testing.T) {
+			data := []byte(`{
+				"type": "FeatureCollection",
+				"features": [
+					{"type": "Feature", "properties": {}, "geometry": {"type": "Point", "coordinates": [95.421, 5.648, 0, 10]}},
+					{"type": "Feature", "properties": {}, "geometry": {"type": "MultiPoint", "coordinates": [[95.422, 5.649, 0, 20], [95.423, 5.650, 0, 30]]}}
+				]
+			}`)
+
+			p, err := NewPointsFromGeoJSON(data, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer p.Release()
+
+			if p.GetCRS() != "EPSG:4326" {
+				t.Fatalf("expected default CRS EPSG:4326, got %s", p.GetCRS())
+			}
+
+			recs := p.GetRecords()
+			if len(recs) != 1 || recs[0].NumRows() != 3 {
+				t.Fatalf("expected 1 record batch with 3 rows, got %+v", recs)
+			}
+		},
+	)
+
+	t.Run(
+		"m_values property fallback and named crs", func(t *testing.T) {
+			data := []byte(`{
+				"type": "FeatureCollection",
+				"crs": {"type": "name", "properties": {"name": "urn:ogc:def:crs:EPSG::3857"}},
+				"features": [
+					{"type": "Feature", "properties": {"m_values": [5.5]}, "geometry": {"type": "Point", "coordinates": [95.421, 5.648]}}
+				]
+			}`)
+
+			p, err := NewPointsFromGeoJSON(data, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer p.Release()
+
+			if p.GetCRS() != "urn:ogc:def:crs:EPSG::3857" {
+				t.Fatalf("expected crs member to resolve, got %s", p.GetCRS())
+			}
+		},
+	)
+
+	t.Run(
+		"unsupported geometry type", func(t *testing.T) {
+			data := []byte(`{
+				"type": "FeatureCollection",
+				"features": [
+					{"type": "Feature", "properties": {}, "geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]}}
+				]
+			}`)
+
+			if _, err := NewPointsFromGeoJSON(data, ""); err == nil {
+				t.Fatal("expected error for unsupported geometry type")
+			}
+		},
+	)
+}
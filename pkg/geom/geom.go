@@ -9,6 +9,12 @@ const (
 	POINTS GeometryType = "points"
 )
 
+// LAMBERT_WKT is the Indonesia Lambert Conformal Conic projection every
+// M-Value calculation is carried out in: geographic (lat/lon) input is
+// always reprojected to this CRS first, since LRS routes are stored and
+// indexed in it.
+const LAMBERT_WKT = `PROJCS["Indonesia Lambert Conformal Conic",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Lambert_Conformal_Conic"],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",115.0],PARAMETER["Standard_Parallel_1",2.0],PARAMETER["Standard_Parallel_2",-7.0],PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`
+
 type Geometry interface {
 	GetCRS() string
 	GetRecords() []arrow.RecordBatch
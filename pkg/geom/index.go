@@ -0,0 +1,376 @@
+package geom
+
+import (
+	"math"
+	"sort"
+)
+
+// Comparator orders two index items, in the spirit of gostl's
+// Comparator func(a, b interface{}) int: negative if a sorts before b,
+// zero if equal, positive if a sorts after b. KDTree's build orders
+// candidates through a Comparator over their box centers; STRTree sorts box
+// centers directly. Both work on any Candidate with a BBox, so the same
+// build/traversal code backs indexes over different candidate kinds
+// (points today; polyline and polygon vertices later) without duplicating it.
+type Comparator func(a, b any) int
+
+// BBox is an axis-aligned bounding box used to order and prune candidates
+// during a spatial query.
+type BBox struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// Intersects reports whether b and o overlap, including touching edges.
+func (b BBox) Intersects(o BBox) bool {
+	return b.MinX <= o.MaxX && b.MaxX >= o.MinX && b.MinY <= o.MaxY && b.MaxY >= o.MinY
+}
+
+// Center returns the midpoint of the box.
+func (b BBox) Center() (x, y float64) {
+	return (b.MinX + b.MaxX) / 2, (b.MinY + b.MaxY) / 2
+}
+
+func union(a, b BBox) BBox {
+	return BBox{
+		MinX: math.Min(a.MinX, b.MinX),
+		MinY: math.Min(a.MinY, b.MinY),
+		MaxX: math.Max(a.MaxX, b.MaxX),
+		MaxY: math.Max(a.MaxY, b.MaxY),
+	}
+}
+
+// Candidate is a single entry held by a spatial index: an opaque item
+// (a point, a polyline segment, ...) alongside the bounding box used to
+// order and query it.
+type Candidate struct {
+	Item any
+	Box  BBox
+}
+
+// SpatialIndex is the query surface shared by every index in this package,
+// so callers (route snapping, future event types) don't need to know
+// whether they're querying a KDTree or an STRTree.
+type SpatialIndex interface {
+	// NearestK returns up to k candidates ordered by ascending distance
+	// from (x, y), measured to each candidate's box center.
+	NearestK(x, y float64, k int) []Candidate
+	// Within returns every candidate whose box intersects box.
+	Within(box BBox) []Candidate
+}
+
+func distSq(x, y float64, b BBox) float64 {
+	cx, cy := b.Center()
+	dx, dy := cx-x, cy-y
+	return dx*dx + dy*dy
+}
+
+// boxDistSq returns the squared distance from (x, y) to the nearest point
+// contained in b (0 if (x, y) is inside b). Because a box's center always
+// lies within the box itself, this is a valid lower bound on distSq(x, y,
+// box) for any descendant candidate whose box is contained in b -- the
+// property STRTree.NearestK's pruning relies on.
+func boxDistSq(x, y float64, b BBox) float64 {
+	dx := 0.0
+	if x < b.MinX {
+		dx = b.MinX - x
+	} else if x > b.MaxX {
+		dx = x - b.MaxX
+	}
+	dy := 0.0
+	if y < b.MinY {
+		dy = b.MinY - y
+	} else if y > b.MaxY {
+		dy = y - b.MaxY
+	}
+	return dx*dx + dy*dy
+}
+
+// byDistance sorts candidates by ascending distance from a fixed point.
+type byDistance struct {
+	items []Candidate
+	x, y  float64
+}
+
+func (s byDistance) Len() int      { return len(s.items) }
+func (s byDistance) Swap(i, j int) { s.items[i], s.items[j] = s.items[j], s.items[i] }
+func (s byDistance) Less(i, j int) bool {
+	return distSq(s.x, s.y, s.items[i].Box) < distSq(s.x, s.y, s.items[j].Box)
+}
+
+// KDTree is a 2D k-d tree over point candidates, used to snap individual
+// points to their nearest neighbors.
+type KDTree struct {
+	root *kdNode
+}
+
+type kdNode struct {
+	candidate   Candidate
+	left, right *kdNode
+}
+
+// NewKDTree builds a balanced k-d tree over items. Building happens once;
+// NearestK and Within then reuse it for every query.
+func NewKDTree(items []Candidate) *KDTree {
+	cp := make([]Candidate, len(items))
+	copy(cp, items)
+	return &KDTree{root: buildKDNode(cp, 0)}
+}
+
+func buildKDNode(items []Candidate, depth int) *kdNode {
+	if len(items) == 0 {
+		return nil
+	}
+
+	axis := depth % 2
+	cmp := Comparator(func(a, b any) int {
+		ca, cb := a.(Candidate), b.(Candidate)
+		ax, ay := ca.Box.Center()
+		bx, by := cb.Box.Center()
+		if axis == 0 {
+			return cmpFloat(ax, bx)
+		}
+		return cmpFloat(ay, by)
+	})
+	sort.Slice(items, func(i, j int) bool { return cmp(items[i], items[j]) < 0 })
+
+	mid := len(items) / 2
+	node := &kdNode{candidate: items[mid]}
+	node.left = buildKDNode(items[:mid], depth+1)
+	node.right = buildKDNode(items[mid+1:], depth+1)
+	return node
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// NearestK returns up to k candidates nearest to (x, y), pruning subtrees
+// whose splitting plane is already farther than the current k-th best
+// distance.
+func (t *KDTree) NearestK(x, y float64, k int) []Candidate {
+	if t.root == nil || k <= 0 {
+		return nil
+	}
+
+	best := make([]Candidate, 0, k)
+	var visit func(n *kdNode, depth int)
+	visit = func(n *kdNode, depth int) {
+		if n == nil {
+			return
+		}
+
+		best = insertBest(best, n.candidate, x, y, k)
+
+		axis := depth % 2
+		cx, cy := n.candidate.Box.Center()
+		var planeDist, diff float64
+		if axis == 0 {
+			diff = x - cx
+		} else {
+			diff = y - cy
+		}
+		planeDist = diff * diff
+
+		near, far := n.left, n.right
+		if diff > 0 {
+			near, far = n.right, n.left
+		}
+
+		visit(near, depth+1)
+		if len(best) < k || planeDist < distSq(x, y, best[len(best)-1].Box) {
+			visit(far, depth+1)
+		}
+	}
+	visit(t.root, 0)
+
+	return best
+}
+
+// insertBest keeps best sorted by ascending distance from (x, y) and
+// truncated to at most k entries.
+func insertBest(best []Candidate, c Candidate, x, y float64, k int) []Candidate {
+	best = append(best, c)
+	sort.Sort(byDistance{items: best, x: x, y: y})
+	if len(best) > k {
+		best = best[:k]
+	}
+	return best
+}
+
+// Within returns every candidate whose box intersects box, via a full
+// traversal (a k-d tree splits on point coordinates, not boxes, so every
+// subtree may contain a match).
+func (t *KDTree) Within(box BBox) []Candidate {
+	var out []Candidate
+	var visit func(n *kdNode)
+	visit = func(n *kdNode) {
+		if n == nil {
+			return
+		}
+		if n.candidate.Box.Intersects(box) {
+			out = append(out, n.candidate)
+		}
+		visit(n.left)
+		visit(n.right)
+	}
+	visit(t.root)
+	return out
+}
+
+// strLeafSize is the target number of candidates per leaf node, following
+// JTS/gostl-style STR-tree defaults.
+const strLeafSize = 10
+
+// STRTree is a sort-tile-recursive tree over box candidates (polyline
+// segments, polygon rings), bulk-loaded once and then queried repeatedly.
+type STRTree struct {
+	root *strNode
+}
+
+type strNode struct {
+	box       BBox
+	candidate *Candidate // set on leaf nodes only
+	children  []*strNode
+}
+
+// NewSTRTree bulk-loads an STR-tree over items: candidates are sorted into
+// vertical slices by box center X, each slice sorted by center Y and cut
+// into leaves of strLeafSize, and the resulting leaves are recursively
+// packed the same way until a single root remains.
+func NewSTRTree(items []Candidate) *STRTree {
+	if len(items) == 0 {
+		return &STRTree{}
+	}
+
+	leaves := make([]*strNode, len(items))
+	for i, c := range items {
+		c := c
+		leaves[i] = &strNode{box: c.Box, candidate: &c}
+	}
+
+	return &STRTree{root: packSTR(leaves)}
+}
+
+// packSTR packs nodes into parents of up to strLeafSize children, tiling
+// by X then Y, until a single node remains.
+func packSTR(nodes []*strNode) *strNode {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+
+	numLeaves := len(nodes)
+	sliceCount := int(math.Ceil(math.Sqrt(float64((numLeaves + strLeafSize - 1) / strLeafSize))))
+	if sliceCount < 1 {
+		sliceCount = 1
+	}
+	sliceSize := int(math.Ceil(float64(numLeaves) / float64(sliceCount)))
+
+	sort.Slice(nodes, func(i, j int) bool {
+		xi, _ := nodes[i].box.Center()
+		xj, _ := nodes[j].box.Center()
+		return xi < xj
+	})
+
+	var parents []*strNode
+	for s := 0; s < len(nodes); s += sliceSize {
+		end := s + sliceSize
+		if end > len(nodes) {
+			end = len(nodes)
+		}
+		slice := nodes[s:end]
+
+		sort.Slice(slice, func(i, j int) bool {
+			_, yi := slice[i].box.Center()
+			_, yj := slice[j].box.Center()
+			return yi < yj
+		})
+
+		for g := 0; g < len(slice); g += strLeafSize {
+			gEnd := g + strLeafSize
+			if gEnd > len(slice) {
+				gEnd = len(slice)
+			}
+			group := slice[g:gEnd]
+
+			box := group[0].box
+			for _, n := range group[1:] {
+				box = union(box, n.box)
+			}
+			parents = append(parents, &strNode{box: box, children: group})
+		}
+	}
+
+	if len(parents) == len(nodes) {
+		// No further packing possible (every slice already fit in one
+		// group); promote the parents directly to avoid looping forever.
+		return &strNode{box: parents[0].box, children: parents}
+	}
+	return packSTR(parents)
+}
+
+// NearestK returns up to k candidates nearest to (x, y), pruning subtrees
+// whose box (a lower bound on the distance to any candidate center inside
+// it, see boxDistSq) is already farther than the current k-th best
+// distance, and visiting nearer children first so that bound tightens as
+// early as possible.
+func (t *STRTree) NearestK(x, y float64, k int) []Candidate {
+	if t.root == nil || k <= 0 {
+		return nil
+	}
+
+	best := make([]Candidate, 0, k)
+	var visit func(n *strNode)
+	visit = func(n *strNode) {
+		if len(best) >= k && boxDistSq(x, y, n.box) > distSq(x, y, best[len(best)-1].Box) {
+			return
+		}
+
+		if n.candidate != nil {
+			best = insertBest(best, *n.candidate, x, y, k)
+			return
+		}
+
+		children := append([]*strNode(nil), n.children...)
+		sort.Slice(children, func(i, j int) bool {
+			return boxDistSq(x, y, children[i].box) < boxDistSq(x, y, children[j].box)
+		})
+		for _, c := range children {
+			visit(c)
+		}
+	}
+	visit(t.root)
+
+	return best
+}
+
+// Within returns every candidate whose box intersects box, pruning
+// subtrees whose bounding box doesn't intersect it.
+func (t *STRTree) Within(box BBox) []Candidate {
+	if t.root == nil {
+		return nil
+	}
+
+	var out []Candidate
+	var visit func(n *strNode)
+	visit = func(n *strNode) {
+		if !n.box.Intersects(box) {
+			return
+		}
+		if n.candidate != nil {
+			out = append(out, *n.candidate)
+			return
+		}
+		for _, c := range n.children {
+			visit(c)
+		}
+	}
+	visit(t.root)
+	return out
+}
@@ -0,0 +1,85 @@
+package geom
+
+import "testing"
+
+func pointCandidates(pts [][2]float64) []Candidate {
+	out := make([]Candidate, len(pts))
+	for i, p := range pts {
+		out[i] = Candidate{
+			Item: p,
+			Box:  BBox{MinX: p[0], MaxX: p[0], MinY: p[1], MaxY: p[1]},
+		}
+	}
+	return out
+}
+
+func TestKDTreeNearestK(t *testing.T) {
+	t.Run("nearest single point", func(t *testing.T) {
+		tree := NewKDTree(pointCandidates([][2]float64{{0, 0}, {10, 10}, {1, 1}, {5, 5}}))
+
+		got := tree.NearestK(0, 0, 1)
+		if len(got) != 1 {
+			t.Fatalf("expected 1 candidate, got %d", len(got))
+		}
+		x, y := got[0].Box.Center()
+		if x != 0 || y != 0 {
+			t.Errorf("expected nearest to be (0,0), got (%v,%v)", x, y)
+		}
+	})
+
+	t.Run("k larger than tree size", func(t *testing.T) {
+		tree := NewKDTree(pointCandidates([][2]float64{{0, 0}, {1, 1}}))
+		got := tree.NearestK(0, 0, 5)
+		if len(got) != 2 {
+			t.Errorf("expected 2 candidates, got %d", len(got))
+		}
+	})
+
+	t.Run("empty tree", func(t *testing.T) {
+		tree := NewKDTree(nil)
+		if got := tree.NearestK(0, 0, 3); got != nil {
+			t.Errorf("expected nil, got %v", got)
+		}
+	})
+}
+
+func TestKDTreeWithin(t *testing.T) {
+	tree := NewKDTree(pointCandidates([][2]float64{{0, 0}, {10, 10}, {1, 1}, {5, 5}}))
+
+	got := tree.Within(BBox{MinX: -1, MaxX: 2, MinY: -1, MaxY: 2})
+	if len(got) != 2 {
+		t.Errorf("expected 2 candidates within box, got %d", len(got))
+	}
+}
+
+func TestSTRTreeNearestKAndWithin(t *testing.T) {
+	var pts [][2]float64
+	for i := 0; i < 50; i++ {
+		pts = append(pts, [2]float64{float64(i), float64(i)})
+	}
+	tree := NewSTRTree(pointCandidates(pts))
+
+	nearest := tree.NearestK(0, 0, 3)
+	if len(nearest) != 3 {
+		t.Fatalf("expected 3 candidates, got %d", len(nearest))
+	}
+	x, y := nearest[0].Box.Center()
+	if x != 0 || y != 0 {
+		t.Errorf("expected nearest to be (0,0), got (%v,%v)", x, y)
+	}
+
+	within := tree.Within(BBox{MinX: 9, MaxX: 11, MinY: 9, MaxY: 11})
+	if len(within) != 3 {
+		t.Errorf("expected 3 candidates within box, got %d", len(within))
+	}
+}
+
+func TestSTRTreeEmpty(t *testing.T) {
+	tree := NewSTRTree(nil)
+	if got := tree.NearestK(0, 0, 1); got != nil {
+		t.Errorf("expected nil, got %v", got)
+	}
+	if got := tree.Within(BBox{}); got != nil {
+		t.Errorf("expected nil, got %v", got)
+	}
+}
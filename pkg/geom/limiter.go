@@ -0,0 +1,457 @@
+package geom
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/duckdb/duckdb-go/v2"
+)
+
+// Vertex is a single lat/lon/M position, the unit Limiter.ClipLine
+// operates on. Callers (route.LRSRoute.Filter/Clip, Points.Filter) convert
+// their own row/vertex representations to and from Vertex at the Limiter
+// boundary.
+type Vertex struct {
+	Lat, Lon, M float64
+}
+
+// Limiter filters or clips geometry against a polygon/multipolygon
+// boundary loaded from GeoJSON, in the spirit of imposm3's -limitto
+// workflow. It holds its own DuckDB connection with the spatial extension
+// loaded, and is safe to reuse across many LRSRoute/Points objects -- e.g.
+// tiling a national LRS network export by province boundary -- reusing
+// the one connection and reprojecting its boundary into each object's CRS
+// on demand, caching the reprojected WKT per CRS.
+type Limiter struct {
+	db  *sql.DB
+	arr *duckdb.Arrow
+
+	boundaryCRS string
+	boundaryWKT string // already buffered by bufferMeters, in boundaryCRS's units
+
+	mu      sync.Mutex
+	byCRS   map[string]string // target CRS -> boundary WKT reprojected into it
+	viewSeq uint64
+}
+
+// NewLimiterFromGeoJSON reads the Polygon/MultiPolygon boundary at path (a
+// FeatureCollection, a Feature, or a bare geometry) and returns a Limiter
+// that filters or clips against it, in crs. bufferMeters, if non-zero,
+// expands the boundary via ST_Buffer once at construction; the buffer is
+// applied in crs's own units, so pass a projected (metric) crs if
+// bufferMeters needs to mean meters.
+func NewLimiterFromGeoJSON(path string, bufferMeters float64, crs string) (*Limiter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read limit-to boundary %s: %w", path, err)
+	}
+
+	wkt, err := boundaryWKTFromGeoJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse limit-to boundary: %w", err)
+	}
+
+	c, err := duckdb.NewConnector("", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := c.Connect(context.Background())
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	ar, err := duckdb.NewArrowFromConn(conn)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	db := sql.OpenDB(c)
+	if _, err := db.ExecContext(context.Background(), "install spatial; load spatial;"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load spatial extension: %w", err)
+	}
+
+	if bufferMeters != 0 {
+		row := db.QueryRowContext(context.Background(),
+			"select ST_AsText(ST_Buffer(ST_GeomFromText(?), ?))", wkt, bufferMeters)
+		if err := row.Scan(&wkt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to buffer limit-to boundary: %w", err)
+		}
+	}
+
+	return &Limiter{
+		db:          db,
+		arr:         ar,
+		boundaryCRS: crs,
+		boundaryWKT: wkt,
+		byCRS:       make(map[string]string),
+	}, nil
+}
+
+// Close releases the Limiter's DuckDB connection.
+func (lim *Limiter) Close() error {
+	return lim.db.Close()
+}
+
+// boundaryInCRS returns the Limiter's boundary WKT reprojected into crs,
+// resolving and caching the reprojection the first time crs is requested.
+func (lim *Limiter) boundaryInCRS(crs string) (string, error) {
+	if crs == lim.boundaryCRS {
+		return lim.boundaryWKT, nil
+	}
+
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	if wkt, ok := lim.byCRS[crs]; ok {
+		return wkt, nil
+	}
+
+	var wkt string
+	row := lim.db.QueryRowContext(context.Background(),
+		"select ST_AsText(ST_Transform(ST_GeomFromText(?), ?, ?))",
+		lim.boundaryWKT, lim.boundaryCRS, crs,
+	)
+	if err := row.Scan(&wkt); err != nil {
+		return "", fmt.Errorf("failed to reproject limit-to boundary to %s: %w", crs, err)
+	}
+
+	lim.byCRS[crs] = wkt
+	return wkt, nil
+}
+
+// nextView returns a name to register an Arrow view under that has not
+// been used before by lim, so concurrent calls never collide.
+func (lim *Limiter) nextView() string {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	lim.viewSeq++
+	return fmt.Sprintf("limiter_view_%d", lim.viewSeq)
+}
+
+// FilterRecords returns the rows of records whose (latCol, lonCol) point
+// falls inside lim's boundary (reprojected into crs on demand), dropping
+// every row outside it and preserving every other column unchanged. This
+// is the filter-mode primitive shared by Points.Filter and
+// route.LRSRoute.Filter.
+func (lim *Limiter) FilterRecords(records []arrow.RecordBatch, latCol, lonCol, crs string) ([]arrow.RecordBatch, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	boundary, err := lim.boundaryInCRS(crs)
+	if err != nil {
+		return nil, err
+	}
+
+	rr, err := array.NewRecordReader(records[0].Schema(), records)
+	if err != nil {
+		return nil, err
+	}
+	defer rr.Release()
+
+	view := lim.nextView()
+	release, err := lim.arr.RegisterView(rr, view)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	query := fmt.Sprintf(
+		"select * from %s where ST_Contains(ST_GeomFromText(%s), ST_Point(%s, %s))",
+		view, sqlQuote(boundary), lonCol, latCol,
+	)
+
+	reader, err := lim.arr.QueryContext(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+
+	var out []arrow.RecordBatch
+	for reader.Next() {
+		rec := reader.RecordBatch()
+		rec.Retain()
+		out = append(out, rec)
+	}
+	return out, nil
+}
+
+// ClipLine splits the ordered polyline vertices into the pieces that fall
+// inside lim's boundary (reprojected into crs on demand), via DuckDB's
+// ST_Intersection. Each returned piece is itself ordered from the
+// original line's start toward its end; M values on original vertices are
+// preserved exactly, and linearly interpolated along the crossed segment
+// for the new vertices introduced where the line crosses the boundary.
+// This is the clip-mode primitive behind route.LRSRoute.Clip.
+func (lim *Limiter) ClipLine(vertices []Vertex, crs string) ([][]Vertex, error) {
+	if len(vertices) < 2 {
+		return nil, fmt.Errorf("need at least 2 vertices to clip a line")
+	}
+
+	boundary, err := lim.boundaryInCRS(crs)
+	if err != nil {
+		return nil, err
+	}
+
+	var resultWKT string
+	row := lim.db.QueryRowContext(context.Background(),
+		"select ST_AsText(ST_Intersection(ST_GeomFromText(?), ST_GeomFromText(?)))",
+		lineStringWKT(vertices), boundary,
+	)
+	if err := row.Scan(&resultWKT); err != nil {
+		return nil, fmt.Errorf("failed to clip line against limit-to boundary: %w", err)
+	}
+
+	lines, err := parseMultiLineStringWKT(resultWKT)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([][]Vertex, 0, len(lines))
+	for _, line := range lines {
+		piece := make([]Vertex, len(line))
+		for i, pt := range line {
+			piece[i] = Vertex{Lat: pt[1], Lon: pt[0], M: interpolateM(vertices, pt[0], pt[1])}
+		}
+		out = append(out, piece)
+	}
+	return out, nil
+}
+
+// interpolateM returns the M value at (lon, lat), assumed to lie on (or
+// extremely near, allowing for floating point error) one of vertices'
+// segments, by finding that segment and linearly interpolating between
+// its endpoints' M values at the point's projected fraction along it.
+func interpolateM(vertices []Vertex, lon, lat float64) float64 {
+	best := 0.0
+	bestDist := math.Inf(1)
+
+	for i := 0; i+1 < len(vertices); i++ {
+		a, b := vertices[i], vertices[i+1]
+		dx, dy := b.Lon-a.Lon, b.Lat-a.Lat
+
+		t := 0.0
+		if length2 := dx*dx + dy*dy; length2 > 0 {
+			t = ((lon-a.Lon)*dx + (lat-a.Lat)*dy) / length2
+			t = math.Max(0, math.Min(1, t))
+		}
+
+		px, py := a.Lon+t*dx, a.Lat+t*dy
+		if dist := (px-lon)*(px-lon) + (py-lat)*(py-lat); dist < bestDist {
+			bestDist = dist
+			best = a.M + t*(b.M-a.M)
+		}
+	}
+
+	return best
+}
+
+// sqlQuote renders s as a single-quoted SQL string literal, escaping any
+// embedded quotes; used to inline a boundary WKT into a query, the same
+// string-building convention projection.transformQuery already uses for
+// CRS identifiers.
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// lineStringWKT renders vertices as a WKT LINESTRING in (lon, lat) order.
+func lineStringWKT(vertices []Vertex) string {
+	pts := make([]string, len(vertices))
+	for i, v := range vertices {
+		pts[i] = fmt.Sprintf("%g %g", v.Lon, v.Lat)
+	}
+	return "LINESTRING (" + strings.Join(pts, ", ") + ")"
+}
+
+// limiterGeometry is the minimal GeoJSON geometry shape boundaryWKTFromGeoJSON
+// needs: a type tag and raw coordinates, decoded only once the type is known.
+type limiterGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+type limiterFeature struct {
+	Type     string          `json:"type"`
+	Geometry limiterGeometry `json:"geometry"`
+}
+
+type limiterFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []limiterFeature `json:"features"`
+}
+
+// boundaryWKTFromGeoJSON extracts a Polygon or MultiPolygon boundary from
+// data -- a FeatureCollection (its first feature), a single Feature, or a
+// bare geometry object -- and renders it as WKT for DuckDB's spatial
+// functions.
+func boundaryWKTFromGeoJSON(data []byte) (string, error) {
+	var probe struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return "", fmt.Errorf("failed to unmarshal geojson: %w", err)
+	}
+
+	var geometry limiterGeometry
+	switch probe.Type {
+	case "FeatureCollection":
+		var fc limiterFeatureCollection
+		if err := json.Unmarshal(data, &fc); err != nil {
+			return "", err
+		}
+		if len(fc.Features) == 0 {
+			return "", fmt.Errorf("feature collection has no features")
+		}
+		geometry = fc.Features[0].Geometry
+	case "Feature":
+		var f limiterFeature
+		if err := json.Unmarshal(data, &f); err != nil {
+			return "", err
+		}
+		geometry = f.Geometry
+	case "Polygon", "MultiPolygon":
+		if err := json.Unmarshal(data, &geometry); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported boundary type %q, expected Polygon, MultiPolygon, Feature, or FeatureCollection", probe.Type)
+	}
+
+	switch geometry.Type {
+	case "Polygon":
+		var rings [][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &rings); err != nil {
+			return "", fmt.Errorf("failed to unmarshal Polygon coordinates: %w", err)
+		}
+		return polygonWKT(rings), nil
+	case "MultiPolygon":
+		var polys [][][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polys); err != nil {
+			return "", fmt.Errorf("failed to unmarshal MultiPolygon coordinates: %w", err)
+		}
+		return multiPolygonWKT(polys), nil
+	default:
+		return "", fmt.Errorf("unsupported boundary geometry type %q, expected Polygon or MultiPolygon", geometry.Type)
+	}
+}
+
+func ringWKT(ring [][]float64) string {
+	pts := make([]string, len(ring))
+	for i, pt := range ring {
+		pts[i] = fmt.Sprintf("%g %g", pt[0], pt[1])
+	}
+	return "(" + strings.Join(pts, ", ") + ")"
+}
+
+func polygonWKT(rings [][][]float64) string {
+	parts := make([]string, len(rings))
+	for i, ring := range rings {
+		parts[i] = ringWKT(ring)
+	}
+	return "POLYGON (" + strings.Join(parts, ", ") + ")"
+}
+
+func multiPolygonWKT(polys [][][][]float64) string {
+	parts := make([]string, len(polys))
+	for i, rings := range polys {
+		ringParts := make([]string, len(rings))
+		for j, ring := range rings {
+			ringParts[j] = ringWKT(ring)
+		}
+		parts[i] = "(" + strings.Join(ringParts, ", ") + ")"
+	}
+	return "MULTIPOLYGON (" + strings.Join(parts, ", ") + ")"
+}
+
+// parseMultiLineStringWKT parses a WKT LINESTRING or MULTILINESTRING (as
+// DuckDB's ST_AsText renders it) into one [lon, lat] slice per line.
+// Intersections that degenerate to a POINT, MULTIPOINT, or an empty
+// geometry (the line never enters the boundary) yield no lines.
+func parseMultiLineStringWKT(wkt string) ([][][2]float64, error) {
+	wkt = strings.TrimSpace(wkt)
+
+	switch {
+	case strings.Contains(wkt, "EMPTY"):
+		return nil, nil
+	case strings.HasPrefix(wkt, "MULTILINESTRING"):
+		body := strings.TrimSpace(strings.TrimPrefix(wkt, "MULTILINESTRING"))
+		body = strings.TrimPrefix(body, "(")
+		body = strings.TrimSuffix(body, ")")
+
+		var lines [][][2]float64
+		for _, part := range splitTopLevel(body) {
+			line, err := parseLineStringCoords(part)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, line)
+		}
+		return lines, nil
+	case strings.HasPrefix(wkt, "LINESTRING"):
+		line, err := parseLineStringCoords(strings.TrimSpace(strings.TrimPrefix(wkt, "LINESTRING")))
+		if err != nil {
+			return nil, err
+		}
+		return [][][2]float64{line}, nil
+	case strings.HasPrefix(wkt, "POINT"), strings.HasPrefix(wkt, "MULTIPOINT"):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported clip result geometry: %s", wkt)
+	}
+}
+
+// parseLineStringCoords parses a single "(lon lat, lon lat, ...)" ring,
+// optionally still wrapped in its own parentheses, into coordinate pairs.
+func parseLineStringCoords(s string) ([][2]float64, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "(")
+	s = strings.TrimSuffix(s, ")")
+
+	fields := strings.Split(s, ",")
+	coords := make([][2]float64, 0, len(fields))
+	for _, f := range fields {
+		var lon, lat float64
+		if _, err := fmt.Sscanf(strings.TrimSpace(f), "%g %g", &lon, &lat); err != nil {
+			return nil, fmt.Errorf("failed to parse coordinate %q: %w", f, err)
+		}
+		coords = append(coords, [2]float64{lon, lat})
+	}
+	return coords, nil
+}
+
+// splitTopLevel splits a MULTILINESTRING body like "(1 2, 3 4), (5 6, 7 8)"
+// into ["(1 2, 3 4)", "(5 6, 7 8)"], respecting paren nesting so commas
+// inside each line's own parens aren't treated as separators.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth := 0
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, strings.TrimSpace(s[start:]))
+	return parts
+}
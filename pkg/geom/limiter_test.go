@@ -0,0 +1,126 @@
+package geom
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestBoundaryWKTFromGeoJSON(t *testing.T) {
+	fc := []byte(`{
+		"type": "FeatureCollection",
+		"features": [{
+			"type": "Feature",
+			"properties": {},
+			"geometry": {
+				"type": "Polygon",
+				"coordinates": [[[95.0, 5.0], [96.0, 5.0], [96.0, 6.0], [95.0, 6.0], [95.0, 5.0]]]
+			}
+		}]
+	}`)
+
+	wkt, err := boundaryWKTFromGeoJSON(fc)
+	if err != nil {
+		t.Fatalf("boundaryWKTFromGeoJSON failed: %v", err)
+	}
+	want := "POLYGON ((95 5, 96 5, 96 6, 95 6, 95 5))"
+	if wkt != want {
+		t.Errorf("expected %q, got %q", want, wkt)
+	}
+}
+
+func TestParseMultiLineStringWKT(t *testing.T) {
+	t.Run("linestring", func(t *testing.T) {
+		lines, err := parseMultiLineStringWKT("LINESTRING (95 5, 96 5)")
+		if err != nil {
+			t.Fatalf("parseMultiLineStringWKT failed: %v", err)
+		}
+		if len(lines) != 1 || len(lines[0]) != 2 {
+			t.Fatalf("expected 1 line with 2 points, got %v", lines)
+		}
+	})
+
+	t.Run("multilinestring", func(t *testing.T) {
+		lines, err := parseMultiLineStringWKT("MULTILINESTRING ((95 5, 96 5), (97 5, 98 5))")
+		if err != nil {
+			t.Fatalf("parseMultiLineStringWKT failed: %v", err)
+		}
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 lines, got %d", len(lines))
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		lines, err := parseMultiLineStringWKT("LINESTRING EMPTY")
+		if err != nil {
+			t.Fatalf("parseMultiLineStringWKT failed: %v", err)
+		}
+		if lines != nil {
+			t.Errorf("expected no lines, got %v", lines)
+		}
+	})
+}
+
+func TestInterpolateM(t *testing.T) {
+	line := []Vertex{{Lat: 0, Lon: 0, M: 0}, {Lat: 0, Lon: 10, M: 100}}
+
+	if got := interpolateM(line, 5, 0); got != 50 {
+		t.Errorf("expected M=50 at the segment midpoint, got %v", got)
+	}
+}
+
+func TestPointsFilter(t *testing.T) {
+	boundaryFile := filepath.Join(t.TempDir(), "boundary.geojson")
+	boundary := `{"type":"Polygon","coordinates":[[[95.0,5.0],[96.0,5.0],[96.0,6.0],[95.0,6.0],[95.0,5.0]]]}`
+	if err := os.WriteFile(boundaryFile, []byte(boundary), 0o644); err != nil {
+		t.Fatalf("failed to write boundary file: %v", err)
+	}
+
+	lim, err := NewLimiterFromGeoJSON(boundaryFile, 0, "EPSG:4326")
+	if err != nil {
+		t.Fatalf("NewLimiterFromGeoJSON failed: %v", err)
+	}
+	defer lim.Close()
+
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+		},
+		nil,
+	)
+
+	builder := array.NewRecordBuilder(pool, schema)
+	defer builder.Release()
+
+	// One point inside the boundary, one well outside it.
+	builder.Field(0).(*array.Float64Builder).AppendValues([]float64{5.5, 50.0}, nil)
+	builder.Field(1).(*array.Float64Builder).AppendValues([]float64{95.5, 150.0}, nil)
+	builder.Field(2).(*array.Float64Builder).AppendValues([]float64{0, 0}, nil)
+
+	rec := builder.NewRecordBatch()
+	defer rec.Release()
+
+	p := NewPoints([]arrow.RecordBatch{rec}, "EPSG:4326")
+	defer p.Release()
+
+	filtered, err := p.Filter(lim)
+	if err != nil {
+		t.Fatalf("Filter failed: %v", err)
+	}
+	defer filtered.Release()
+
+	var kept int
+	for _, r := range filtered.GetRecords() {
+		kept += int(r.NumRows())
+	}
+	if kept != 1 {
+		t.Errorf("expected 1 point inside the boundary, got %d", kept)
+	}
+}
@@ -50,3 +50,14 @@ func (p *Points) GetAttributes() map[string]any {
 
 	return out
 }
+
+// Filter returns a copy of p containing only the points that fall inside
+// lim's boundary (reprojected into p's CRS on demand), dropping every
+// other point.
+func (p *Points) Filter(lim *Limiter) (Points, error) {
+	filtered, err := lim.FilterRecords(p.records, p.LatitudeColumn, p.LongitudeColumn, p.crs)
+	if err != nil {
+		return Points{}, err
+	}
+	return NewPoints(filtered, p.crs), nil
+}
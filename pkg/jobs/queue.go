@@ -0,0 +1,269 @@
+// Package jobs implements a small buffered worker pool for running
+// long-running LRS operations (chiefly M-Value calculation over large
+// point sets) asynchronously, so a caller can submit a request and poll
+// for status/progress instead of holding a single streaming connection
+// open for the whole computation.
+package jobs
+
+import (
+	"bm-lrs/pkg/progress"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// State is a Job's lifecycle stage.
+type State string
+
+const (
+	StatePending State = "pending"
+	StateRunning State = "running"
+	StateDone    State = "done"
+	StateFailed  State = "failed"
+)
+
+// Progress reports how far a running Job has gotten, in the same
+// done/total shape flight's in-band progress frames use.
+type Progress struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total"`
+}
+
+// Job is one submitted unit of work: its lifecycle state, progress, and
+// -- once State is StateDone -- the URI of its materialized result.
+// Queue persists Job as JSON under its data directory after every
+// transition, so GET /api/v1/jobs/{id} (and Flight's get_status action)
+// can resolve a job even if the process restarted mid-run.
+type Job struct {
+	ID        string    `json:"id"`
+	State     State     `json:"state"`
+	Progress  Progress  `json:"progress"`
+	Error     string    `json:"error,omitempty"`
+	ResultURI string    `json:"result_uri,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Task is the work a Queue runs for one Job: compute a result, write it
+// somewhere durable (a Parquet file in every caller this package has
+// today), and return its URI so ResultURI can be resolved into a stream
+// later. report lets a long-running Task update the Job's Progress as it
+// goes; wrap it with ProgressReporter to hand it to code that already
+// takes a progress.Progress, such as mvalue.CalculatePointsMValue's
+// callers.
+type Task func(ctx context.Context, report func(done, total int64)) (resultURI string, err error)
+
+// DefaultConcurrency is Queue's worker count when no WithConcurrency
+// option is supplied.
+const DefaultConcurrency = 4
+
+// Queue is a buffered worker pool of fixed concurrency that runs
+// submitted Tasks and persists each Job's state to disk as it
+// progresses.
+type Queue struct {
+	dir         string
+	concurrency int
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+
+	submit chan queuedTask
+	wg     sync.WaitGroup
+}
+
+type queuedTask struct {
+	job  *Job
+	task Task
+}
+
+// QueueOption configures optional behavior of a Queue.
+type QueueOption func(*Queue)
+
+// WithConcurrency overrides the number of Tasks Queue runs concurrently.
+// Defaults to DefaultConcurrency.
+func WithConcurrency(n int) QueueOption {
+	return func(q *Queue) {
+		q.concurrency = n
+	}
+}
+
+// NewQueue creates a Queue whose job metadata is persisted under dir
+// (created if it doesn't already exist) and starts its worker pool.
+// Call Close to stop the pool once the Queue is no longer needed.
+func NewQueue(dir string, opts ...QueueOption) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create jobs directory: %w", err)
+	}
+
+	q := &Queue{
+		dir:         dir,
+		concurrency: DefaultConcurrency,
+		jobs:        make(map[string]*Job),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	q.submit = make(chan queuedTask, q.concurrency*4)
+
+	for i := 0; i < q.concurrency; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+
+	return q, nil
+}
+
+// Submit enqueues task under a new job ID and returns it immediately; the
+// task runs asynchronously on the worker pool.
+func (q *Queue) Submit(task Task) (string, error) {
+	id, err := newJobID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	job := &Job{ID: id, State: StatePending, CreatedAt: now, UpdatedAt: now}
+
+	q.mu.Lock()
+	q.jobs[id] = job
+	q.mu.Unlock()
+
+	if err := q.persist(job); err != nil {
+		return "", err
+	}
+
+	q.submit <- queuedTask{job: job, task: task}
+	return id, nil
+}
+
+// Get returns a copy of the job with id, falling back to the persisted
+// JSON on disk if it isn't held in memory (e.g. after a restart).
+func (q *Queue) Get(id string) (Job, error) {
+	q.mu.Lock()
+	job, ok := q.jobs[id]
+	q.mu.Unlock()
+	if ok {
+		return *job, nil
+	}
+
+	return q.load(id)
+}
+
+// Close stops accepting new submissions and waits for every in-flight
+// Task to finish.
+func (q *Queue) Close() {
+	close(q.submit)
+	q.wg.Wait()
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for qt := range q.submit {
+		q.run(qt.job, qt.task)
+	}
+}
+
+func (q *Queue) run(job *Job, task Task) {
+	q.transition(job, func(j *Job) { j.State = StateRunning })
+
+	report := func(done, total int64) {
+		q.transition(job, func(j *Job) { j.Progress = Progress{Done: done, Total: total} })
+	}
+
+	uri, err := task(context.Background(), report)
+
+	q.transition(job, func(j *Job) {
+		if err != nil {
+			j.State = StateFailed
+			j.Error = err.Error()
+			return
+		}
+		j.State = StateDone
+		j.ResultURI = uri
+	})
+}
+
+// transition applies mutate to job under q's lock, stamps UpdatedAt, and
+// persists the result; persist errors are swallowed here (the in-memory
+// job map is still authoritative for the life of the process) so a
+// transient disk failure doesn't abort a running Task.
+func (q *Queue) transition(job *Job, mutate func(*Job)) {
+	q.mu.Lock()
+	mutate(job)
+	job.UpdatedAt = time.Now()
+	snapshot := *job
+	q.mu.Unlock()
+
+	_ = q.persistSnapshot(snapshot)
+}
+
+func (q *Queue) persist(job *Job) error {
+	q.mu.Lock()
+	snapshot := *job
+	q.mu.Unlock()
+	return q.persistSnapshot(snapshot)
+}
+
+func (q *Queue) persistSnapshot(job Job) error {
+	blob, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal job %s: %w", job.ID, err)
+	}
+	if err := os.WriteFile(filepath.Join(q.dir, job.ID+".json"), blob, 0o644); err != nil {
+		return fmt.Errorf("failed to persist job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+func (q *Queue) load(id string) (Job, error) {
+	blob, err := os.ReadFile(filepath.Join(q.dir, id+".json"))
+	if err != nil {
+		return Job{}, fmt.Errorf("job %q not found: %w", id, err)
+	}
+	var job Job
+	if err := json.Unmarshal(blob, &job); err != nil {
+		return Job{}, fmt.Errorf("failed to parse job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+func newJobID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate job id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// ProgressReporter adapts report (a Task's progress callback) into a
+// progress.Progress, so code that already accepts one -- batch route
+// loading, mvalue's DuckDB query phase -- can report into a Job without
+// knowing about jobs.Queue.
+func ProgressReporter(report func(done, total int64)) progress.Progress {
+	return &reporterAdapter{report: report}
+}
+
+type reporterAdapter struct {
+	report      func(done, total int64)
+	done, total int64
+}
+
+func (r *reporterAdapter) Start(total int64) {
+	r.total = total
+	r.report(r.done, r.total)
+}
+
+func (r *reporterAdapter) Add(n int64) {
+	r.done += n
+	r.report(r.done, r.total)
+}
+
+func (r *reporterAdapter) Finish() {
+	r.report(r.done, r.total)
+}
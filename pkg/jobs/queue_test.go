@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueSubmitRunsToCompletion(t *testing.T) {
+	dir, err := os.MkdirTemp("", "lrs_jobs_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	q, err := NewQueue(dir, WithConcurrency(2))
+	require.NoError(t, err)
+	defer q.Close()
+
+	id, err := q.Submit(func(ctx context.Context, report func(done, total int64)) (string, error) {
+		report(0, 1)
+		report(1, 1)
+		return "file:///tmp/result.parquet", nil
+	})
+	require.NoError(t, err)
+
+	job := waitForTerminal(t, q, id)
+	assert.Equal(t, StateDone, job.State)
+	assert.Equal(t, "file:///tmp/result.parquet", job.ResultURI)
+	assert.Equal(t, int64(1), job.Progress.Done)
+	assert.Equal(t, int64(1), job.Progress.Total)
+}
+
+func TestQueueSubmitRecordsTaskError(t *testing.T) {
+	dir, err := os.MkdirTemp("", "lrs_jobs_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	q, err := NewQueue(dir)
+	require.NoError(t, err)
+	defer q.Close()
+
+	id, err := q.Submit(func(ctx context.Context, report func(done, total int64)) (string, error) {
+		return "", fmt.Errorf("boom")
+	})
+	require.NoError(t, err)
+
+	job := waitForTerminal(t, q, id)
+	assert.Equal(t, StateFailed, job.State)
+	assert.Equal(t, "boom", job.Error)
+}
+
+func TestQueueGetFallsBackToDisk(t *testing.T) {
+	dir, err := os.MkdirTemp("", "lrs_jobs_test_*")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	q, err := NewQueue(dir)
+	require.NoError(t, err)
+	defer q.Close()
+
+	id, err := q.Submit(func(ctx context.Context, report func(done, total int64)) (string, error) {
+		return "result.parquet", nil
+	})
+	require.NoError(t, err)
+	waitForTerminal(t, q, id)
+
+	// A fresh Queue over the same directory, simulating a process restart,
+	// should still resolve the job from its persisted JSON.
+	q2, err := NewQueue(dir)
+	require.NoError(t, err)
+	defer q2.Close()
+
+	job, err := q2.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, StateDone, job.State)
+	assert.Equal(t, "result.parquet", job.ResultURI)
+}
+
+func waitForTerminal(t *testing.T, q *Queue, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := q.Get(id)
+		require.NoError(t, err)
+		if job.State == StateDone || job.State == StateFailed {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s did not reach a terminal state in time", id)
+	return Job{}
+}
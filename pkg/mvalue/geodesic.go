@@ -0,0 +1,489 @@
+package mvalue
+
+import (
+	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/route"
+	"bm-lrs/pkg/route_event"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// Engine selects the implementation CalculatePointsMValue uses to match
+// points to their route and interpolate an M-Value.
+type Engine int
+
+const (
+	// EngineDuckDB pushes the nearest-segment search and M-Value
+	// interpolation down to DuckDB's spatial extension. It is the default.
+	EngineDuckDB Engine = iota
+
+	// EngineGeodesic walks each route's vertices in Go instead, via the
+	// same STR-tree route.LRSRoute.Index builds for in-process point
+	// snapping (see route_event.SnapToRoute), so it works wherever the
+	// spatial extension can't be installed. lrs must implement
+	// route.RouteIndexer.
+	EngineGeodesic
+)
+
+// geodesicCandidateCount bounds how many of a route's nearest segments (by
+// bounding-box center) the geodesic engine inspects per point before
+// picking the true nearest by perpendicular distance, mirroring
+// route_event's snapCandidateCount.
+const geodesicCandidateCount = 8
+
+// geodesicEarthRadiusMeters is the sphere radius the Haversine distance
+// used for EPSG:4326 points assumes, matching the WGS 84 mean radius.
+const geodesicEarthRadiusMeters = 6371000.0
+
+// calculatePointsMValueGeodesic is CalculatePointsMValue's EngineGeodesic
+// path. For each point it looks up its route's segment index via
+// route.RouteIndexer, projects the point onto the nearest segment with a
+// foot-of-perpendicular calculation, and linearly interpolates the
+// segment's endpoint M-Values at that projection. It produces the same
+// LRSEvents shape (MVAL/dist_to_line/rejected columns) as the DuckDB path,
+// so callers don't need to know which engine ran.
+func calculatePointsMValueGeodesic(lrs route.LRSRouteInterface, points route_event.LRSEvents, cfg options) (*route_event.LRSEvents, error) {
+	indexer, ok := lrs.(route.RouteIndexer)
+	if !ok {
+		return nil, fmt.Errorf("engine geodesic requires a route.RouteIndexer, got %T", lrs)
+	}
+
+	pointsRecords := points.GetRecords()
+	if len(pointsRecords) == 0 {
+		return nil, fmt.Errorf("points records are empty")
+	}
+
+	pool := memory.NewGoAllocator()
+	crs := points.GetCRS()
+
+	cfg.progress.Start(int64(len(pointsRecords)))
+	outRecs := make([]arrow.RecordBatch, 0, len(pointsRecords))
+	for _, rec := range pointsRecords {
+		out, err := geodesicBatch(pool, rec, indexer, points.LatitudeColumn(), points.LongitudeColumn(), points.MValueColumn(), points.RouteIDColumn(), crs, cfg.precision)
+		if err != nil {
+			return nil, err
+		}
+		outRecs = append(outRecs, out)
+		cfg.progress.Add(1)
+	}
+	cfg.progress.Finish()
+
+	out, err := route_event.NewLRSEvents(outRecs, crs)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.summary != nil {
+		populateRejectionSummaryGeodesic(out, cfg.summary)
+	}
+
+	if cfg.rejectedPath != "" {
+		if err := writeRejectedPointsGeodesic(out, cfg.rejectedPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// geodesicBatch matches every point in rec against its route (via
+// indexer), replacing its mValCol column (if present) with the computed
+// M-Value and appending "dist_to_line" and "rejected" columns, mirroring
+// the query CalculatePointsMValue runs under EngineDuckDB.
+func geodesicBatch(pool memory.Allocator, rec arrow.RecordBatch, indexer route.RouteIndexer, latCol, lonCol, mValCol, routeIDCol, crs string, precision float64) (arrow.RecordBatch, error) {
+	schema := rec.Schema()
+
+	latIdx := schema.FieldIndices(latCol)
+	lonIdx := schema.FieldIndices(lonCol)
+	routeIdx := schema.FieldIndices(routeIDCol)
+	if len(latIdx) == 0 || len(lonIdx) == 0 || len(routeIdx) == 0 {
+		return nil, fmt.Errorf("points records missing one of %s, %s, %s columns", latCol, lonCol, routeIDCol)
+	}
+
+	lat, ok := rec.Column(latIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not float64", latCol)
+	}
+	lon, ok := rec.Column(lonIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not float64", lonCol)
+	}
+	routeID, ok := rec.Column(routeIdx[0]).(*array.String)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not string", routeIDCol)
+	}
+
+	numRows := int(rec.NumRows())
+
+	mvalOut := array.NewFloat64Builder(pool)
+	distOut := array.NewFloat64Builder(pool)
+	rejectedOut := array.NewBooleanBuilder(pool)
+	defer mvalOut.Release()
+	defer distOut.Release()
+	defer rejectedOut.Release()
+
+	indexCache := make(map[string]*geom.STRTree)
+
+	for i := 0; i < numRows; i++ {
+		rid := routeID.Value(i)
+
+		idx, cached := indexCache[rid]
+		if !cached {
+			var err error
+			idx, err = indexer.RouteIndex(rid)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get route index for %s: %w", rid, err)
+			}
+			indexCache[rid] = idx
+		}
+
+		mval, dist, found := geodesicNearest(idx, lon.Value(i), lat.Value(i), crs)
+		if !found {
+			mvalOut.AppendNull()
+			distOut.AppendNull()
+			rejectedOut.Append(true)
+			continue
+		}
+
+		rejected := dist > precision
+		if rejected {
+			mvalOut.AppendNull()
+		} else {
+			mvalOut.Append(mval)
+		}
+		distOut.Append(dist)
+		rejectedOut.Append(rejected)
+	}
+
+	mvalArr := mvalOut.NewArray()
+	distArr := distOut.NewArray()
+	rejectedArr := rejectedOut.NewArray()
+	defer mvalArr.Release()
+	defer distArr.Release()
+	defer rejectedArr.Release()
+
+	fields := make([]arrow.Field, 0, schema.NumFields()+2)
+	cols := make([]arrow.Array, 0, schema.NumFields()+2)
+	for i := 0; i < schema.NumFields(); i++ {
+		field := schema.Field(i)
+		if field.Name == mValCol {
+			continue
+		}
+		fields = append(fields, field)
+		cols = append(cols, rec.Column(i))
+	}
+	fields = append(fields,
+		arrow.Field{Name: mValCol, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		arrow.Field{Name: "dist_to_line", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		arrow.Field{Name: "rejected", Type: arrow.FixedWidthTypes.Boolean},
+	)
+	cols = append(cols, mvalArr, distArr, rejectedArr)
+
+	outSchema := arrow.NewSchema(fields, nil)
+	return array.NewRecordBatch(outSchema, cols, int64(numRows)), nil
+}
+
+// NearestOnRoute snaps (lon, lat) to the nearest segment in idx and
+// returns the interpolated M-Value and distance of that snap, exported for
+// callers outside this package that want a single-point snap without going
+// through CalculatePointsMValue, e.g. flight's snap_to_route DoExchange
+// operation.
+func NearestOnRoute(idx *geom.STRTree, lon, lat float64, crs string) (mval, dist float64, found bool) {
+	return geodesicNearest(idx, lon, lat, crs)
+}
+
+// geodesicNearest narrows idx to geodesicCandidateCount candidates around
+// (lon, lat) by bounding-box center, then measures the true distance to
+// each candidate segment's foot-of-perpendicular projection, returning the
+// interpolated M-Value and distance of the closest one. found is false
+// when idx has no segments to compare against.
+func geodesicNearest(idx *geom.STRTree, lon, lat float64, crs string) (mval, dist float64, found bool) {
+	candidates := idx.NearestK(lon, lat, geodesicCandidateCount)
+
+	best := math.Inf(1)
+	for _, c := range candidates {
+		seg, ok := c.Item.(route.Segment)
+		if !ok {
+			continue
+		}
+
+		candMVal, candDist := geodesicProjectOntoSegment(lon, lat, seg, crs)
+		if candDist < best {
+			best = candDist
+			mval = candMVal
+			found = true
+		}
+	}
+
+	if !found {
+		return 0, 0, false
+	}
+	return mval, best, true
+}
+
+// geodesicProjectOntoSegment finds seg's foot-of-perpendicular projection
+// of (lon, lat): t = clamp(dot(p-a, b-a)/dot(b-a, b-a), 0, 1), proj = a +
+// t*(b-a), worked directly in seg's own coordinates. It then measures the
+// distance from (lon, lat) to that projection geodesically -- Haversine
+// when crs is geographic (EPSG:4326), else a straight planar distance,
+// matching the Lambert-projected meters the DuckDB path already assumes
+// for any other CRS. mval is seg's endpoint M-Values interpolated at t.
+func geodesicProjectOntoSegment(lon, lat float64, seg route.Segment, crs string) (mval, distUnits float64) {
+	abLon, abLat := seg.EndLon-seg.StartLon, seg.EndLat-seg.StartLat
+	apLon, apLat := lon-seg.StartLon, lat-seg.StartLat
+
+	t := 0.0
+	if lenSq := abLon*abLon + abLat*abLat; lenSq > 0 {
+		t = (apLon*abLon + apLat*abLat) / lenSq
+		if t < 0 {
+			t = 0
+		} else if t > 1 {
+			t = 1
+		}
+	}
+
+	projLon := seg.StartLon + t*abLon
+	projLat := seg.StartLat + t*abLat
+	mval = seg.StartMVal + t*(seg.EndMVal-seg.StartMVal)
+
+	if crs == "EPSG:4326" {
+		return mval, haversineMeters(lat, lon, projLat, projLon)
+	}
+	return mval, math.Hypot(lon-projLon, lat-projLat)
+}
+
+// haversineMeters is the great-circle distance between two EPSG:4326
+// lat/lon pairs (degrees), using geodesicEarthRadiusMeters.
+func haversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return geodesicEarthRadiusMeters * c
+}
+
+// populateRejectionSummaryGeodesic fills summary by scanning out's
+// "rejected", "dist_to_line", and ROUTEID columns directly, the in-memory
+// equivalent of populateRejectionSummary's SQL aggregation under
+// EngineDuckDB.
+func populateRejectionSummaryGeodesic(out *route_event.LRSEvents, summary *RejectionSummary) {
+	perRoute := make(map[string]int)
+	total, rejected := 0, 0
+	minDist, maxDist, sumDist := math.Inf(1), math.Inf(-1), 0.0
+
+	for _, rec := range out.GetRecords() {
+		schema := rec.Schema()
+		rejectedIdx := schema.FieldIndices("rejected")
+		distIdx := schema.FieldIndices("dist_to_line")
+		routeIdx := schema.FieldIndices(out.RouteIDColumn())
+		if len(rejectedIdx) == 0 || len(distIdx) == 0 || len(routeIdx) == 0 {
+			continue
+		}
+
+		rejectedCol := rec.Column(rejectedIdx[0]).(*array.Boolean)
+		distCol := rec.Column(distIdx[0]).(*array.Float64)
+		routeCol := rec.Column(routeIdx[0]).(*array.String)
+
+		for i := 0; i < int(rec.NumRows()); i++ {
+			total++
+			if !rejectedCol.Value(i) {
+				continue
+			}
+			rejected++
+			perRoute[routeCol.Value(i)]++
+
+			if distCol.IsNull(i) {
+				continue
+			}
+			d := distCol.Value(i)
+			if d < minDist {
+				minDist = d
+			}
+			if d > maxDist {
+				maxDist = d
+			}
+			sumDist += d
+		}
+	}
+
+	summary.Total = total
+	summary.Rejected = rejected
+	summary.PerRoute = perRoute
+	if rejected > 0 {
+		summary.MinDist = minDist
+		summary.MaxDist = maxDist
+		summary.MeanDist = sumDist / float64(rejected)
+	}
+}
+
+// writeRejectedPointsGeodesic writes out's rejected rows to a standalone
+// Parquet file at path, the EngineGeodesic equivalent of EngineDuckDB's
+// "COPY (... WHERE rejected) TO path" in CalculatePointsMValue.
+func writeRejectedPointsGeodesic(out *route_event.LRSEvents, path string) error {
+	pool := memory.NewGoAllocator()
+	records := out.GetRecords()
+	if len(records) == 0 {
+		return nil
+	}
+
+	schema := records[0].Schema()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create rejected points file: %w", err)
+	}
+	defer f.Close()
+
+	writer, err := pqarrow.NewFileWriter(
+		schema,
+		f,
+		parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy)),
+		pqarrow.DefaultWriterProps(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+	defer writer.Close()
+
+	for _, rec := range records {
+		rejectedIdx := schema.FieldIndices("rejected")
+		if len(rejectedIdx) == 0 {
+			return fmt.Errorf("result records missing rejected column")
+		}
+		mask, ok := rec.Column(rejectedIdx[0]).(*array.Boolean)
+		if !ok {
+			return fmt.Errorf("rejected column is not boolean")
+		}
+
+		filtered, err := filterRecordBatch(pool, rec, mask)
+		if err != nil {
+			return fmt.Errorf("failed to filter rejected points: %w", err)
+		}
+		if filtered.NumRows() == 0 {
+			filtered.Release()
+			continue
+		}
+
+		if err := writer.WriteBuffered(filtered); err != nil {
+			filtered.Release()
+			return fmt.Errorf("failed to write rejected points: %w", err)
+		}
+		filtered.Release()
+	}
+
+	return nil
+}
+
+// filterRecordBatch returns a new record containing only rec's rows where
+// mask is true, for the column types points records commonly carry in this
+// repo (see route_event's getColumnValue for the same type set).
+func filterRecordBatch(pool memory.Allocator, rec arrow.RecordBatch, mask *array.Boolean) (arrow.RecordBatch, error) {
+	schema := rec.Schema()
+	cols := make([]arrow.Array, schema.NumFields())
+
+	numRows := 0
+	for i := 0; i < mask.Len(); i++ {
+		if mask.Value(i) {
+			numRows++
+		}
+	}
+
+	for i := 0; i < schema.NumFields(); i++ {
+		filtered, err := filterColumn(pool, rec.Column(i), mask)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", schema.Field(i).Name, err)
+		}
+		cols[i] = filtered
+	}
+
+	return array.NewRecordBatch(schema, cols, int64(numRows)), nil
+}
+
+// filterColumn applies mask to col, appending a null wherever the source
+// value was null so nullability is preserved across the filter.
+func filterColumn(pool memory.Allocator, col arrow.Array, mask *array.Boolean) (arrow.Array, error) {
+	switch c := col.(type) {
+	case *array.Float64:
+		b := array.NewFloat64Builder(pool)
+		defer b.Release()
+		for i := 0; i < c.Len(); i++ {
+			if !mask.Value(i) {
+				continue
+			}
+			if c.IsNull(i) {
+				b.AppendNull()
+			} else {
+				b.Append(c.Value(i))
+			}
+		}
+		return b.NewArray(), nil
+	case *array.Int32:
+		b := array.NewInt32Builder(pool)
+		defer b.Release()
+		for i := 0; i < c.Len(); i++ {
+			if !mask.Value(i) {
+				continue
+			}
+			if c.IsNull(i) {
+				b.AppendNull()
+			} else {
+				b.Append(c.Value(i))
+			}
+		}
+		return b.NewArray(), nil
+	case *array.Int64:
+		b := array.NewInt64Builder(pool)
+		defer b.Release()
+		for i := 0; i < c.Len(); i++ {
+			if !mask.Value(i) {
+				continue
+			}
+			if c.IsNull(i) {
+				b.AppendNull()
+			} else {
+				b.Append(c.Value(i))
+			}
+		}
+		return b.NewArray(), nil
+	case *array.String:
+		b := array.NewStringBuilder(pool)
+		defer b.Release()
+		for i := 0; i < c.Len(); i++ {
+			if !mask.Value(i) {
+				continue
+			}
+			if c.IsNull(i) {
+				b.AppendNull()
+			} else {
+				b.Append(c.Value(i))
+			}
+		}
+		return b.NewArray(), nil
+	case *array.Boolean:
+		b := array.NewBooleanBuilder(pool)
+		defer b.Release()
+		for i := 0; i < c.Len(); i++ {
+			if !mask.Value(i) {
+				continue
+			}
+			if c.IsNull(i) {
+				b.AppendNull()
+			} else {
+				b.Append(c.Value(i))
+			}
+		}
+		return b.NewArray(), nil
+	default:
+		return nil, fmt.Errorf("unsupported column type %T for rejected-points filter", col)
+	}
+}
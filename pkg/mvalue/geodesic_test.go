@@ -0,0 +1,248 @@
+package mvalue
+
+import (
+	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/route"
+	"bm-lrs/pkg/route_event"
+	"context"
+	"math"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCalculatePointsMValueGeodesicMatchesDuckDB fuzz-compares
+// EngineGeodesic against EngineDuckDB on the same straight route and
+// points, which should agree within floating-point tolerance since both
+// ultimately solve the same nearest-segment projection.
+func TestCalculatePointsMValueGeodesicMatchesDuckDB(t *testing.T) {
+	pool := memory.NewGoAllocator()
+
+	routeSchema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "VERTEX_SEQ", Type: arrow.PrimitiveTypes.Int32},
+			{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+		},
+		nil,
+	)
+
+	routeLatBuilder := array.NewFloat64Builder(pool)
+	routeLonBuilder := array.NewFloat64Builder(pool)
+	routeMvalBuilder := array.NewFloat64Builder(pool)
+	routeSeqBuilder := array.NewInt32Builder(pool)
+	routeIDBuilder := array.NewStringBuilder(pool)
+
+	defer routeLatBuilder.Release()
+	defer routeLonBuilder.Release()
+	defer routeMvalBuilder.Release()
+	defer routeSeqBuilder.Release()
+	defer routeIDBuilder.Release()
+
+	routeLatBuilder.AppendValues([]float64{0, 0}, nil)
+	routeLonBuilder.AppendValues([]float64{0, 100}, nil)
+	routeMvalBuilder.AppendValues([]float64{0, 100}, nil)
+	routeSeqBuilder.AppendValues([]int32{0, 1}, nil)
+	routeIDBuilder.AppendValues([]string{"R1", "R1"}, nil)
+
+	routeRec := array.NewRecordBatch(
+		routeSchema,
+		[]arrow.Array{
+			routeLatBuilder.NewArray(),
+			routeLonBuilder.NewArray(),
+			routeMvalBuilder.NewArray(),
+			routeSeqBuilder.NewArray(),
+			routeIDBuilder.NewArray(),
+		},
+		2,
+	)
+
+	lrs := route.NewLRSRoute("R1", []arrow.RecordBatch{routeRec}, geom.LAMBERT_WKT)
+	defer lrs.Release()
+	assert.NoError(t, lrs.Sink())
+
+	pointsSchema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+		},
+		nil,
+	)
+
+	latBuilder := array.NewFloat64Builder(pool)
+	lonBuilder := array.NewFloat64Builder(pool)
+	mvalBuilder := array.NewFloat64Builder(pool)
+	routeidBuilder := array.NewStringBuilder(pool)
+
+	defer latBuilder.Release()
+	defer lonBuilder.Release()
+	defer mvalBuilder.Release()
+	defer routeidBuilder.Release()
+
+	latBuilder.AppendValues([]float64{0, 0}, nil)
+	lonBuilder.AppendValues([]float64{25, 60}, nil)
+	mvalBuilder.AppendValues(make([]float64, 2), nil)
+	routeidBuilder.AppendValues([]string{"R1", "R1"}, nil)
+
+	pointsRec := array.NewRecordBatch(
+		pointsSchema,
+		[]arrow.Array{
+			latBuilder.NewArray(),
+			lonBuilder.NewArray(),
+			mvalBuilder.NewArray(),
+			routeidBuilder.NewArray(),
+		},
+		2,
+	)
+
+	points, err := route_event.NewLRSEvents([]arrow.RecordBatch{pointsRec}, geom.LAMBERT_WKT)
+	assert.NoError(t, err)
+	defer points.Release()
+
+	duckdbResult, err := CalculatePointsMValue(context.Background(), &lrs, *points)
+	if err != nil {
+		t.Fatalf("CalculatePointsMValue (EngineDuckDB) failed: %v", err)
+	}
+	defer duckdbResult.Release()
+
+	geodesicResult, err := CalculatePointsMValue(context.Background(), &lrs, *points, WithEngine(EngineGeodesic))
+	if err != nil {
+		t.Fatalf("CalculatePointsMValue (EngineGeodesic) failed: %v", err)
+	}
+	defer geodesicResult.Release()
+
+	duckdbMVALs := duckdbResult.GetRecords()[0].Column(3).(*array.Float64)
+	geodesicMVALs := geodesicResult.GetRecords()[0].Column(3).(*array.Float64)
+	for i := 0; i < 2; i++ {
+		if math.Abs(duckdbMVALs.Value(i)-geodesicMVALs.Value(i)) > 0.001 {
+			t.Errorf("point %d: DuckDB MVAL %f, geodesic MVAL %f", i, duckdbMVALs.Value(i), geodesicMVALs.Value(i))
+		}
+	}
+}
+
+// TestCalculatePointsMValueGeodesicRejectsPointsOutsidePrecision mirrors
+// TestCalculatePointsMValueRejectsPointsOutsidePrecision under
+// EngineGeodesic.
+func TestCalculatePointsMValueGeodesicRejectsPointsOutsidePrecision(t *testing.T) {
+	pool := memory.NewGoAllocator()
+
+	routeSchema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "VERTEX_SEQ", Type: arrow.PrimitiveTypes.Int32},
+			{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+		},
+		nil,
+	)
+
+	routeLatBuilder := array.NewFloat64Builder(pool)
+	routeLonBuilder := array.NewFloat64Builder(pool)
+	routeMvalBuilder := array.NewFloat64Builder(pool)
+	routeSeqBuilder := array.NewInt32Builder(pool)
+	routeIDBuilder := array.NewStringBuilder(pool)
+
+	defer routeLatBuilder.Release()
+	defer routeLonBuilder.Release()
+	defer routeMvalBuilder.Release()
+	defer routeSeqBuilder.Release()
+	defer routeIDBuilder.Release()
+
+	routeLatBuilder.AppendValues([]float64{0, 0}, nil)
+	routeLonBuilder.AppendValues([]float64{0, 100}, nil)
+	routeMvalBuilder.AppendValues([]float64{0, 100}, nil)
+	routeSeqBuilder.AppendValues([]int32{0, 1}, nil)
+	routeIDBuilder.AppendValues([]string{"R1", "R1"}, nil)
+
+	routeRec := array.NewRecordBatch(
+		routeSchema,
+		[]arrow.Array{
+			routeLatBuilder.NewArray(),
+			routeLonBuilder.NewArray(),
+			routeMvalBuilder.NewArray(),
+			routeSeqBuilder.NewArray(),
+			routeIDBuilder.NewArray(),
+		},
+		2,
+	)
+
+	lrs := route.NewLRSRoute("R1", []arrow.RecordBatch{routeRec}, geom.LAMBERT_WKT)
+	defer lrs.Release()
+	assert.NoError(t, lrs.Sink())
+
+	pointsSchema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+		},
+		nil,
+	)
+
+	latBuilder := array.NewFloat64Builder(pool)
+	lonBuilder := array.NewFloat64Builder(pool)
+	mvalBuilder := array.NewFloat64Builder(pool)
+	routeidBuilder := array.NewStringBuilder(pool)
+
+	defer latBuilder.Release()
+	defer lonBuilder.Release()
+	defer mvalBuilder.Release()
+	defer routeidBuilder.Release()
+
+	// Point 0 sits on the route; point 1 is 50 units off it.
+	latBuilder.AppendValues([]float64{0, 50}, nil)
+	lonBuilder.AppendValues([]float64{50, 25}, nil)
+	mvalBuilder.AppendValues(make([]float64, 2), nil)
+	routeidBuilder.AppendValues([]string{"R1", "R1"}, nil)
+
+	pointsRec := array.NewRecordBatch(
+		pointsSchema,
+		[]arrow.Array{
+			latBuilder.NewArray(),
+			lonBuilder.NewArray(),
+			mvalBuilder.NewArray(),
+			routeidBuilder.NewArray(),
+		},
+		2,
+	)
+
+	points, err := route_event.NewLRSEvents([]arrow.RecordBatch{pointsRec}, geom.LAMBERT_WKT)
+	assert.NoError(t, err)
+	defer points.Release()
+
+	var summary RejectionSummary
+	result, err := CalculatePointsMValue(context.Background(), &lrs, *points, WithEngine(EngineGeodesic), WithRejectionSummary(&summary))
+	if err != nil {
+		t.Fatalf("CalculatePointsMValue failed: %v", err)
+	}
+	defer result.Release()
+
+	resultRecs := result.GetRecords()
+	if len(resultRecs) != 1 {
+		t.Fatalf("Expected 1 record batch, got %d", len(resultRecs))
+	}
+
+	rejected := resultRecs[0].Column(5).(*array.Boolean)
+	if rejected.Value(0) {
+		t.Error("expected the on-route point not to be rejected")
+	}
+	if !rejected.Value(1) {
+		t.Error("expected the 50-unit-off point to be rejected")
+	}
+
+	if summary.Rejected != 1 {
+		t.Errorf("expected summary.Rejected 1, got %d", summary.Rejected)
+	}
+	if summary.PerRoute["R1"] != 1 {
+		t.Errorf("expected summary.PerRoute[R1] 1, got %d", summary.PerRoute["R1"])
+	}
+}
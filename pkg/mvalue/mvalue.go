@@ -1,6 +1,7 @@
 package mvalue
 
 import (
+	"bm-lrs/pkg/progress"
 	"bm-lrs/pkg/route"
 	"bm-lrs/pkg/route_event"
 	"context"
@@ -12,9 +13,98 @@ import (
 	"github.com/duckdb/duckdb-go/v2"
 )
 
+// DefaultPointToLinePrecision is the maximum distance, in the units of the
+// LRS's CRS (meters for the routes this package operates on), a point may
+// sit off its matched route's linestring before CalculatePointsMValue
+// rejects it instead of assigning an M-Value.
+const DefaultPointToLinePrecision = 10.0
+
+// RejectionSummary reports how many points CalculatePointsMValue rejected
+// for sitting further than the configured PointToLinePrecision from their
+// matched route, for operators to review before committing a catalog
+// update built from the result.
+type RejectionSummary struct {
+	Total    int
+	Rejected int
+	PerRoute map[string]int
+	MinDist  float64
+	MaxDist  float64
+	MeanDist float64
+}
+
+// Option configures optional behavior of CalculatePointsMValue.
+type Option func(*options)
+
+type options struct {
+	precision    float64
+	summary      *RejectionSummary
+	rejectedPath string
+	engine       Engine
+	progress     progress.Progress
+}
+
+// WithPointToLinePrecision overrides DefaultPointToLinePrecision. Points
+// further than precisionMeters from their matched route's linestring are
+// rejected rather than assigned an M-Value.
+func WithPointToLinePrecision(precisionMeters float64) Option {
+	return func(o *options) {
+		o.precision = precisionMeters
+	}
+}
+
+// WithRejectionSummary populates summary with the rejection counts and
+// distance stats from the call. summary must not be nil.
+func WithRejectionSummary(summary *RejectionSummary) Option {
+	return func(o *options) {
+		o.summary = summary
+	}
+}
+
+// WithRejectedPointsPath writes the rejected points, if any, to a
+// standalone Parquet file at path so operators can review off-corridor
+// observations before catalog commit.
+func WithRejectedPointsPath(path string) Option {
+	return func(o *options) {
+		o.rejectedPath = path
+	}
+}
+
+// WithEngine selects the matching/interpolation engine. Defaults to
+// EngineDuckDB; see EngineGeodesic for the pure-Go alternative.
+func WithEngine(e Engine) Option {
+	return func(o *options) {
+		o.engine = e
+	}
+}
+
+// WithProgress reports coarse progress through the matching/interpolation
+// phase: under EngineDuckDB that's one Add per output batch fetched from
+// the query, under EngineGeodesic one Add per input points batch
+// processed. Defaults to progress.NoOp.
+func WithProgress(p progress.Progress) Option {
+	return func(o *options) {
+		o.progress = p
+	}
+}
+
 // CalculatePointsMValue calculates the M-Value of points relative to an LRS route.
 // It uses DuckDB spatial extension for shortest line and interpolation.
-func CalculatePointsMValue(ctx context.Context, lrs route.LRSRouteInterface, points route_event.LRSEvents) (*route_event.LRSEvents, error) {
+//
+// A point is rejected, rather than assigned an M-Value, when its distance
+// to the matched route's linestring exceeds PointToLinePrecision (see
+// WithPointToLinePrecision); rejected points are flagged in the returned
+// events via a "rejected" boolean column. Use WithRejectionSummary and
+// WithRejectedPointsPath to inspect and persist what was rejected.
+func CalculatePointsMValue(ctx context.Context, lrs route.LRSRouteInterface, points route_event.LRSEvents, opts ...Option) (*route_event.LRSEvents, error) {
+	cfg := options{precision: DefaultPointToLinePrecision, progress: progress.NoOp}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.engine == EngineGeodesic {
+		return calculatePointsMValueGeodesic(lrs, points, cfg)
+	}
+
 	c, err := duckdb.NewConnector("", nil)
 
 	if err != nil {
@@ -139,10 +229,11 @@ func CalculatePointsMValue(ctx context.Context, lrs route.LRSRouteInterface, poi
 	best_interpolated AS (
 		SELECT DISTINCT ON (point_id) * FROM interpolated ORDER BY point_id, dist ASC
 	)
-	SELECT 
-		p.* EXCLUDE (%s), 
-		COALESCE(i.m_val, 0) as "%s",
-		i.dist as dist_to_line
+	SELECT
+		p.* EXCLUDE (%s),
+		CASE WHEN i.dist IS NULL OR i.dist > %v THEN NULL ELSE i.m_val END as "%s",
+		i.dist as dist_to_line,
+		(i.dist IS NULL OR i.dist > %v) as rejected
 	FROM points_table p
 	LEFT JOIN best_interpolated i ON p.point_id = i.point_id
 	ORDER BY p.point_id
@@ -153,7 +244,7 @@ func CalculatePointsMValue(ctx context.Context, lrs route.LRSRouteInterface, poi
 		lrs.LatitudeColumn(), lrs.LongitudeColumn(),
 		lrs.LongitudeColumn(), lrs.LongitudeColumn(), lrs.LongitudeColumn(), lrs.LongitudeColumn(),
 		lrs.LatitudeColumn(), lrs.LatitudeColumn(), lrs.LatitudeColumn(), lrs.LatitudeColumn(),
-		excludeClause, points.MValueColumn())
+		excludeClause, cfg.precision, points.MValueColumn(), cfg.precision)
 
 	// Debug: check counts
 	var pointsCount, lrsLineCount, lrsSegmentCount int
@@ -168,12 +259,15 @@ func CalculatePointsMValue(ctx context.Context, lrs route.LRSRouteInterface, poi
 	}
 	defer outReader.Release()
 
+	cfg.progress.Start(0)
 	var outRecs []arrow.RecordBatch
 	for outReader.Next() {
 		rec := outReader.RecordBatch()
 		rec.Retain()
 		outRecs = append(outRecs, rec)
+		cfg.progress.Add(rec.NumRows())
 	}
+	cfg.progress.Finish()
 
 	if len(outRecs) == 0 {
 		return nil, fmt.Errorf("expected records, got 0. Counts: points=%d, lrs_line=%d, lrs_segment=%d", pointsCount, lrsLineCount, lrsSegmentCount)
@@ -184,5 +278,78 @@ func CalculatePointsMValue(ctx context.Context, lrs route.LRSRouteInterface, poi
 		return nil, err
 	}
 
+	if cfg.summary != nil || cfg.rejectedPath != "" {
+		resultReader, err := array.NewRecordReader(outRecs[0].Schema(), outRecs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create result record reader: %v", err)
+		}
+		defer resultReader.Release()
+
+		releaseResult, err := ar.RegisterView(resultReader, "result_view")
+		if err != nil {
+			return nil, fmt.Errorf("failed to register result view: %v", err)
+		}
+		defer releaseResult()
+
+		if cfg.summary != nil {
+			if err := populateRejectionSummary(ctx, conn_sql, cfg.summary); err != nil {
+				return nil, err
+			}
+		}
+
+		if cfg.rejectedPath != "" {
+			copyRejectedSQL := fmt.Sprintf("COPY (SELECT * FROM result_view WHERE rejected) TO '%s' (FORMAT PARQUET)", cfg.rejectedPath)
+			if _, err := conn_sql.ExecContext(ctx, copyRejectedSQL); err != nil {
+				return nil, fmt.Errorf("failed to persist rejected points: %v", err)
+			}
+		}
+	}
+
 	return out, nil
 }
+
+// populateRejectionSummary fills summary with the rejection count, distance
+// stats, and per-route breakdown for the rows in result_view, which must
+// already be registered on conn's connector and carry a "rejected" and
+// "dist_to_line" column (see CalculatePointsMValue's interpolation query).
+func populateRejectionSummary(ctx context.Context, conn *sql.Conn, summary *RejectionSummary) error {
+	row := conn.QueryRowContext(ctx, `
+		SELECT
+			count(*),
+			count(*) FILTER (WHERE rejected),
+			min(dist_to_line) FILTER (WHERE rejected),
+			max(dist_to_line) FILTER (WHERE rejected),
+			avg(dist_to_line) FILTER (WHERE rejected)
+		FROM result_view
+	`)
+
+	var total, rejected int
+	var minDist, maxDist, meanDist sql.NullFloat64
+	if err := row.Scan(&total, &rejected, &minDist, &maxDist, &meanDist); err != nil {
+		return fmt.Errorf("failed to summarize rejections: %v", err)
+	}
+
+	summary.Total = total
+	summary.Rejected = rejected
+	summary.MinDist = minDist.Float64
+	summary.MaxDist = maxDist.Float64
+	summary.MeanDist = meanDist.Float64
+
+	perRoute := make(map[string]int)
+	rows, err := conn.QueryContext(ctx, `SELECT ROUTEID, count(*) FROM result_view WHERE rejected GROUP BY ROUTEID`)
+	if err != nil {
+		return fmt.Errorf("failed to summarize per-route rejections: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var routeID string
+		var count int
+		if err := rows.Scan(&routeID, &count); err != nil {
+			return fmt.Errorf("failed to scan per-route rejection row: %v", err)
+		}
+		perRoute[routeID] = count
+	}
+	summary.PerRoute = perRoute
+
+	return rows.Err()
+}
@@ -0,0 +1,25 @@
+// Package progress defines a small reporting interface used by long-running
+// operations in this module (Sink, MergeParquetFiles, Sync) so a caller can
+// render a progress bar or estimate completion without scraping log lines.
+package progress
+
+// Progress receives incremental updates about a long-running operation.
+// Start is called once the total unit count is known (row count, file
+// count, etc.), Add reports incremental progress, and Finish marks
+// completion.
+type Progress interface {
+	Start(total int64)
+	Add(n int64)
+	Finish()
+}
+
+// noop is the default Progress that discards every update.
+type noop struct{}
+
+func (noop) Start(int64) {}
+func (noop) Add(int64)   {}
+func (noop) Finish()     {}
+
+// NoOp is a Progress that does nothing, used as the default when no
+// WithProgress option is supplied.
+var NoOp Progress = noop{}
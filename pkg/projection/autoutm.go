@@ -0,0 +1,108 @@
+package projection
+
+import (
+	"bm-lrs/pkg/geom"
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// AutoUTMCRS is the sentinel target CRS Transform accepts in place of a
+// concrete EPSG code or WKT, telling it to pick the WGS84 UTM zone that
+// covers the input's own centroid instead of the caller hand-picking one.
+const AutoUTMCRS = "AUTO:UTM"
+
+// AutoUTM inspects the centroid of obj's coordinates (reprojecting to
+// EPSG:4326 first if obj isn't already geographic) and returns the EPSG
+// code of the WGS84 UTM zone that covers it -- zones 46N through 54S span
+// the Indonesian archipelago this module targets -- via the package-level
+// default Transformer.
+func AutoUTM(ctx context.Context, obj geom.Geometry) (string, error) {
+	t, err := defaultTransformerInstance()
+	if err != nil {
+		return "", err
+	}
+	return t.AutoUTM(ctx, obj)
+}
+
+// AutoUTM inspects the centroid of obj's coordinates, reusing t's
+// already-loaded DuckDB connection, and returns the EPSG code of the
+// WGS84 UTM zone that covers it. ctx bounds the underlying DuckDB
+// queries.
+func (t *Transformer) AutoUTM(ctx context.Context, obj geom.Geometry) (string, error) {
+	records := obj.GetRecords()
+	if len(records) == 0 {
+		return "", fmt.Errorf("object has no records to determine a UTM zone from")
+	}
+
+	rr, err := array.NewRecordReader(records[0].Schema(), records)
+	if err != nil {
+		return "", err
+	}
+	defer rr.Release()
+
+	viewName := t.nextViewName()
+	release, err := t.arrow.RegisterView(rr, viewName)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	source := viewName
+	if obj.GetCRS() != "EPSG:4326" {
+		reproject, err := transformQuery(viewName, obj.GetCRS(), "EPSG:4326", false)
+		if err != nil {
+			return "", err
+		}
+
+		geoViewName := t.nextViewName()
+		if _, err := t.db.ExecContext(ctx, fmt.Sprintf("create temp view %s as %s", geoViewName, reproject)); err != nil {
+			return "", fmt.Errorf("failed to reproject to geographic coordinates for UTM zone detection: %w", err)
+		}
+		defer t.db.ExecContext(ctx, fmt.Sprintf("drop view %s", geoViewName))
+		source = geoViewName
+	}
+
+	var lon, lat float64
+	row := t.db.QueryRowContext(ctx, centroidQuery(source))
+	if err := row.Scan(&lon, &lat); err != nil {
+		return "", fmt.Errorf("failed to compute centroid for UTM zone detection: %w", err)
+	}
+
+	return utmEPSG(lon, lat), nil
+}
+
+// centroidQuery builds the query that averages view's LAT/LONG columns
+// into a single centroid, casting each point to GEOGRAPHY first so an
+// out-of-range (e.g. already-projected) LAT/LONG pair fails loudly here
+// instead of silently picking the wrong hemisphere/zone downstream.
+func centroidQuery(view string) string {
+	return fmt.Sprintf(`
+	with
+	points as (
+		select ST_Point(LONG, LAT) as pt from %s
+	),
+	validated as (
+		select pt, pt::GEOGRAPHY as geog from points
+	)
+	select avg(ST_X(pt)) as lon, avg(ST_Y(pt)) as lat from validated
+	`, view)
+}
+
+// utmEPSG returns the EPSG code of the WGS84 UTM zone covering (lon, lat).
+func utmEPSG(lon, lat float64) string {
+	zone := int(math.Floor((lon+180)/6)) + 1
+	if zone < 1 {
+		zone = 1
+	}
+	if zone > 60 {
+		zone = 60
+	}
+
+	if lat >= 0 {
+		return fmt.Sprintf("EPSG:%d", 32600+zone)
+	}
+	return fmt.Sprintf("EPSG:%d", 32700+zone)
+}
@@ -3,6 +3,7 @@ package projection
 import (
 	"bm-lrs/pkg/geom"
 	"bm-lrs/pkg/route"
+	"context"
 	"io"
 	"os"
 	"testing"
@@ -63,7 +64,7 @@ func TestTransform(t *testing.T) {
 				"project to lambert", func(t *testing.T) {
 					// Lambert WKT
 					lambert_wkt := `PROJCS["Indonesia Lambert Conformal Conic",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Lambert_Conformal_Conic"],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",115.0],PARAMETER["Standard_Parallel_1",2.0],PARAMETER["Standard_Parallel_2",-7.0],PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`
-					new_p, err := Transform(&p, lambert_wkt, false) // Transform to Lambert
+					new_p, err := Transform(context.Background(), &p, lambert_wkt, false) // Transform to Lambert
 
 					if err != nil {
 						t.Error(err)
@@ -77,7 +78,7 @@ func TestTransform(t *testing.T) {
 				"project back lambert to 4326", func(t *testing.T) {
 					// Lambert WKT
 					lambert_wkt := `PROJCS["Indonesia Lambert Conformal Conic",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Lambert_Conformal_Conic"],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",115.0],PARAMETER["Standard_Parallel_1",2.0],PARAMETER["Standard_Parallel_2",-7.0],PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`
-					new_p, err := Transform(&p, lambert_wkt, false) // Transform to Lambert
+					new_p, err := Transform(context.Background(), &p, lambert_wkt, false) // Transform to Lambert
 
 					if err != nil {
 						t.Error(err)
@@ -85,7 +86,7 @@ func TestTransform(t *testing.T) {
 
 					defer new_p.Release()
 
-					test_p, err := Transform(new_p, "EPSG:4326", true) // Transform back to 4326
+					test_p, err := Transform(context.Background(), new_p, "EPSG:4326", true) // Transform back to 4326
 
 					if err != nil {
 						t.Error(err)
@@ -111,14 +112,17 @@ func TestTransform(t *testing.T) {
 
 			jsonByte, _ := io.ReadAll(jsonFile)
 
-			lrs := route.NewLRSRouteFromESRIGeoJSON(
+			lrs, err := route.NewLRSRouteFromESRIGeoJSON(
 				jsonByte,
 				0,
 				lambert_wkt,
 			)
+			if err != nil {
+				t.Fatal(err)
+			}
 			defer lrs.Release()
 
-			new_lrs, err := Transform(&lrs, "EPSG:4326", true)
+			new_lrs, err := Transform(context.Background(), &lrs, "EPSG:4326", true)
 			if err != nil {
 				t.Error(err)
 			}
@@ -126,3 +130,107 @@ func TestTransform(t *testing.T) {
 		},
 	)
 }
+
+func TestTransformBatch(t *testing.T) {
+	lambert_wkt := `PROJCS["Indonesia Lambert Conformal Conic",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Lambert_Conformal_Conic"],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",115.0],PARAMETER["Standard_Parallel_1",2.0],PARAMETER["Standard_Parallel_2",-7.0],PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`
+
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+		},
+		nil,
+	)
+
+	newPoints := func(lat, lon float64) geom.Points {
+		lat_builder := array.NewFloat64Builder(pool)
+		long_builder := array.NewFloat64Builder(pool)
+		mval_builder := array.NewFloat64Builder(pool)
+		defer lat_builder.Release()
+		defer long_builder.Release()
+		defer mval_builder.Release()
+
+		lat_builder.AppendValues([]float64{lat}, nil)
+		long_builder.AppendValues([]float64{lon}, nil)
+		mval_builder.AppendValues([]float64{0}, nil)
+
+		rec := array.NewRecordBatch(
+			schema,
+			[]arrow.Array{lat_builder.NewArray(), long_builder.NewArray(), mval_builder.NewArray()},
+			1,
+		)
+		return geom.NewPoints([]arrow.RecordBatch{rec}, "EPSG:4326")
+	}
+
+	p1 := newPoints(5.647860000331377, 95.42103999972832)
+	defer p1.Release()
+	p2 := newPoints(5.5072984, 95.3588172)
+	defer p2.Release()
+
+	out, err := TransformBatch(context.Background(), []geom.Geometry{&p1, &p2}, lambert_wkt, false)
+	if err != nil {
+		t.Fatalf("TransformBatch failed: %v", err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("expected 2 transformed objects, got %d", len(out))
+	}
+	for _, g := range out {
+		if g.GetCRS() != lambert_wkt {
+			t.Errorf("expected CRS %s, got %s", lambert_wkt, g.GetCRS())
+		}
+		g.Release()
+	}
+}
+
+func TestAutoUTM(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+		},
+		nil,
+	)
+
+	lat_builder := array.NewFloat64Builder(pool)
+	long_builder := array.NewFloat64Builder(pool)
+	mval_builder := array.NewFloat64Builder(pool)
+	defer lat_builder.Release()
+	defer long_builder.Release()
+	defer mval_builder.Release()
+
+	// Banda Aceh, squarely in UTM zone 46N.
+	lat_builder.AppendValues([]float64{5.647860000331377}, nil)
+	long_builder.AppendValues([]float64{95.42103999972832}, nil)
+	mval_builder.AppendValues([]float64{0}, nil)
+
+	rec := array.NewRecordBatch(
+		schema,
+		[]arrow.Array{lat_builder.NewArray(), long_builder.NewArray(), mval_builder.NewArray()},
+		1,
+	)
+
+	p := geom.NewPoints([]arrow.RecordBatch{rec}, "EPSG:4326")
+	defer p.Release()
+
+	crs, err := AutoUTM(context.Background(), &p)
+	if err != nil {
+		t.Fatalf("AutoUTM failed: %v", err)
+	}
+	if crs != "EPSG:32646" {
+		t.Errorf("expected EPSG:32646 for a point in zone 46N, got %s", crs)
+	}
+
+	new_p, err := Transform(context.Background(), &p, AutoUTMCRS, false)
+	if err != nil {
+		t.Fatalf("Transform with AutoUTMCRS failed: %v", err)
+	}
+	defer new_p.Release()
+
+	if new_p.GetCRS() != "EPSG:32646" {
+		t.Errorf("expected Transform(AUTO:UTM) to resolve to EPSG:32646, got %s", new_p.GetCRS())
+	}
+}
@@ -0,0 +1,210 @@
+package projection
+
+import (
+	"bm-lrs/pkg/geom"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// CRSHandle is the canonical internal representation of a coordinate
+// reference system resolved by a Registry: a PROJ-recognized reference
+// (an "AUTHORITY:CODE" string or a WKT definition) that Transform can be
+// called with directly.
+type CRSHandle struct {
+	id  string
+	ref string
+}
+
+// String returns the PROJ-recognized reference Transform expects.
+func (h CRSHandle) String() string {
+	return h.ref
+}
+
+// transformKey identifies a cached (origin, target) CRS pair.
+type transformKey struct {
+	from, to string
+}
+
+// CachedTransform is a resolved (origin, target) CRS pair, ready to drive
+// Transform without re-parsing either identifier.
+type CachedTransform struct {
+	From CRSHandle
+	To   CRSHandle
+}
+
+// Registry resolves CRS identifiers supplied as EPSG codes, OGC URNs, WKT2
+// definitions, or PROJJSON objects into a canonical CRSHandle, and caches
+// both individual handles and resolved (origin, target) transform pairs so
+// repeated lookups for the same identifiers skip re-parsing them.
+type Registry struct {
+	mu         sync.RWMutex
+	handles    map[string]CRSHandle
+	transforms map[transformKey]*CachedTransform
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		handles:    make(map[string]CRSHandle),
+		transforms: make(map[transformKey]*CachedTransform),
+	}
+}
+
+// DefaultRegistry is the process-wide Registry used wherever a CRS needs
+// resolving but no specific *Registry has been injected.
+var DefaultRegistry = NewRegistry()
+
+// Resolve parses id into a canonical CRSHandle, caching the result so
+// later calls for the same identifier are free. id may be:
+//   - a string EPSG code, with or without an "EPSG:" prefix ("4326", "EPSG:4326")
+//   - an OGC URN ("urn:ogc:def:crs:EPSG::4326")
+//   - a WKT2 (or ESRI WKT) definition string, passed through verbatim
+//   - a PROJJSON object, as a map[string]any, []byte, or json.RawMessage
+func (r *Registry) Resolve(id any) (CRSHandle, error) {
+	key, ref, err := normalizeCRS(id)
+	if err != nil {
+		return CRSHandle{}, err
+	}
+
+	r.mu.RLock()
+	if h, ok := r.handles[key]; ok {
+		r.mu.RUnlock()
+		return h, nil
+	}
+	r.mu.RUnlock()
+
+	h := CRSHandle{id: key, ref: ref}
+
+	r.mu.Lock()
+	r.handles[key] = h
+	r.mu.Unlock()
+
+	return h, nil
+}
+
+// ResolveTransform resolves from and to and caches the pair under their
+// canonical keys, so repeated calls for the same (origin, target)
+// combination skip re-resolving either identifier.
+func (r *Registry) ResolveTransform(from, to any) (*CachedTransform, error) {
+	fromHandle, err := r.Resolve(from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve origin CRS: %w", err)
+	}
+	toHandle, err := r.Resolve(to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve target CRS: %w", err)
+	}
+
+	key := transformKey{from: fromHandle.id, to: toHandle.id}
+
+	r.mu.RLock()
+	if t, ok := r.transforms[key]; ok {
+		r.mu.RUnlock()
+		return t, nil
+	}
+	r.mu.RUnlock()
+
+	t := &CachedTransform{From: fromHandle, To: toHandle}
+
+	r.mu.Lock()
+	r.transforms[key] = t
+	r.mu.Unlock()
+
+	return t, nil
+}
+
+// Transform resolves obj's current CRS and to via ResolveTransform, then
+// delegates to the package-level Transform with the target's canonical
+// PROJ-recognized reference; obj's own CRS string reaches the query
+// unchanged. ctx bounds the underlying DuckDB query.
+func (r *Registry) Transform(ctx context.Context, obj geom.Geometry, to any, inverted bool) (geom.Geometry, error) {
+	t, err := r.ResolveTransform(obj.GetCRS(), to)
+	if err != nil {
+		return nil, err
+	}
+	return Transform(ctx, obj, t.To.String(), inverted)
+}
+
+var (
+	epsgURNPattern  = regexp.MustCompile(`(?i)^urn:ogc:def:crs:([a-z0-9]+):[^:]*:([a-z0-9]+)$`)
+	epsgCodePattern = regexp.MustCompile(`(?i)^epsg:([0-9]+)$`)
+	digitsPattern   = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// normalizeCRS resolves id into a (cache key, PROJ-recognized reference)
+// pair.
+func normalizeCRS(id any) (key string, ref string, err error) {
+	switch v := id.(type) {
+	case string:
+		return normalizeCRSString(v)
+	case map[string]any:
+		return normalizeProjJSON(v)
+	case json.RawMessage:
+		return normalizeProjJSONBytes(v)
+	case []byte:
+		return normalizeProjJSONBytes(v)
+	case CRSHandle:
+		return v.id, v.ref, nil
+	default:
+		return "", "", fmt.Errorf("unsupported CRS identifier type %T", id)
+	}
+}
+
+// normalizeCRSString resolves a string CRS identifier: an EPSG code (bare
+// or "EPSG:"-prefixed), an OGC URN, or a WKT definition passed through
+// verbatim.
+func normalizeCRSString(s string) (string, string, error) {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return "", "", fmt.Errorf("empty CRS identifier")
+	}
+
+	if m := epsgURNPattern.FindStringSubmatch(trimmed); m != nil {
+		ref := fmt.Sprintf("%s:%s", strings.ToUpper(m[1]), m[2])
+		return ref, ref, nil
+	}
+	if m := epsgCodePattern.FindStringSubmatch(trimmed); m != nil {
+		ref := fmt.Sprintf("EPSG:%s", m[1])
+		return ref, ref, nil
+	}
+	if digitsPattern.MatchString(trimmed) {
+		ref := fmt.Sprintf("EPSG:%s", trimmed)
+		return ref, ref, nil
+	}
+
+	// Otherwise assume a WKT2 (or PROJ-recognized) definition; DuckDB's
+	// ST_Transform accepts WKT directly, so pass it through as-is.
+	return trimmed, trimmed, nil
+}
+
+// normalizeProjJSONBytes unmarshals raw JSON bytes into a PROJJSON object
+// before resolving it.
+func normalizeProjJSONBytes(b []byte) (string, string, error) {
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return "", "", fmt.Errorf("invalid PROJJSON: %w", err)
+	}
+	return normalizeProjJSON(m)
+}
+
+// normalizeProjJSON resolves a decoded PROJJSON object, preferring its
+// "id": {"authority": ..., "code": ...} member (the canonical PROJJSON
+// identifier) and falling back to its "name" member when no id is present.
+func normalizeProjJSON(m map[string]any) (string, string, error) {
+	if idObj, ok := m["id"].(map[string]any); ok {
+		authority, _ := idObj["authority"].(string)
+		code := idObj["code"]
+		if authority != "" && code != nil {
+			ref := fmt.Sprintf("%s:%v", authority, code)
+			return ref, ref, nil
+		}
+	}
+	if name, ok := m["name"].(string); ok && name != "" {
+		return "projjson:" + name, name, nil
+	}
+	return "", "", fmt.Errorf(`unrecognized PROJJSON object: no "id" or "name" field`)
+}
@@ -0,0 +1,121 @@
+package projection
+
+import "testing"
+
+func TestRegistryResolve(t *testing.T) {
+	t.Run("bare EPSG code", func(t *testing.T) {
+		r := NewRegistry()
+		h, err := r.Resolve("4326")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.String() != "EPSG:4326" {
+			t.Errorf("expected EPSG:4326, got %s", h.String())
+		}
+	})
+
+	t.Run("EPSG prefixed code", func(t *testing.T) {
+		r := NewRegistry()
+		h, err := r.Resolve("epsg:3857")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.String() != "EPSG:3857" {
+			t.Errorf("expected EPSG:3857, got %s", h.String())
+		}
+	})
+
+	t.Run("OGC URN", func(t *testing.T) {
+		r := NewRegistry()
+		h, err := r.Resolve("urn:ogc:def:crs:EPSG::4326")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.String() != "EPSG:4326" {
+			t.Errorf("expected EPSG:4326, got %s", h.String())
+		}
+	})
+
+	t.Run("WKT passthrough", func(t *testing.T) {
+		r := NewRegistry()
+		wkt := `PROJCS["Indonesia Lambert Conformal Conic", ...]`
+		h, err := r.Resolve(wkt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.String() != wkt {
+			t.Errorf("expected WKT passed through verbatim, got %s", h.String())
+		}
+	})
+
+	t.Run("PROJJSON with id", func(t *testing.T) {
+		r := NewRegistry()
+		projjson := map[string]any{
+			"type": "GeographicCRS",
+			"name": "WGS 84",
+			"id":   map[string]any{"authority": "EPSG", "code": float64(4326)},
+		}
+		h, err := r.Resolve(projjson)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.String() != "EPSG:4326" {
+			t.Errorf("expected EPSG:4326, got %s", h.String())
+		}
+	})
+
+	t.Run("PROJJSON with name only", func(t *testing.T) {
+		r := NewRegistry()
+		projjson := map[string]any{"name": "My Local Grid"}
+		h, err := r.Resolve(projjson)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.String() != "My Local Grid" {
+			t.Errorf("expected My Local Grid, got %s", h.String())
+		}
+	})
+
+	t.Run("unrecognized PROJJSON", func(t *testing.T) {
+		r := NewRegistry()
+		if _, err := r.Resolve(map[string]any{}); err == nil {
+			t.Error("expected error for PROJJSON with no id or name")
+		}
+	})
+
+	t.Run("equivalent identifiers resolve to the same reference", func(t *testing.T) {
+		r := NewRegistry()
+		a, err := r.Resolve("EPSG:4326")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := r.Resolve("4326")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.String() != b.String() {
+			t.Errorf("expected equivalent identifiers to resolve to the same reference, got %s and %s", a.String(), b.String())
+		}
+	})
+}
+
+func TestRegistryResolveTransform(t *testing.T) {
+	r := NewRegistry()
+	ct, err := r.ResolveTransform("4326", "EPSG:3857")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct.From.String() != "EPSG:4326" || ct.To.String() != "EPSG:3857" {
+		t.Errorf("unexpected resolved pair: %s -> %s", ct.From.String(), ct.To.String())
+	}
+
+	// Resolving the same pair again should hit the cache and return an
+	// equivalent result.
+	ct2, err := r.ResolveTransform("EPSG:4326", "3857")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct2.From.String() != ct.From.String() || ct2.To.String() != ct.To.String() {
+		t.Error("expected cached transform to match")
+	}
+}
@@ -0,0 +1,275 @@
+package projection
+
+import (
+	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/route"
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"text/template"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/duckdb/duckdb-go/v2"
+)
+
+// Transformer holds a single DuckDB connection with the spatial extension
+// already installed and loaded, so repeated Transform/TransformBatch calls
+// skip the per-call INSTALL/LOAD cost that otherwise dominates latency when
+// reprojecting many small objects in a loop (e.g. per-vertex reprojection
+// during LRS event location). Callers own the Transformer they construct
+// and must call Close once done with it; the package-level default
+// Transformer used by Transform and TransformBatch is shared and must not
+// be closed.
+type Transformer struct {
+	connector *duckdb.Connector
+	db        *sql.DB
+	arrow     *duckdb.Arrow
+
+	viewSeq uint64
+}
+
+// NewTransformer opens a DuckDB connection and installs and loads the
+// spatial extension once, returning a Transformer ready to reuse across
+// many Transform/TransformBatch calls.
+func NewTransformer(ctx context.Context) (*Transformer, error) {
+	c, err := duckdb.NewConnector("", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	ar, err := duckdb.NewArrowFromConn(conn)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	db := sql.OpenDB(c)
+	if _, err := db.ExecContext(ctx, "install spatial; load spatial;"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load spatial extension: %v", err)
+	}
+
+	return &Transformer{connector: c, db: db, arrow: ar}, nil
+}
+
+// Close releases the Transformer's DuckDB connection.
+func (t *Transformer) Close() error {
+	return t.db.Close()
+}
+
+// nextViewName returns a name to register an Arrow view (or materialize a
+// batched result) under that has not been used before by t, so concurrent
+// Transform/TransformBatch calls on the same Transformer never collide.
+func (t *Transformer) nextViewName() string {
+	return fmt.Sprintf("records_%d", atomic.AddUint64(&t.viewSeq, 1))
+}
+
+// Transform reprojects obj to crs, reusing t's already-loaded DuckDB
+// connection instead of opening a fresh one per call. crs may be the
+// sentinel AutoUTMCRS ("AUTO:UTM"), in which case obj is reprojected into
+// whichever WGS84 UTM zone covers its own centroid instead of a
+// caller-chosen CRS. ctx bounds the DuckDB query driving the
+// reprojection.
+func (t *Transformer) Transform(ctx context.Context, obj geom.Geometry, crs string, inverted bool) (geom.Geometry, error) {
+	if crs == AutoUTMCRS {
+		zone, err := t.AutoUTM(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		crs = zone
+	}
+
+	records := obj.GetRecords()
+	if len(records) == 0 {
+		return nil, fmt.Errorf("object has no records to transform")
+	}
+
+	rr, err := array.NewRecordReader(records[0].Schema(), records)
+	if err != nil {
+		return nil, err
+	}
+	defer rr.Release()
+
+	viewName := t.nextViewName()
+	release, err := t.arrow.RegisterView(rr, viewName)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	query, err := transformQuery(viewName, obj.GetCRS(), crs, inverted)
+	if err != nil {
+		return nil, err
+	}
+
+	out_reader, err := t.arrow.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer out_reader.Release()
+
+	var recs []arrow.RecordBatch
+	for out_reader.Next() {
+		rec := out_reader.RecordBatch()
+		rec.Retain()
+		recs = append(recs, rec)
+
+		return geometryFromRecords(obj, recs, crs)
+	}
+
+	return nil, nil
+}
+
+// TransformBatch reprojects every object in objs to crs, fusing all of them
+// into a single SQL query via UNION ALL so DuckDB only plans the
+// ST_Transform expression once, then reads each object's rows back out by
+// the batch index it was tagged with. inverted applies uniformly to every
+// object, mirroring Transform's parameter of the same name. ctx bounds
+// both the batched query and the per-object reads back out of it.
+func (t *Transformer) TransformBatch(ctx context.Context, objs []geom.Geometry, crs string, inverted bool) ([]geom.Geometry, error) {
+	if len(objs) == 0 {
+		return nil, nil
+	}
+
+	var releases []func()
+	defer func() {
+		for _, release := range releases {
+			release()
+		}
+	}()
+
+	selects := make([]string, len(objs))
+	for i, obj := range objs {
+		records := obj.GetRecords()
+		if len(records) == 0 {
+			return nil, fmt.Errorf("object %d has no records to transform", i)
+		}
+
+		rr, err := array.NewRecordReader(records[0].Schema(), records)
+		if err != nil {
+			return nil, err
+		}
+		defer rr.Release()
+
+		viewName := t.nextViewName()
+		release, err := t.arrow.RegisterView(rr, viewName)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, release)
+
+		query, err := transformQuery(viewName, obj.GetCRS(), crs, inverted)
+		if err != nil {
+			return nil, err
+		}
+		selects[i] = fmt.Sprintf("select %d as __batch_idx, * from (%s)", i, query)
+	}
+
+	unionTable := t.nextViewName()
+	if _, err := t.db.ExecContext(ctx, fmt.Sprintf(
+		"create temp table %s as %s", unionTable, strings.Join(selects, " union all "),
+	)); err != nil {
+		return nil, fmt.Errorf("failed to run batched transform: %v", err)
+	}
+	defer t.db.ExecContext(ctx, fmt.Sprintf("drop table %s", unionTable))
+
+	out := make([]geom.Geometry, len(objs))
+	for i, obj := range objs {
+		reader, err := t.arrow.QueryContext(ctx, fmt.Sprintf(
+			"select * exclude(__batch_idx) from %s where __batch_idx = %d", unionTable, i,
+		))
+		if err != nil {
+			return nil, err
+		}
+
+		var recs []arrow.RecordBatch
+		for reader.Next() {
+			rec := reader.RecordBatch()
+			rec.Retain()
+			recs = append(recs, rec)
+		}
+		reader.Release()
+
+		geomOut, err := geometryFromRecords(obj, recs, crs)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = geomOut
+	}
+
+	return out, nil
+}
+
+// transformQuery builds the CTE query that reprojects view's LAT/LONG
+// columns from originCRS to targetCRS via DuckDB's ST_Transform, splitting
+// the resulting point back into LAT/LONG columns. When inverted is true,
+// ST_Point's arguments are swapped, matching the free function Transform's
+// existing inverted behavior.
+func transformQuery(view, originCRS, targetCRS string, inverted bool) (string, error) {
+	pointArgs := "{{.LatCol}}, {{.LongCol}}"
+	if inverted {
+		pointArgs = "{{.LongCol}}, {{.LatCol}}"
+	}
+
+	raw := fmt.Sprintf(`
+	with
+	transformed as (
+	select
+	* exclude({{.LatCol}}, {{.LongCol}}),
+	ST_Transform(ST_Point(%s), '{{.OriginCRS}}', '{{.TargetCRS}}') as shape
+	from {{.View}}
+	)
+	select * exclude(shape), ST_X(shape) as {{.LongCol}}, ST_Y(shape) as {{.LatCol}} from transformed
+	`, pointArgs)
+
+	tmpl, err := template.New("transformQuery").Parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	data := map[string]string{
+		"LatCol":    "LAT",
+		"LongCol":   "LONG",
+		"OriginCRS": originCRS,
+		"TargetCRS": targetCRS,
+		"View":      view,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// geometryFromRecords reconstructs a geom.Geometry of the same concrete
+// type as obj from recs, the rows read back from a Transform/TransformBatch
+// query.
+func geometryFromRecords(obj geom.Geometry, recs []arrow.RecordBatch, crs string) (geom.Geometry, error) {
+	switch obj.GetGeometryType() {
+	case geom.LRS:
+		out := route.NewLRSRoute(
+			obj.GetAttributes()["RouteID"].(string),
+			recs,
+			crs,
+		)
+		return &out, nil
+
+	case geom.POINTS:
+		out := geom.NewPoints(recs, crs)
+		return &out, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %v for transform", obj.GetGeometryType())
+	}
+}
@@ -0,0 +1,155 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// httpClient builds the *http.Client an ArcGIS call should use, timing
+// out a single round trip at policy.Timeout.
+func (r *LRSRouteRepository) httpClient(policy HTTPPolicy) *http.Client {
+	return &http.Client{Timeout: policy.Timeout}
+}
+
+// arcgisErrorCode extracts error.code from an ArcGIS JSON response body
+// that failed logically rather than at the HTTP level -- ArcGIS reports
+// an invalid/expired token (498/499) and other request errors this way,
+// with an HTTP 200 status. ok is false for a body with no top-level
+// "error" object.
+func arcgisErrorCode(body []byte) (code int, ok bool) {
+	var result struct {
+		Error *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || result.Error == nil {
+		return 0, false
+	}
+	return result.Error.Code, true
+}
+
+// arcgisRouteIDsWhereClause builds the ArcGIS "where" value restricting
+// a feature query to routeIDs' RouteId, or "1=1" (every feature) for an
+// empty/nil routeIDs, matching SyncAll's no-filter pagination.
+func arcgisRouteIDsWhereClause(routeIDs []string) string {
+	switch len(routeIDs) {
+	case 0:
+		return "1=1"
+	case 1:
+		return fmt.Sprintf("RouteId='%s'", routeIDs[0])
+	default:
+		quoted := make([]string, len(routeIDs))
+		for i, id := range routeIDs {
+			quoted[i] = fmt.Sprintf("'%s'", id)
+		}
+		return fmt.Sprintf("RouteId IN (%s)", strings.Join(quoted, ","))
+	}
+}
+
+// fetchArcGISFeatureCountOnce asks the feature service for the total
+// feature count matching where, the unit withArcGISTokenRetry retries
+// (and refreshes token for). Shared by SyncAll's no-filter pagination
+// and FetchArcGISFeatures' RouteId-filtered pagination.
+func (r *LRSRouteRepository) fetchArcGISFeatureCountOnce(ctx context.Context, token, where string, policy HTTPPolicy) (int, error) {
+	params := url.Values{}
+	params.Set("where", where)
+	params.Set("f", "json")
+	params.Set("token", token)
+	params.Set("returnCountOnly", "true")
+
+	fullURL := fmt.Sprintf("%s?%s", r.featureServiceURL, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := r.httpClient(policy).Do(req)
+	if err != nil {
+		return 0, retryable(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("feature count request failed with status: %d", resp.StatusCode)
+		if isRetryableStatus(resp.StatusCode) {
+			return 0, retryable(err)
+		}
+		return 0, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if code, ok := arcgisErrorCode(body); ok {
+		if isTokenExpiredCode(code) {
+			return 0, &tokenExpiredError{code: code}
+		}
+		return 0, fmt.Errorf("arcgis error (code %d)", code)
+	}
+
+	var result struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, err
+	}
+	return result.Count, nil
+}
+
+// fetchArcGISFeaturesPageOnce makes a single paged feature request
+// against where, the unit withArcGISTokenRetry retries (and refreshes
+// token for). Shared by SyncAll's no-filter pagination and
+// FetchArcGISFeatures' RouteId-filtered pagination.
+func (r *LRSRouteRepository) fetchArcGISFeaturesPageOnce(ctx context.Context, token, where string, offset, limit int, policy HTTPPolicy) ([]byte, error) {
+	params := url.Values{}
+	params.Set("where", where)
+	params.Set("outfields", "LINKID,LINK_NAME,SK_LENGTH")
+	params.Set("f", "json")
+	params.Set("token", token)
+	params.Set("returnGeometry", "true")
+	params.Set("returnM", "true")
+	params.Set("returnZ", "true")
+	params.Set("resultOffset", strconv.Itoa(offset))
+	params.Set("resultRecordCount", strconv.Itoa(limit))
+
+	fullURL := fmt.Sprintf("%s?%s", r.featureServiceURL, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.httpClient(policy).Do(req)
+	if err != nil {
+		return nil, retryable(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("feature request failed with status: %d", resp.StatusCode)
+		if isRetryableStatus(resp.StatusCode) {
+			return nil, retryable(err)
+		}
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if code, ok := arcgisErrorCode(body); ok {
+		if isTokenExpiredCode(code) {
+			return nil, &tokenExpiredError{code: code}
+		}
+		return nil, fmt.Errorf("arcgis error (code %d)", code)
+	}
+	return body, nil
+}
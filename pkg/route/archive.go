@@ -0,0 +1,377 @@
+package route
+
+import (
+	"archive/tar"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// archiveFormatVersion is written into every export's manifest header so a
+// future Import can tell whether it understands the archive's layout.
+const archiveFormatVersion = 1
+
+// ExportFilter restricts which lrs_catalogs rows Export includes. Left
+// zero-valued, every catalog version is exported.
+type ExportFilter struct {
+	// Versions restricts the export to these catalog versions. Empty
+	// exports every version in lrs_catalogs.
+	Versions []int
+}
+
+// manifest is the JSON document stored as manifest.json in every export
+// tarball.
+type manifest struct {
+	FormatVersion int               `json:"format_version"`
+	Catalogs      []manifestCatalog `json:"catalogs"`
+}
+
+// manifestCatalog is one lrs_catalogs row in the manifest. PointFile and
+// LineStringFile are paths relative to the archive root (under files/),
+// not the original absolute local paths -- Import rewrites them to a new
+// local layout on extraction. LRS_SEGMENT_FILE isn't exported: it's always
+// derivable from the point file via LRSRoute.SegmentQuery's fallback, so
+// Import leaves it unset and lets that fallback recompute it.
+type manifestCatalog struct {
+	Version        int        `json:"version"`
+	StartDate      time.Time  `json:"start_date"`
+	EndDate        *time.Time `json:"end_date,omitempty"`
+	Author         string     `json:"author"`
+	CommitMsg      string     `json:"commit_msg"`
+	PointFile      string     `json:"point_file,omitempty"`
+	LineStringFile string     `json:"linestring_file,omitempty"`
+}
+
+// Export streams a tar archive of every lrs_catalogs row matching filter
+// to w: a manifest.json (format version header + the selected catalog
+// rows) plus each row's point and linestring parquet files under files/,
+// deduplicated since a merged file is often referenced by several
+// versions. Used for backup and cross-environment migration; see Import.
+func (r *LRSRouteRepository) Export(ctx context.Context, w io.Writer, filter ExportFilter) error {
+	entries, err := r.catalogEntriesForExport(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+
+	m := manifest{FormatVersion: archiveFormatVersion}
+	archivePaths := make(map[string]string) // local path -> archive path
+
+	nextArchivePath := func(localPath string) (string, error) {
+		if localPath == "" {
+			return "", nil
+		}
+		if archivePath, ok := archivePaths[localPath]; ok {
+			return archivePath, nil
+		}
+		archivePath := fmt.Sprintf("files/%d_%s", len(archivePaths), filepath.Base(localPath))
+		archivePaths[localPath] = archivePath
+		return archivePath, nil
+	}
+
+	for _, e := range entries {
+		pointArchivePath, err := nextArchivePath(e.PointFile)
+		if err != nil {
+			return err
+		}
+		lineArchivePath, err := nextArchivePath(e.LineStringFile)
+		if err != nil {
+			return err
+		}
+
+		mc := manifestCatalog{
+			Version:        e.Version,
+			StartDate:      e.StartDate,
+			EndDate:        e.EndDate,
+			Author:         e.Author,
+			CommitMsg:      e.CommitMsg,
+			PointFile:      pointArchivePath,
+			LineStringFile: lineArchivePath,
+		}
+		m.Catalogs = append(m.Catalogs, mc)
+	}
+
+	manifestBytes, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal export manifest: %w", err)
+	}
+	if err := writeTarBytes(tw, "manifest.json", manifestBytes); err != nil {
+		return err
+	}
+
+	for localPath, archivePath := range archivePaths {
+		if err := writeTarFile(tw, archivePath, localPath); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", localPath, err)
+		}
+	}
+
+	return nil
+}
+
+// catalogEntriesForExport returns the lrs_catalogs rows Export should
+// include, applying filter.Versions when set.
+func (r *LRSRouteRepository) catalogEntriesForExport(ctx context.Context, filter ExportFilter) ([]CatalogEntry, error) {
+	if err := r.attachCatalogDB(ctx); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT VERSION, START_DATE, END_DATE, AUTHOR, COMMIT_MSG, LRS_POINT_FILE, LRS_SEGMENT_FILE, LRS_LINESTR_FILE
+		FROM postgres_db.lrs_catalogs
+	`
+	if len(filter.Versions) > 0 {
+		query += fmt.Sprintf(" WHERE VERSION IN (%s)", intListSQL(filter.Versions))
+	}
+	query += " ORDER BY VERSION ASC"
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lrs_catalogs for export: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []CatalogEntry
+	for rows.Next() {
+		var e CatalogEntry
+		var endDate sql.NullTime
+		if err := rows.Scan(&e.Version, &e.StartDate, &endDate, &e.Author, &e.CommitMsg, &e.PointFile, &e.SegmentFile, &e.LineStringFile); err != nil {
+			return nil, fmt.Errorf("failed to scan lrs_catalogs row: %w", err)
+		}
+		if endDate.Valid {
+			e.EndDate = &endDate.Time
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lrs_catalogs rows: %w", err)
+	}
+
+	return entries, nil
+}
+
+// intListSQL renders vs as a comma-separated SQL literal list for an IN
+// clause.
+func intListSQL(vs []int) string {
+	out := ""
+	for i, v := range vs {
+		if i > 0 {
+			out += ","
+		}
+		out += fmt.Sprintf("%d", v)
+	}
+	return out
+}
+
+// writeTarBytes writes a single tar entry containing data.
+func writeTarBytes(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write tar header for %s: %w", name, err)
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// writeTarFile archives the local file at localPath under name.
+func writeTarFile(tw *tar.Writer, name, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write tar header for %s: %w", name, err)
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Import reads a tar archive produced by Export, extracts its parquet
+// files under LRS_DATA_DIR, and replays its manifest into lrs_catalogs
+// with file paths rewritten to the new local layout. Existing history in
+// lrs_catalogs is left alone; importing into a catalog that already has
+// rows at the same VERSION numbers will conflict, so Import is meant for
+// restoring into an empty catalog (e.g. disaster recovery or migrating to
+// a new environment). With opts.DryRun, the manifest and every referenced
+// archive entry are validated but nothing is written.
+func (r *LRSRouteRepository) Import(ctx context.Context, archiveReader io.Reader, opts SyncOptions) error {
+	tr := tar.NewReader(archiveReader)
+
+	var m *manifest
+	extractedFiles := make(map[string]string) // archive path -> extracted local path
+
+	dataDir := os.Getenv("LRS_DATA_DIR")
+	if dataDir == "" {
+		dataDir = "./data"
+	}
+	if !opts.DryRun {
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			return fmt.Errorf("failed to create data dir: %w", err)
+		}
+	}
+
+	nanoStamp := time.Now().UnixNano()
+	fileIdx := 0
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %w", err)
+		}
+
+		if hdr.Name == "manifest.json" {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return fmt.Errorf("failed to read manifest: %w", err)
+			}
+			var parsed manifest
+			if err := json.Unmarshal(data, &parsed); err != nil {
+				return fmt.Errorf("failed to parse manifest: %w", err)
+			}
+			if parsed.FormatVersion > archiveFormatVersion {
+				return fmt.Errorf("archive format version %d is newer than this build supports (%d)", parsed.FormatVersion, archiveFormatVersion)
+			}
+			m = &parsed
+			continue
+		}
+
+		if opts.DryRun {
+			// Still read and discard so the rest of the archive is
+			// validated as well-formed, but record that hdr.Name was
+			// present -- the manifest cross-check below looks entries
+			// up in extractedFiles, and DryRun never extracts a real
+			// local path for replayManifest to use.
+			if _, err := io.Copy(io.Discard, tr); err != nil {
+				return fmt.Errorf("failed to validate archive entry %s: %w", hdr.Name, err)
+			}
+			extractedFiles[hdr.Name] = ""
+			continue
+		}
+
+		localPath := filepath.Join(dataDir, fmt.Sprintf("lrs_import_%d_%d_%s", nanoStamp, fileIdx, filepath.Base(hdr.Name)))
+		fileIdx++
+
+		f, err := os.Create(localPath)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", localPath, err)
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to extract %s: %w", hdr.Name, err)
+		}
+		f.Close()
+
+		extractedFiles[hdr.Name] = localPath
+	}
+
+	if m == nil {
+		return fmt.Errorf("archive has no manifest.json")
+	}
+
+	if opts.DryRun {
+		for _, mc := range m.Catalogs {
+			if mc.PointFile != "" {
+				if _, ok := extractedFiles[mc.PointFile]; !ok {
+					return fmt.Errorf("manifest references missing archive entry %s", mc.PointFile)
+				}
+			}
+			if mc.LineStringFile != "" {
+				if _, ok := extractedFiles[mc.LineStringFile]; !ok {
+					return fmt.Errorf("manifest references missing archive entry %s", mc.LineStringFile)
+				}
+			}
+		}
+		return nil
+	}
+
+	return r.replayManifest(ctx, m, extractedFiles)
+}
+
+// replayManifest inserts each manifest entry into lrs_catalogs, with
+// PointFile/LineStringFile rewritten from their archive paths to
+// extractedFiles' local paths.
+func (r *LRSRouteRepository) replayManifest(ctx context.Context, m *manifest, extractedFiles map[string]string) error {
+	if _, err := r.db.ExecContext(ctx, "INSTALL postgres; LOAD postgres;"); err != nil {
+		return fmt.Errorf("failed to load postgres extension: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, fmt.Sprintf("ATTACH IF NOT EXISTS '%s' AS postgres_db (TYPE POSTGRES)", r.pgConnStr)); err != nil {
+		return fmt.Errorf("failed to attach postgres: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	createTable := `
+	CREATE TABLE IF NOT EXISTS postgres_db.lrs_catalogs (
+		VERSION INTEGER,
+		START_DATE DATE,
+		END_DATE DATE,
+		LRS_POINT_FILE TEXT,
+		LRS_SEGMENT_FILE TEXT,
+		LRS_LINESTR_FILE TEXT,
+		AUTHOR TEXT,
+		COMMIT_MSG TEXT
+	)`
+	if _, err := tx.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("failed to create catalog table: %w", err)
+	}
+
+	insertQuery := `INSERT INTO postgres_db.lrs_catalogs
+		(VERSION, START_DATE, END_DATE, LRS_POINT_FILE, LRS_SEGMENT_FILE, LRS_LINESTR_FILE, AUTHOR, COMMIT_MSG)
+		VALUES (?, ?, ?, ?, '', ?, ?, ?)`
+
+	for _, mc := range m.Catalogs {
+		var pointFile, linestrFile string
+		if mc.PointFile != "" {
+			pointFile = extractedFiles[mc.PointFile]
+		}
+		if mc.LineStringFile != "" {
+			linestrFile = extractedFiles[mc.LineStringFile]
+		}
+
+		var endDate any
+		if mc.EndDate != nil {
+			endDate = *mc.EndDate
+		}
+
+		if _, err := tx.ExecContext(ctx, insertQuery, mc.Version, mc.StartDate, endDate, pointFile, linestrFile, mc.Author, mc.CommitMsg); err != nil {
+			return fmt.Errorf("failed to insert catalog version %d: %w", mc.Version, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit imported catalog: %w", err)
+	}
+
+	return nil
+}
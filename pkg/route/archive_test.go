@@ -0,0 +1,130 @@
+package route
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/duckdb/duckdb-go/v2"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	connector, err := duckdb.NewConnector("", nil)
+	if err != nil {
+		t.Fatalf("Failed to create DuckDB connector: %v", err)
+	}
+	defer connector.Close()
+
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, _ = db.ExecContext(ctx, "install postgres; load postgres;")
+	_, err = db.ExecContext(ctx, fmt.Sprintf("ATTACH IF NOT EXISTS '%s' AS postgres_db (TYPE POSTGRES)", testPgConnStr))
+	if err == nil {
+		_, _ = db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs")
+	}
+
+	originalDir, err := os.MkdirTemp("", "lrs_export_src_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(originalDir)
+
+	os.Setenv("LRS_DATA_DIR", originalDir)
+
+	repo := NewLRSRouteRepository(connector, testPgConnStr, db)
+
+	jsonBytes1, err := os.ReadFile("./testdata/lrs_01001.json")
+	if err != nil {
+		t.Fatalf("Failed to read test JSON: %v", err)
+	}
+	jsonBytes2, err := os.ReadFile("./testdata/lrs_01002.json")
+	if err != nil {
+		t.Fatalf("Failed to read test JSON: %v", err)
+	}
+
+	if err := repo.SyncFromGeoJSON(ctx, jsonBytes1, SyncOptions{Author: "SYSTEM", CommitMsg: "EXPORT TEST 1"}); err != nil {
+		t.Fatalf("SyncFromGeoJSON failed: %v", err)
+	}
+	if err := repo.SyncFromGeoJSON(ctx, jsonBytes2, SyncOptions{Author: "SYSTEM", CommitMsg: "EXPORT TEST 2"}); err != nil {
+		t.Fatalf("SyncFromGeoJSON failed: %v", err)
+	}
+
+	var archive bytes.Buffer
+	if err := repo.Export(ctx, &archive, ExportFilter{}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	// Dry-run import validates the archive without touching anything.
+	if err := repo.Import(ctx, bytes.NewReader(archive.Bytes()), SyncOptions{DryRun: true}); err != nil {
+		t.Fatalf("dry-run Import failed: %v", err)
+	}
+
+	wantRoute1, err := repo.GetLatest(ctx, "01001")
+	if err != nil {
+		t.Fatalf("Failed to get original 01001: %v", err)
+	}
+	defer wantRoute1.Release()
+	wantRoute2, err := repo.GetLatest(ctx, "01002")
+	if err != nil {
+		t.Fatalf("Failed to get original 01002: %v", err)
+	}
+	defer wantRoute2.Release()
+
+	var wantCount1, wantCount2 int64
+	db.QueryRowContext(ctx, fmt.Sprintf("SELECT count(*) FROM %s", wantRoute1.ViewName())).Scan(&wantCount1)
+	db.QueryRowContext(ctx, fmt.Sprintf("SELECT count(*) FROM %s", wantRoute2.ViewName())).Scan(&wantCount2)
+
+	// Wipe: fresh catalog, fresh data directory -- simulating a clean
+	// environment the archive is restored into.
+	_, _ = db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs")
+
+	restoredDir, err := os.MkdirTemp("", "lrs_export_dst_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(restoredDir)
+	os.Setenv("LRS_DATA_DIR", restoredDir)
+	defer os.Unsetenv("LRS_DATA_DIR")
+
+	if err := repo.Import(ctx, bytes.NewReader(archive.Bytes()), SyncOptions{Author: "SYSTEM", CommitMsg: "IMPORT TEST"}); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	gotRoute1, err := repo.GetLatest(ctx, "01001")
+	if err != nil {
+		t.Fatalf("Failed to get restored 01001: %v", err)
+	}
+	defer gotRoute1.Release()
+	gotRoute2, err := repo.GetLatest(ctx, "01002")
+	if err != nil {
+		t.Fatalf("Failed to get restored 01002: %v", err)
+	}
+	defer gotRoute2.Release()
+
+	var gotCount1, gotCount2 int64
+	db.QueryRowContext(ctx, fmt.Sprintf("SELECT count(*) FROM %s", gotRoute1.ViewName())).Scan(&gotCount1)
+	db.QueryRowContext(ctx, fmt.Sprintf("SELECT count(*) FROM %s", gotRoute2.ViewName())).Scan(&gotCount2)
+
+	if gotCount1 != wantCount1 {
+		t.Errorf("expected restored 01001 to have %d points, got %d", wantCount1, gotCount1)
+	}
+	if gotCount2 != wantCount2 {
+		t.Errorf("expected restored 01002 to have %d points, got %d", wantCount2, gotCount2)
+	}
+
+	diff, err := repo.Diff(ctx, "01001", 1, 3)
+	if err != nil {
+		t.Fatalf("Diff between original and restored 01001 failed: %v", err)
+	}
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 || len(diff.Moved) != 0 {
+		t.Errorf("expected restored 01001 to be geometrically identical to the original, got diff %+v", diff)
+	}
+
+	// Cleanup
+	_, _ = db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs")
+}
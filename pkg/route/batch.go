@@ -1,8 +1,16 @@
 package route
 
 import (
+	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/progress"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
 )
 
 type sourceFile struct {
@@ -17,15 +25,128 @@ type batchSourceFiles struct {
 }
 
 type LRSRouteBatch struct {
-	routes       map[string]LRSRoute
-	sourceFiles  *batchSourceFiles
-	latitudeCol  string
-	longitudeCol string
-	mValueCol    string
+	routes        map[string]LRSRoute
+	sourceFiles   *batchSourceFiles
+	latitudeCol   string
+	longitudeCol  string
+	mValueCol     string
+	progress      progress.Progress
+	singleFileDir string
+}
+
+// BatchOption configures optional behavior of an LRSRouteBatch.
+type BatchOption func(*LRSRouteBatch)
+
+// WithProgress reports one unit of progress per AddRoute call, whether or
+// not the route is added. Defaults to progress.NoOp.
+func WithProgress(p progress.Progress) BatchOption {
+	return func(l *LRSRouteBatch) {
+		l.progress = p
+	}
+}
+
+// NewLRSRouteBatch creates an empty LRSRouteBatch ready to receive routes
+// via AddRoute.
+func NewLRSRouteBatch(opts ...BatchOption) *LRSRouteBatch {
+	l := &LRSRouteBatch{progress: progress.NoOp}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// BatchOptions configures whole-batch behavior that doesn't fit
+// BatchOption's per-call functional style.
+type BatchOptions struct {
+	// SingleFile, when true, makes Sink concatenate every route's point
+	// RecordBatches (each row already tagged by ROUTEID) into one flat
+	// Parquet file instead of one file per route, so ViewName reduces to
+	// a single-file scan with nothing left for it to coalesce. Only point
+	// data is flattened: afterwards the batch holds no segment or
+	// linestring sources, and SegmentQuery and LinestringQuery return "".
+	SingleFile bool
+}
+
+// Sink writes every route already added to the batch to disk per opts.
+// With SingleFile, every route's in-memory records (each row already
+// tagged by its own ROUTEID column) are concatenated into one shared
+// Parquet file, replacing whatever per-route files AddRoute sunk them to
+// individually, so the batch becomes a single scan instead of one file
+// per route. The batch's segment and linestring sources are dropped, not
+// rebuilt. A no-op unless opts.SingleFile is set; call it after every
+// route has been added.
+func (l *LRSRouteBatch) Sink(opts BatchOptions) error {
+	if !opts.SingleFile {
+		return nil
+	}
+	if len(l.routes) == 0 {
+		return nil
+	}
+
+	tempDir, err := os.MkdirTemp("", "lrs_batch_*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	l.singleFileDir = tempDir
+
+	filePath := filepath.Join(tempDir, "batch.parquet")
+	f, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	defer f.Close()
+
+	var writer *pqarrow.FileWriter
+	for id, route := range l.routes {
+		if len(route.records) == 0 {
+			return fmt.Errorf("route %q has no records", route.GetRouteID())
+		}
+
+		if writer == nil {
+			writer, err = pqarrow.NewFileWriter(
+				route.records[0].Schema(),
+				f,
+				parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy)),
+				pqarrow.DefaultWriterProps(),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create parquet writer: %v", err)
+			}
+			defer writer.Close()
+		}
+
+		for _, rec := range route.records {
+			if err := writer.WriteBuffered(rec); err != nil {
+				return fmt.Errorf("failed to write record batch for route %q: %v", route.GetRouteID(), err)
+			}
+		}
+
+		route.setPushDown(true)
+		route.source_files = &sourceFiles{Point: &filePath}
+		l.routes[id] = route
+	}
+
+	if writer == nil {
+		return nil
+	}
+
+	l.sourceFiles = &batchSourceFiles{
+		Point: []sourceFile{{
+			filePath:     filePath,
+			routes:       l.RouteIDs(),
+			materialized: true,
+		}},
+	}
+	return nil
 }
 
 // Add LRSRoute to the batch
 func (l *LRSRouteBatch) AddRoute(route LRSRoute) error {
+	if l.progress == nil {
+		l.progress = progress.NoOp
+	}
+	defer l.progress.Add(1)
+
 	if l.routes == nil {
 		l.routes = make(map[string]LRSRoute)
 	}
@@ -151,35 +272,57 @@ func (l *LRSRouteBatch) Release() {
 	for _, route := range l.routes {
 		route.Release()
 	}
+	if l.singleFileDir != "" {
+		os.RemoveAll(l.singleFileDir)
+	}
 }
 
-// ViewName returns a query for loading point data from all source files in the batch
-func (l *LRSRouteBatch) ViewName() string {
-	if l.sourceFiles == nil || len(l.sourceFiles.Point) == 0 {
-		return ""
+// coalesceSourceFiles builds a query over a set of source files sharing a
+// common schema: every pushdown file (AddRoute's per-file ROUTEID list)
+// is combined into a single read_parquet([...]) call with one combined
+// WHERE ROUTEID IN (...) predicate, so DuckDB's Parquet row-group
+// pruning only has to reason about one scan instead of one UNION ALL
+// branch per file. Files with no per-route tracking (no-pushdown) get
+// their own single read_parquet call the same way. Non-materialized
+// (inline SQL) branches aren't files DuckDB can scan together, so they
+// remain separate UNION arms.
+func coalesceSourceFiles(files []sourceFile) string {
+	var pushDownFiles, pushDownRoutes, noPushDownFiles, inlineQueries []string
+
+	for _, sf := range files {
+		switch {
+		case !sf.materialized:
+			inlineQueries = append(inlineQueries, sf.filePath)
+		case len(sf.routes) == 0:
+			noPushDownFiles = append(noPushDownFiles, fmt.Sprintf(`'%s'`, sf.filePath))
+		default:
+			pushDownFiles = append(pushDownFiles, fmt.Sprintf(`'%s'`, sf.filePath))
+			pushDownRoutes = append(pushDownRoutes, sf.routes...)
+		}
 	}
 
 	var queries []string
-	var noPushDownFiles []string
-	for _, sf := range l.sourceFiles.Point {
-		if sf.materialized {
-			if len(sf.routes) == 0 {
-				noPushDownFiles = append(noPushDownFiles, sf.filePath)
-			} else {
-				routeList := strings.Join(sf.routes, "','")
-				queries = append(queries, fmt.Sprintf(`SELECT * FROM "%s" WHERE ROUTEID IN ['%s']`, sf.filePath, routeList))
-			}
-		} else {
-			queries = append(queries, sf.filePath)
-		}
+	if len(pushDownFiles) > 0 {
+		routeList := strings.Join(pushDownRoutes, "','")
+		queries = append(queries, fmt.Sprintf(
+			`SELECT * FROM read_parquet([%s]) WHERE ROUTEID IN ['%s']`,
+			strings.Join(pushDownFiles, ", "), routeList,
+		))
 	}
-
 	if len(noPushDownFiles) > 0 {
-		noPushDownQuery := fmt.Sprintf(`SELECT * FROM read_parquet(["%s"])`, strings.Join(noPushDownFiles, `", "`))
-		queries = append(queries, noPushDownQuery)
+		queries = append(queries, fmt.Sprintf(`SELECT * FROM read_parquet([%s])`, strings.Join(noPushDownFiles, ", ")))
 	}
+	queries = append(queries, inlineQueries...)
 
-	return fmt.Sprintf("(%s)", strings.Join(queries, " UNION ALL "))
+	return strings.Join(queries, " UNION ALL ")
+}
+
+// ViewName returns a query for loading point data from all source files in the batch
+func (l *LRSRouteBatch) ViewName() string {
+	if l.sourceFiles == nil || len(l.sourceFiles.Point) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("(%s)", coalesceSourceFiles(l.sourceFiles.Point))
 }
 
 // SegmentQuery returns a query for loading segment data from all source files in the batch
@@ -187,28 +330,7 @@ func (l *LRSRouteBatch) SegmentQuery() string {
 	if l.sourceFiles == nil || len(l.sourceFiles.Segment) == 0 {
 		return ""
 	}
-
-	var queries []string
-	var noPushDownFiles []string
-	for _, sf := range l.sourceFiles.Segment {
-		if sf.materialized {
-			if len(sf.routes) == 0 {
-				noPushDownFiles = append(noPushDownFiles, sf.filePath)
-			} else {
-				routeList := strings.Join(sf.routes, "','")
-				queries = append(queries, fmt.Sprintf(`SELECT * FROM "%s" WHERE ROUTEID IN ['%s']`, sf.filePath, routeList))
-			}
-		} else {
-			queries = append(queries, sf.filePath)
-		}
-	}
-
-	if len(noPushDownFiles) > 0 {
-		noPushDownQuery := fmt.Sprintf(`SELECT * FROM read_parquet([%s])`, strings.Join(noPushDownFiles, ", "))
-		queries = append(queries, noPushDownQuery)
-	}
-
-	return strings.Join(queries, " UNION ALL ")
+	return coalesceSourceFiles(l.sourceFiles.Segment)
 }
 
 // LinestringQuery returns a query for loading linestring data from all source files in the batch
@@ -216,28 +338,37 @@ func (l *LRSRouteBatch) LinestringQuery() string {
 	if l.sourceFiles == nil || len(l.sourceFiles.LineString) == 0 {
 		return ""
 	}
+	return coalesceSourceFiles(l.sourceFiles.LineString)
+}
 
-	var queries []string
-	var noPushDownFiles []string
-	for _, sf := range l.sourceFiles.LineString {
-		if sf.materialized {
-			if len(sf.routes) == 0 {
-				noPushDownFiles = append(noPushDownFiles, sf.filePath)
-			} else {
-				routeList := strings.Join(sf.routes, "','")
-				queries = append(queries, fmt.Sprintf(`SELECT * FROM "%s" WHERE ROUTEID IN ['%s']`, sf.filePath, routeList))
-			}
-		} else {
-			queries = append(queries, sf.filePath)
-		}
+// RouteIDs returns the RouteID of every route added to the batch, in no
+// particular order.
+func (l *LRSRouteBatch) RouteIDs() []string {
+	ids := make([]string, 0, len(l.routes))
+	for id := range l.routes {
+		ids = append(ids, id)
 	}
+	return ids
+}
 
-	if len(noPushDownFiles) > 0 {
-		noPushDownQuery := fmt.Sprintf(`SELECT * FROM read_parquet([%s])`, strings.Join(noPushDownFiles, ", "))
-		queries = append(queries, noPushDownQuery)
+// RouteIndex implements RouteIndexer by looking up routeID among the
+// routes added to the batch and returning its own segment Index.
+func (l *LRSRouteBatch) RouteIndex(routeID string) (*geom.STRTree, error) {
+	rte, ok := l.routes[routeID]
+	if !ok {
+		return nil, fmt.Errorf("route %q not found in batch", routeID)
 	}
+	return rte.Index()
+}
 
-	return strings.Join(queries, " UNION ALL ")
+// LocateMValue implements MValueLocator by looking up routeID among the
+// routes added to the batch and delegating to its own LocateMValue.
+func (l *LRSRouteBatch) LocateMValue(routeID string, target float64) (lat, lon float64, err error) {
+	rte, ok := l.routes[routeID]
+	if !ok {
+		return 0, 0, fmt.Errorf("route %q not found in batch", routeID)
+	}
+	return rte.LocateMValue(routeID, target)
 }
 
 func (l *LRSRouteBatch) LatitudeColumn() string {
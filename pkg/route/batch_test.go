@@ -12,9 +12,13 @@ func createTestLRSRouteFromJSON(t *testing.T, routeID string, filename string) L
 		t.Fatalf("Failed to read test data %s: %v", filename, err)
 	}
 
-	// Assuming CRS is available in the JSON or use a default one like geom.LAMBERT_WKT
-	// NewLRSRouteFromESRIGeoJSON handles the parsing
-	route := NewLRSRouteFromESRIGeoJSON(routeID, jsonByte, 0, geom.LAMBERT_WKT)
+	route, err := NewLRSRouteFromESRIGeoJSON(jsonByte, 0, geom.LAMBERT_WKT)
+	if err != nil {
+		t.Fatalf("NewLRSRouteFromESRIGeoJSON failed for %s: %v", filename, err)
+	}
+	if route.route_id != routeID {
+		t.Fatalf("expected route_id %s, got %s", routeID, route.route_id)
+	}
 	return route
 }
 
@@ -162,9 +166,58 @@ func TestLRSRouteBatch(t *testing.T) {
 		batch.AddRoute(route2)
 
 		query := batch.ViewName()
-		expected := `(SELECT * FROM "shared_point.parquet" WHERE ROUTEID IN ['01001','01002'])`
+		expected := `(SELECT * FROM read_parquet(['shared_point.parquet']) WHERE ROUTEID IN ['01001','01002'])`
 		if query != expected {
 			t.Errorf("Expected query %s, got %s", expected, query)
 		}
 	})
+
+	t.Run("ViewNameCoalescesMultiplePushDownFiles", func(t *testing.T) {
+		batch := &LRSRouteBatch{
+			sourceFiles: &batchSourceFiles{},
+		}
+
+		point1 := "point1.parquet"
+		route1 := LRSRoute{route_id: "01001", source_files: &sourceFiles{Point: &point1}}
+		route1.setPushDown(true)
+
+		point2 := "point2.parquet"
+		route2 := LRSRoute{route_id: "01002", source_files: &sourceFiles{Point: &point2}}
+		route2.setPushDown(true)
+
+		batch.AddRoute(route1)
+		batch.AddRoute(route2)
+
+		query := batch.ViewName()
+		expected := `(SELECT * FROM read_parquet(['point1.parquet', 'point2.parquet']) WHERE ROUTEID IN ['01001','01002'])`
+		if query != expected {
+			t.Errorf("Expected a single coalesced read_parquet call, got %s", query)
+		}
+	})
+
+	t.Run("SinkSingleFileFlattensBatch", func(t *testing.T) {
+		batch := NewLRSRouteBatch()
+
+		route1 := createTestLRSRouteFromJSON(t, "01001", "lrs_01001.json")
+		route2 := createTestLRSRouteFromJSON(t, "15010", "lrs_15010.json")
+
+		if err := batch.AddRoute(route1); err != nil {
+			t.Fatalf("AddRoute failed: %v", err)
+		}
+		if err := batch.AddRoute(route2); err != nil {
+			t.Fatalf("AddRoute failed: %v", err)
+		}
+		defer batch.Release()
+
+		if err := batch.Sink(BatchOptions{SingleFile: true}); err != nil {
+			t.Fatalf("Sink(SingleFile) failed: %v", err)
+		}
+
+		if len(batch.sourceFiles.Point) != 1 {
+			t.Fatalf("Expected a single flattened point source file, got %d", len(batch.sourceFiles.Point))
+		}
+		if len(batch.sourceFiles.Point[0].routes) != 2 {
+			t.Errorf("Expected both routes tracked against the flattened file, got %v", batch.sourceFiles.Point[0].routes)
+		}
+	})
 }
@@ -0,0 +1,52 @@
+package route
+
+import (
+	"bm-lrs/pkg/geom"
+	"context"
+)
+
+// RouteBBoxIndex is a coarse spatial index over every known route's
+// overall bounding box (see LRSRoute.BBox), used to narrow a candidate
+// point down to a handful of routes worth checking with Index()/
+// NearestOnRoute before walking their segments -- the route-level
+// counterpart to Index()'s per-segment STR-tree.
+type RouteBBoxIndex struct {
+	tree *geom.STRTree
+}
+
+// NewRouteBBoxIndex loads every route ID known to repo's latest catalog
+// and indexes each route's overall bounding box. Routes that fail to load
+// or whose bbox can't be computed are skipped rather than failing the
+// whole index, since it's meant as a coarse, best-effort first pass.
+func NewRouteBBoxIndex(ctx context.Context, repo *LRSRouteRepository) (*RouteBBoxIndex, error) {
+	routeIDs, err := repo.ListRouteIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []geom.Candidate
+	for _, routeID := range routeIDs {
+		r, err := repo.GetLatest(ctx, routeID)
+		if err != nil {
+			continue
+		}
+
+		if box, err := r.BBox(); err == nil {
+			candidates = append(candidates, geom.Candidate{Item: routeID, Box: box})
+		}
+		r.Release()
+	}
+
+	return &RouteBBoxIndex{tree: geom.NewSTRTree(candidates)}, nil
+}
+
+// Nearest returns up to k route IDs whose bounding box is nearest to
+// (lat, lon), ordered by ascending distance to each box's center.
+func (idx *RouteBBoxIndex) Nearest(lat, lon float64, k int) []string {
+	candidates := idx.tree.NearestK(lon, lat, k)
+	ids := make([]string, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.Item.(string)
+	}
+	return ids
+}
@@ -0,0 +1,240 @@
+package route
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// CSVOptions configures NewLRSRouteFromCSV/NewLRSRouteBatchFromCSV's
+// column mapping and dialect. Column names are matched against the
+// file's header row. Every field defaults to the Arrow schema column
+// names used throughout pkg/route (LAT, LON, MVAL, VERTEX_SEQ, ROUTEID)
+// when left empty, so a file already using those headers needs no
+// mapping at all. When VertexSeqColumn isn't present in the header,
+// vertices are numbered by their row order within each route instead.
+type CSVOptions struct {
+	LatitudeColumn  string
+	LongitudeColumn string
+	MValueColumn    string
+	VertexSeqColumn string
+	RouteIDColumn   string
+	Delimiter       rune
+	CRS             string
+}
+
+// withDefaults fills in every column-name field and Delimiter left unset;
+// CRS is left as given.
+func (o CSVOptions) withDefaults() CSVOptions {
+	if o.LatitudeColumn == "" {
+		o.LatitudeColumn = "LAT"
+	}
+	if o.LongitudeColumn == "" {
+		o.LongitudeColumn = "LON"
+	}
+	if o.MValueColumn == "" {
+		o.MValueColumn = "MVAL"
+	}
+	if o.VertexSeqColumn == "" {
+		o.VertexSeqColumn = "VERTEX_SEQ"
+	}
+	if o.RouteIDColumn == "" {
+		o.RouteIDColumn = "ROUTEID"
+	}
+	if o.Delimiter == 0 {
+		o.Delimiter = ','
+	}
+	return o
+}
+
+// NewLRSRouteFromCSV reads a single route's vertices from reader (CSV,
+// TSV, or any other single-character-delimited dialect opts.Delimiter
+// names), building the same Arrow schema used throughout pkg/route and
+// routing through NewLRSRoute. Every row must share the same
+// opts.RouteIDColumn value; use NewLRSRouteBatchFromCSV to ingest a file
+// holding more than one route.
+func NewLRSRouteFromCSV(reader io.Reader, opts CSVOptions) (LRSRoute, error) {
+	routes, order, err := parseLRSRoutesFromCSV(reader, opts)
+	if err != nil {
+		return LRSRoute{}, err
+	}
+	if len(order) != 1 {
+		return LRSRoute{}, fmt.Errorf("expected a single route, got %d distinct %q values; use NewLRSRouteBatchFromCSV", len(order), opts.withDefaults().RouteIDColumn)
+	}
+	return routes[order[0]], nil
+}
+
+// NewLRSRouteBatchFromCSV is NewLRSRouteFromCSV's multi-route sibling: in
+// a single pass over reader, it groups rows by opts.RouteIDColumn and
+// returns one LRSRoute per group as a populated LRSRouteBatch.
+func NewLRSRouteBatchFromCSV(reader io.Reader, opts CSVOptions, batchOpts ...BatchOption) (*LRSRouteBatch, error) {
+	routes, order, err := parseLRSRoutesFromCSV(reader, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := NewLRSRouteBatch(batchOpts...)
+	for _, routeID := range order {
+		if err := batch.AddRoute(routes[routeID]); err != nil {
+			batch.Release()
+			return nil, fmt.Errorf("failed to add route %q to batch: %w", routeID, err)
+		}
+	}
+	return batch, nil
+}
+
+// csvRouteBuilders accumulates one route's Arrow builders while
+// parseLRSRoutesFromCSV scans rows in a single pass.
+type csvRouteBuilders struct {
+	lat, lon, mval *array.Float64Builder
+	vertexSeq      *array.Int32Builder
+	routeID        *array.StringBuilder
+	nextSeq        int32
+}
+
+// parseLRSRoutesFromCSV reads reader's header row to resolve opts'
+// column names, then scans every row once, grouping rows into one set of
+// builders per distinct route id (in first-seen order) and building an
+// LRSRoute per group.
+func parseLRSRoutesFromCSV(reader io.Reader, opts CSVOptions) (map[string]LRSRoute, []string, error) {
+	opts = opts.withDefaults()
+
+	r := csv.NewReader(reader)
+	r.Comma = opts.Delimiter
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	colIdx := make(map[string]int, len(header))
+	for i, name := range header {
+		colIdx[name] = i
+	}
+
+	latIdx, ok := colIdx[opts.LatitudeColumn]
+	if !ok {
+		return nil, nil, fmt.Errorf("csv missing latitude column %q", opts.LatitudeColumn)
+	}
+	lonIdx, ok := colIdx[opts.LongitudeColumn]
+	if !ok {
+		return nil, nil, fmt.Errorf("csv missing longitude column %q", opts.LongitudeColumn)
+	}
+	mvalIdx, ok := colIdx[opts.MValueColumn]
+	if !ok {
+		return nil, nil, fmt.Errorf("csv missing m-value column %q", opts.MValueColumn)
+	}
+	routeIdx, ok := colIdx[opts.RouteIDColumn]
+	if !ok {
+		return nil, nil, fmt.Errorf("csv missing route id column %q", opts.RouteIDColumn)
+	}
+	vertexSeqIdx, hasVertexSeq := colIdx[opts.VertexSeqColumn]
+
+	pool := memory.NewGoAllocator()
+	builders := make(map[string]*csvRouteBuilders)
+	var order []string
+
+	rowNum := 1
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read csv row %d: %w", rowNum, err)
+		}
+		rowNum++
+
+		routeID := row[routeIdx]
+		b, ok := builders[routeID]
+		if !ok {
+			b = &csvRouteBuilders{
+				lat:       array.NewFloat64Builder(pool),
+				lon:       array.NewFloat64Builder(pool),
+				mval:      array.NewFloat64Builder(pool),
+				vertexSeq: array.NewInt32Builder(pool),
+				routeID:   array.NewStringBuilder(pool),
+			}
+			builders[routeID] = b
+			order = append(order, routeID)
+		}
+
+		lat, err := strconv.ParseFloat(row[latIdx], 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("row %d: invalid %q value %q: %w", rowNum, opts.LatitudeColumn, row[latIdx], err)
+		}
+		lon, err := strconv.ParseFloat(row[lonIdx], 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("row %d: invalid %q value %q: %w", rowNum, opts.LongitudeColumn, row[lonIdx], err)
+		}
+		mval, err := strconv.ParseFloat(row[mvalIdx], 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("row %d: invalid %q value %q: %w", rowNum, opts.MValueColumn, row[mvalIdx], err)
+		}
+
+		seq := b.nextSeq
+		if hasVertexSeq {
+			parsed, err := strconv.ParseInt(row[vertexSeqIdx], 10, 32)
+			if err != nil {
+				return nil, nil, fmt.Errorf("row %d: invalid %q value %q: %w", rowNum, opts.VertexSeqColumn, row[vertexSeqIdx], err)
+			}
+			seq = int32(parsed)
+		}
+		b.nextSeq = seq + 1
+
+		b.lat.Append(lat)
+		b.lon.Append(lon)
+		b.mval.Append(mval)
+		b.vertexSeq.Append(seq)
+		b.routeID.Append(routeID)
+	}
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "VERTEX_SEQ", Type: arrow.PrimitiveTypes.Int32},
+			{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+		},
+		nil,
+	)
+
+	routes := make(map[string]LRSRoute, len(order))
+	for _, routeID := range order {
+		b := builders[routeID]
+
+		lat_arr := b.lat.NewArray()
+		lon_arr := b.lon.NewArray()
+		mval_arr := b.mval.NewArray()
+		vertex_seq_arr := b.vertexSeq.NewArray()
+		routeid_arr := b.routeID.NewArray()
+
+		rec := array.NewRecordBatch(
+			schema,
+			[]arrow.Array{lat_arr, lon_arr, mval_arr, vertex_seq_arr, routeid_arr},
+			int64(vertex_seq_arr.Len()),
+		)
+
+		lat_arr.Release()
+		lon_arr.Release()
+		mval_arr.Release()
+		vertex_seq_arr.Release()
+		routeid_arr.Release()
+
+		b.lat.Release()
+		b.lon.Release()
+		b.mval.Release()
+		b.vertexSeq.Release()
+		b.routeID.Release()
+
+		routes[routeID] = NewLRSRoute(routeID, []arrow.RecordBatch{rec}, opts.CRS)
+	}
+
+	return routes, order, nil
+}
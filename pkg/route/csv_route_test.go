@@ -0,0 +1,71 @@
+package route
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewLRSRouteBatchFromCSVGroupsByRouteID(t *testing.T) {
+	data := "ROUTEID,LAT,LON,MVAL\n" +
+		"01001,0,0,0\n" +
+		"01001,1,1,1\n" +
+		"01002,2,2,0\n"
+
+	batch, err := NewLRSRouteBatchFromCSV(strings.NewReader(data), CSVOptions{CRS: "EPSG:4326"})
+	if err != nil {
+		t.Fatalf("NewLRSRouteBatchFromCSV failed: %v", err)
+	}
+	defer batch.Release()
+
+	ids := batch.RouteIDs()
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 distinct routes, got %d: %v", len(ids), ids)
+	}
+
+	_, err = NewLRSRouteFromCSV(strings.NewReader(data), CSVOptions{})
+	if err == nil {
+		t.Error("expected NewLRSRouteFromCSV to reject a multi-route file")
+	}
+}
+
+func TestNewLRSRouteFromCSVCustomColumnsAndDelimiter(t *testing.T) {
+	data := "route\tlatitude\tlongitude\tmeasure\n" +
+		"L01\t0\t0\t0\n" +
+		"L01\t1\t1\t1\n"
+
+	opts := CSVOptions{
+		RouteIDColumn:   "route",
+		LatitudeColumn:  "latitude",
+		LongitudeColumn: "longitude",
+		MValueColumn:    "measure",
+		Delimiter:       '\t',
+		CRS:             "EPSG:4326",
+	}
+
+	route, err := NewLRSRouteFromCSV(strings.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("NewLRSRouteFromCSV failed: %v", err)
+	}
+	defer route.Release()
+
+	if route.GetRouteID() != "L01" {
+		t.Errorf("expected route id L01, got %s", route.GetRouteID())
+	}
+	if route.GetCRS() != "EPSG:4326" {
+		t.Errorf("expected crs EPSG:4326, got %s", route.GetCRS())
+	}
+
+	recs := route.GetRecords()
+	if len(recs) != 1 || recs[0].NumRows() != 2 {
+		t.Fatalf("expected 2 vertex rows, got %+v", recs)
+	}
+}
+
+func TestNewLRSRouteFromCSVMissingColumn(t *testing.T) {
+	data := "ROUTEID,LAT,LON\n01001,0,0\n"
+
+	_, err := NewLRSRouteFromCSV(strings.NewReader(data), CSVOptions{})
+	if err == nil {
+		t.Error("expected an error for a missing MVAL column")
+	}
+}
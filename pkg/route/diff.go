@@ -0,0 +1,107 @@
+package route
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Vertex is a single LRS point, identified by its VERTEX_SEQ within a
+// route.
+type Vertex struct {
+	VertexSeq int32
+	Lat       float64
+	Lon       float64
+	MVal      float64
+}
+
+// MovedVertex pairs the same VERTEX_SEQ across two versions whose position
+// and/or M value changed.
+type MovedVertex struct {
+	VertexSeq int32
+	From      Vertex
+	To        Vertex
+	MValDelta float64
+}
+
+// RouteDiff is the result of comparing routeID's geometry between two
+// catalog versions.
+type RouteDiff struct {
+	RouteID     string
+	FromVersion int
+	ToVersion   int
+	Added       []Vertex
+	Removed     []Vertex
+	Moved       []MovedVertex
+}
+
+// Diff compares routeID's point geometry between fromVersion and
+// toVersion, joining the two catalog versions' point parquet files on
+// ROUTEID+VERTEX_SEQ. A VERTEX_SEQ present only in toVersion is Added,
+// present only in fromVersion is Removed, and present in both with a
+// changed LAT/LON/MVAL is Moved, carrying the M-value delta (ToMVal minus
+// FromMVal).
+func (r *LRSRouteRepository) Diff(ctx context.Context, routeID string, fromVersion, toVersion int) (*RouteDiff, error) {
+	from, err := r.catalogEntryAtVersion(ctx, fromVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up fromVersion: %w", err)
+	}
+	to, err := r.catalogEntryAtVersion(ctx, toVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up toVersion: %w", err)
+	}
+
+	conn, err := r.connector.Connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get db connection: %w", err)
+	}
+	defer conn.Close()
+
+	query := fmt.Sprintf(`
+		SELECT
+			COALESCE(a.VERTEX_SEQ, b.VERTEX_SEQ) AS VERTEX_SEQ,
+			a.LAT, a.LON, a.MVAL,
+			b.LAT, b.LON, b.MVAL
+		FROM (SELECT * FROM '%s' WHERE ROUTEID = '%s') a
+		FULL OUTER JOIN (SELECT * FROM '%s' WHERE ROUTEID = '%s') b
+			ON a.VERTEX_SEQ = b.VERTEX_SEQ
+		ORDER BY VERTEX_SEQ
+	`, from.PointFile, routeID, to.PointFile, routeID)
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff route points: %w", err)
+	}
+	defer rows.Close()
+
+	diff := &RouteDiff{RouteID: routeID, FromVersion: fromVersion, ToVersion: toVersion}
+
+	for rows.Next() {
+		var vertexSeq int32
+		var fromLat, fromLon, fromMVal, toLat, toLon, toMVal sql.NullFloat64
+		if err := rows.Scan(&vertexSeq, &fromLat, &fromLon, &fromMVal, &toLat, &toLon, &toMVal); err != nil {
+			return nil, fmt.Errorf("failed to scan diff row: %w", err)
+		}
+
+		switch {
+		case !fromLat.Valid && toLat.Valid:
+			diff.Added = append(diff.Added, Vertex{VertexSeq: vertexSeq, Lat: toLat.Float64, Lon: toLon.Float64, MVal: toMVal.Float64})
+
+		case fromLat.Valid && !toLat.Valid:
+			diff.Removed = append(diff.Removed, Vertex{VertexSeq: vertexSeq, Lat: fromLat.Float64, Lon: fromLon.Float64, MVal: fromMVal.Float64})
+
+		case fromLat.Float64 != toLat.Float64 || fromLon.Float64 != toLon.Float64 || fromMVal.Float64 != toMVal.Float64:
+			diff.Moved = append(diff.Moved, MovedVertex{
+				VertexSeq: vertexSeq,
+				From:      Vertex{VertexSeq: vertexSeq, Lat: fromLat.Float64, Lon: fromLon.Float64, MVal: fromMVal.Float64},
+				To:        Vertex{VertexSeq: vertexSeq, Lat: toLat.Float64, Lon: toLon.Float64, MVal: toMVal.Float64},
+				MValDelta: toMVal.Float64 - fromMVal.Float64,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating diff rows: %w", err)
+	}
+
+	return diff, nil
+}
@@ -36,6 +36,62 @@ func (e *EsriRouteJson) FeatureCount() int {
 	return len(e.Features)
 }
 
+// EsriRouteFeatureInput is one route's worth of vertices (lon, lat, m)
+// going into MarshalEsriRouteJSON, in path order.
+type EsriRouteFeatureInput struct {
+	RouteID string
+	Path    [][3]float64
+}
+
+// MarshalEsriRouteJSON reproduces the EsriRouteJson schema
+// (spatialReference.wkt, features[].geometry.paths, hasM=true) consumed by
+// NewLRSRouteFromESRIGeoJSON, so routes or events derived in this module
+// can be POSTed back to an ArcGIS Feature Service. routeIDKey names the
+// feature attribute each RouteID is written under, defaulting to "LINKID".
+func MarshalEsriRouteJSON(wkt string, routeIDKey string, features []EsriRouteFeatureInput) ([]byte, error) {
+	if routeIDKey == "" {
+		routeIDKey = routeIDPropertyKey
+	}
+
+	out := EsriRouteJson{
+		SpatialReference: spatRef{WKT: wkt},
+		Features:         make([]featureRow, 0, len(features)),
+	}
+
+	for _, f := range features {
+		path := make([]vertexes, len(f.Path))
+		for i, v := range f.Path {
+			path[i] = vertexes(v)
+		}
+		out.Features = append(out.Features, featureRow{
+			Geometry:   featureGeom{HasM: true, Paths: [][]vertexes{path}},
+			Attributes: map[string]any{routeIDKey: f.RouteID},
+		})
+	}
+
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// ToEsriRouteJSON converts the route to the ESRI JSON schema consumed by
+// NewLRSRouteFromESRIGeoJSON, as a single feature carrying all vertices in
+// VERTEX_SEQ order as one path.
+func (l *LRSRoute) ToEsriRouteJSON() ([]byte, error) {
+	vertices, err := collectVertices(l.records, l.latitudeCol, l.longitudeCol, l.mValueCol, l.VertexSeqColumn)
+	if err != nil {
+		return nil, err
+	}
+	sortVertices(vertices)
+
+	path := make([][3]float64, len(vertices))
+	for i, v := range vertices {
+		path[i] = [3]float64{v.lon, v.lat, v.mval}
+	}
+
+	return MarshalEsriRouteJSON(l.crs, "", []EsriRouteFeatureInput{
+		{RouteID: l.route_id, Path: path},
+	})
+}
+
 // Create LRSRoute from ESRI GeoJSON
 func NewLRSRouteFromESRIGeoJSON(jsonbyte []byte, feature_idx int, crs string) (LRSRoute, error) {
 	var esriJson EsriRouteJson
@@ -0,0 +1,335 @@
+package route
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LRSEventType identifies what kind of mutation an LRSEvent describes.
+type LRSEventType string
+
+const (
+	// EventTypeRouteSynced is emitted once per RouteID whenever Sync,
+	// SyncFromGeoJSON, or SyncAll folds that route's geometry into a new
+	// catalog version.
+	EventTypeRouteSynced LRSEventType = "route_synced"
+
+	// EventTypeRouteDeleted is emitted when a route is removed from the
+	// catalog. No LRSRouteRepository method deletes a route yet; the
+	// constant exists so subscribers can already switch on it once one
+	// does.
+	EventTypeRouteDeleted LRSEventType = "route_deleted"
+
+	// EventTypeCatalogVersionCreated is emitted once per new lrs_catalogs
+	// row, right after the version-cut transaction committing it
+	// succeeds.
+	EventTypeCatalogVersionCreated LRSEventType = "catalog_version_created"
+
+	// EventTypeCatalogVersionRolledBack is emitted by RollbackVersion once
+	// a bad version's row has been removed and its prior version
+	// re-opened. RouteID is empty, like EventTypeCatalogVersionCreated.
+	EventTypeCatalogVersionRolledBack LRSEventType = "catalog_version_rolled_back"
+)
+
+// LRSEvent is a single catalog mutation, delivered to every subscriber of
+// LRSRouteRepository.StreamEvents. RouteID is empty for
+// EventTypeCatalogVersionCreated, which describes the catalog as a whole
+// rather than a single route.
+type LRSEvent struct {
+	Type      LRSEventType
+	RouteID   string
+	Version   int
+	Author    string
+	CommitMsg string
+	Timestamp time.Time
+}
+
+// StreamEventsOptions configures StreamEvents.
+type StreamEventsOptions struct {
+	// IncludePast replays every existing lrs_catalogs row, oldest first,
+	// as EventTypeCatalogVersionCreated events before switching to live
+	// tailing. Per-route history isn't replayed: lrs_catalogs only
+	// records catalog-wide versions, not which routes changed in each
+	// one, so EventTypeRouteSynced events are only ever emitted live.
+	IncludePast bool
+
+	// PollInterval is how often the live tail checks lrs_catalogs for a
+	// new MAX(VERSION), catching mutations committed by another process
+	// sharing the same Postgres catalog. Defaults to 2 seconds when zero.
+	PollInterval time.Duration
+}
+
+// EventStream is the handle StreamEvents returns alongside its channel.
+// Close stops the background tailing goroutine and closes the channel;
+// callers that only read the channel until ctx is done don't need to call
+// it.
+type EventStream struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Close stops the stream's tailing goroutine and waits for it to exit.
+func (s *EventStream) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// subscribe registers a new live-event receiver, returning its ID (for
+// unsubscribe) and the channel publish sends to.
+func (r *LRSRouteRepository) subscribe() (uint64, chan *LRSEvent) {
+	r.eventMu.Lock()
+	defer r.eventMu.Unlock()
+
+	if r.eventSubs == nil {
+		r.eventSubs = make(map[uint64]chan *LRSEvent)
+	}
+	r.eventSubSeq++
+	id := r.eventSubSeq
+	ch := make(chan *LRSEvent, 16)
+	r.eventSubs[id] = ch
+	return id, ch
+}
+
+// unsubscribe removes and closes the subscriber registered under id.
+func (r *LRSRouteRepository) unsubscribe(id uint64) {
+	r.eventMu.Lock()
+	defer r.eventMu.Unlock()
+	if ch, ok := r.eventSubs[id]; ok {
+		delete(r.eventSubs, id)
+		close(ch)
+	}
+}
+
+// publish fans ev out to every live subscriber, dropping it for any
+// subscriber whose buffer is full rather than blocking the mutation that
+// triggered it.
+func (r *LRSRouteRepository) publish(ev *LRSEvent) {
+	r.eventMu.Lock()
+	defer r.eventMu.Unlock()
+	for _, ch := range r.eventSubs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// StreamEvents subscribes to every mutation LRSRouteRepository performs --
+// SyncFromGeoJSON, Sync, SyncAll, and the version-cut writes to
+// lrs_catalogs they share -- delivering one LRSEvent per route synced plus
+// one per catalog version created. With IncludePast, existing
+// lrs_catalogs rows are replayed as EventTypeCatalogVersionCreated events,
+// oldest first, before the channel switches to live tailing; live events
+// published in-process arrive immediately, and a polling loop keyed on
+// MAX(VERSION) additionally catches versions committed by another process
+// sharing the same catalog. The returned EventStream's Close stops the
+// tail and closes the channel; it's also fine to just let ctx expire.
+func (r *LRSRouteRepository) StreamEvents(ctx context.Context, opts StreamEventsOptions) (<-chan *LRSEvent, *EventStream, error) {
+	pollInterval := opts.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = 2 * time.Second
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+
+	// Subscribe before replaying so no live mutation is missed in the
+	// gap between reading catalog history and registering the
+	// subscriber.
+	id, liveCh := r.subscribe()
+
+	out := make(chan *LRSEvent, 16)
+	done := make(chan struct{})
+	stream := &EventStream{cancel: cancel, done: done}
+
+	var lastVersion int
+	if opts.IncludePast {
+		past, err := r.replayPastVersions(streamCtx)
+		if err != nil {
+			cancel()
+			r.unsubscribe(id)
+			close(done)
+			return nil, nil, fmt.Errorf("failed to replay past catalog versions: %w", err)
+		}
+		for _, ev := range past {
+			if ev.Version > lastVersion {
+				lastVersion = ev.Version
+			}
+		}
+		go replayThenTail(streamCtx, r, id, liveCh, past, out, done, pollInterval, lastVersion)
+		return out, stream, nil
+	}
+
+	latest, err := r.latestCatalogVersion(streamCtx)
+	if err != nil {
+		cancel()
+		r.unsubscribe(id)
+		close(done)
+		return nil, nil, fmt.Errorf("failed to read current catalog version: %w", err)
+	}
+	lastVersion = latest
+
+	go replayThenTail(streamCtx, r, id, liveCh, nil, out, done, pollInterval, lastVersion)
+	return out, stream, nil
+}
+
+// replayThenTail drains past (already fetched by StreamEvents) into out,
+// then forwards live-published events from liveCh and polls for catalog
+// versions newer than lastVersion until ctx is done.
+func replayThenTail(
+	ctx context.Context,
+	r *LRSRouteRepository,
+	subID uint64,
+	liveCh chan *LRSEvent,
+	past []*LRSEvent,
+	out chan *LRSEvent,
+	done chan struct{},
+	pollInterval time.Duration,
+	lastVersion int,
+) {
+	defer close(done)
+	defer close(out)
+	defer r.unsubscribe(subID)
+
+	for _, ev := range past {
+		select {
+		case out <- ev:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case ev, ok := <-liveCh:
+			if !ok {
+				return
+			}
+			if ev.Version > lastVersion {
+				lastVersion = ev.Version
+			}
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return
+			}
+
+		case <-ticker.C:
+			versions, err := r.newCatalogVersionsSince(ctx, lastVersion)
+			if err != nil {
+				continue
+			}
+			for _, ev := range versions {
+				if ev.Version > lastVersion {
+					lastVersion = ev.Version
+				}
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// attachCatalogDB installs the postgres extension, attaches r's catalog
+// database, and ensures lrs_catalogs exists -- the same setup
+// mergeWithExisting performs before writing a new version -- so
+// StreamEvents can query it even before the first Sync/SyncAll commits.
+func (r *LRSRouteRepository) attachCatalogDB(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, "INSTALL postgres; LOAD postgres;"); err != nil {
+		return fmt.Errorf("failed to load postgres extension: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, fmt.Sprintf("ATTACH IF NOT EXISTS '%s' AS postgres_db (TYPE POSTGRES)", r.pgConnStr)); err != nil {
+		return fmt.Errorf("failed to attach postgres: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS postgres_db.lrs_catalogs (
+		VERSION INTEGER,
+		START_DATE DATE,
+		END_DATE DATE,
+		LRS_POINT_FILE TEXT,
+		LRS_SEGMENT_FILE TEXT,
+		LRS_LINESTR_FILE TEXT,
+		AUTHOR TEXT,
+		COMMIT_MSG TEXT
+	)`); err != nil {
+		return fmt.Errorf("failed to create catalog table: %w", err)
+	}
+	return nil
+}
+
+// replayPastVersions returns every lrs_catalogs row, oldest first, as
+// EventTypeCatalogVersionCreated events.
+func (r *LRSRouteRepository) replayPastVersions(ctx context.Context) ([]*LRSEvent, error) {
+	return r.catalogVersionEvents(ctx, "SELECT VERSION, AUTHOR, COMMIT_MSG, START_DATE FROM postgres_db.lrs_catalogs ORDER BY VERSION ASC")
+}
+
+// newCatalogVersionsSince returns every lrs_catalogs row newer than
+// afterVersion, oldest first, as EventTypeCatalogVersionCreated events.
+func (r *LRSRouteRepository) newCatalogVersionsSince(ctx context.Context, afterVersion int) ([]*LRSEvent, error) {
+	return r.catalogVersionEvents(ctx, fmt.Sprintf(
+		"SELECT VERSION, AUTHOR, COMMIT_MSG, START_DATE FROM postgres_db.lrs_catalogs WHERE VERSION > %d ORDER BY VERSION ASC",
+		afterVersion,
+	))
+}
+
+// catalogVersionEvents attaches the catalog database and runs query,
+// scanning each row into an EventTypeCatalogVersionCreated event.
+func (r *LRSRouteRepository) catalogVersionEvents(ctx context.Context, query string) ([]*LRSEvent, error) {
+	if err := r.attachCatalogDB(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lrs_catalogs: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*LRSEvent
+	for rows.Next() {
+		var version int
+		var author, commitMsg string
+		var startDate time.Time
+		if err := rows.Scan(&version, &author, &commitMsg, &startDate); err != nil {
+			return nil, fmt.Errorf("failed to scan lrs_catalogs row: %w", err)
+		}
+		events = append(events, &LRSEvent{
+			Type:      EventTypeCatalogVersionCreated,
+			Version:   version,
+			Author:    author,
+			CommitMsg: commitMsg,
+			Timestamp: startDate,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lrs_catalogs rows: %w", err)
+	}
+
+	return events, nil
+}
+
+// latestCatalogVersion returns the highest VERSION in lrs_catalogs, or 0
+// if the table is empty or doesn't exist yet.
+func (r *LRSRouteRepository) latestCatalogVersion(ctx context.Context) (int, error) {
+	if err := r.attachCatalogDB(ctx); err != nil {
+		return 0, err
+	}
+
+	var version sql.NullInt64
+	err := r.db.QueryRowContext(ctx, "SELECT MAX(VERSION) FROM postgres_db.lrs_catalogs").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query latest catalog version: %w", err)
+	}
+	return int(version.Int64), nil
+}
@@ -0,0 +1,105 @@
+package route
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/duckdb/duckdb-go/v2"
+)
+
+func TestStreamEvents(t *testing.T) {
+	connector, err := duckdb.NewConnector("", nil)
+	if err != nil {
+		t.Fatalf("Failed to create DuckDB connector: %v", err)
+	}
+	defer connector.Close()
+
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, _ = db.ExecContext(ctx, "install postgres; load postgres;")
+	_, err = db.ExecContext(ctx, fmt.Sprintf("ATTACH IF NOT EXISTS '%s' AS postgres_db (TYPE POSTGRES)", testPgConnStr))
+	if err == nil {
+		_, _ = db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs")
+	}
+
+	tempDir, err := os.MkdirTemp("", "lrs_events_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.Setenv("LRS_DATA_DIR", tempDir)
+	defer os.Unsetenv("LRS_DATA_DIR")
+
+	repo := NewLRSRouteRepository(connector, testPgConnStr, db)
+
+	stream, handle, err := repo.StreamEvents(ctx, StreamEventsOptions{})
+	if err != nil {
+		t.Fatalf("StreamEvents failed: %v", err)
+	}
+	defer handle.Close()
+
+	jsonBytes1, err := os.ReadFile("./testdata/lrs_01001.json")
+	if err != nil {
+		t.Fatalf("Failed to read test JSON: %v", err)
+	}
+	jsonBytes2, err := os.ReadFile("./testdata/lrs_01002.json")
+	if err != nil {
+		t.Fatalf("Failed to read test JSON: %v", err)
+	}
+
+	if err := repo.SyncFromGeoJSON(ctx, jsonBytes1, SyncOptions{Author: "SYSTEM", CommitMsg: "TEST EVENT 1"}); err != nil {
+		t.Fatalf("SyncFromGeoJSON failed: %v", err)
+	}
+	if err := repo.SyncFromGeoJSON(ctx, jsonBytes2, SyncOptions{Author: "SYSTEM", CommitMsg: "TEST EVENT 2"}); err != nil {
+		t.Fatalf("SyncFromGeoJSON failed: %v", err)
+	}
+
+	var got []*LRSEvent
+	timeout := time.After(5 * time.Second)
+collect:
+	for len(got) < 4 {
+		select {
+		case ev := <-stream:
+			got = append(got, ev)
+		case <-timeout:
+			break collect
+		}
+	}
+
+	if len(got) != 4 {
+		t.Fatalf("expected 4 events (route_synced + catalog_version_created per sync), got %d: %+v", len(got), got)
+	}
+
+	want := []LRSEventType{
+		EventTypeRouteSynced, EventTypeCatalogVersionCreated,
+		EventTypeRouteSynced, EventTypeCatalogVersionCreated,
+	}
+	for i, ev := range got {
+		if ev.Type != want[i] {
+			t.Errorf("event %d: expected type %s, got %s", i, want[i], ev.Type)
+		}
+	}
+
+	if got[0].RouteID != "01001" {
+		t.Errorf("expected first route_synced event for 01001, got %s", got[0].RouteID)
+	}
+	if got[0].Version != 1 || got[1].Version != 1 {
+		t.Errorf("expected version 1 for the first sync's events, got %d and %d", got[0].Version, got[1].Version)
+	}
+	if got[2].RouteID != "01002" {
+		t.Errorf("expected second route_synced event for 01002, got %s", got[2].RouteID)
+	}
+	if got[2].Version != 2 || got[3].Version != 2 {
+		t.Errorf("expected version 2 for the second sync's events, got %d and %d", got[2].Version, got[3].Version)
+	}
+
+	// Cleanup
+	_, _ = db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs WHERE AUTHOR = 'SYSTEM'")
+}
@@ -0,0 +1,502 @@
+package route
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FeatureSource fetches raw feature bytes (ESRI JSON or RFC 7946
+// GeoJSON) for the routeIDs Sync is asked to sync, abstracting where
+// those features actually live so Sync itself stays source-agnostic.
+// An empty routeIDs means "every feature", mirroring
+// FetchArcGISFeatures' existing convention.
+type FeatureSource interface {
+	FetchFeatures(ctx context.Context, routeIDs []string) ([]byte, error)
+}
+
+// arcgisFeatureSource is Sync's default FeatureSource: it generates an
+// ArcGIS token, then fetches features for routeIDs from repo's ArcGIS
+// feature service, reusing the retry/backoff already built into
+// GenerateArcGISToken and FetchArcGISFeatures.
+type arcgisFeatureSource struct {
+	repo *LRSRouteRepository
+}
+
+func (s *arcgisFeatureSource) FetchFeatures(ctx context.Context, routeIDs []string) ([]byte, error) {
+	token, err := s.repo.GenerateArcGISToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate arcgis token: %w", err)
+	}
+	return s.repo.FetchArcGISFeatures(ctx, token, routeIDs, false, nil)
+}
+
+// defaultWFSPageSize is WFSFeatureSource's count per GetFeature request
+// when no WithWFSPageSize option is supplied.
+const defaultWFSPageSize = 1000
+
+// WFSFeatureSourceOption configures optional behavior of a
+// WFSFeatureSource.
+type WFSFeatureSourceOption func(*WFSFeatureSource)
+
+// WithWFSOutputCRS sets the srsName GetFeature requests, e.g.
+// "EPSG:4326". Defaults to "EPSG:4326".
+func WithWFSOutputCRS(epsg string) WFSFeatureSourceOption {
+	return func(s *WFSFeatureSource) { s.outputCRS = epsg }
+}
+
+// WithWFSPageSize sets GetFeature's count per page. Defaults to
+// defaultWFSPageSize.
+func WithWFSPageSize(n int) WFSFeatureSourceOption {
+	return func(s *WFSFeatureSource) { s.pageSize = n }
+}
+
+// WithWFSBBox restricts GetFeature to a "minx,miny,maxx,maxy[,crs]"
+// bounding box, left unset for no spatial restriction.
+func WithWFSBBox(bbox string) WFSFeatureSourceOption {
+	return func(s *WFSFeatureSource) { s.bbox = bbox }
+}
+
+// WithWFSHTTPClient overrides the http.Client used for GetCapabilities
+// and GetFeature requests, e.g. to set a custom Timeout or Transport for
+// a service behind mutual TLS.
+func WithWFSHTTPClient(c *http.Client) WFSFeatureSourceOption {
+	return func(s *WFSFeatureSource) { s.httpClient = c }
+}
+
+// WithWFSFilter sets an additional CQL_FILTER applied to every GetFeature
+// request, ANDed together with any per-call routeID filter FetchFeatures
+// adds.
+func WithWFSFilter(filter string) WFSFeatureSourceOption {
+	return func(s *WFSFeatureSource) { s.filter = filter }
+}
+
+// WithWFSSortBy sets GetFeature's sortBy parameter, making paging
+// deterministic against services that don't otherwise guarantee stable
+// ordering across requests.
+func WithWFSSortBy(sortBy string) WFSFeatureSourceOption {
+	return func(s *WFSFeatureSource) { s.sortBy = sortBy }
+}
+
+// WFSFeatureSource fetches features from an OGC WFS 2.0 service's
+// GetFeature operation, paging with startIndex/count, mirroring how the
+// gemma waterway-profile importer pulls linear features from WFS
+// services. typeName is the feature type requested; use
+// GetCapabilities to discover the names a service offers.
+type WFSFeatureSource struct {
+	baseURL    string
+	typeName   string
+	routeIDKey string
+	outputCRS  string
+	pageSize   int
+	bbox       string
+	filter     string
+	sortBy     string
+	httpClient *http.Client
+}
+
+// NewWFSFeatureSource creates a WFSFeatureSource against baseURL's
+// GetFeature/GetCapabilities endpoints for typeName. routeIDKey names
+// the feature attribute FetchFeatures filters on via CQL_FILTER,
+// defaulting to "LINKID" (the attribute NewLRSRouteFromESRIGeoJSON takes
+// route IDs from) when empty.
+func NewWFSFeatureSource(baseURL, typeName, routeIDKey string, opts ...WFSFeatureSourceOption) *WFSFeatureSource {
+	if routeIDKey == "" {
+		routeIDKey = "LINKID"
+	}
+	s := &WFSFeatureSource{
+		baseURL:    baseURL,
+		typeName:   typeName,
+		routeIDKey: routeIDKey,
+		outputCRS:  "EPSG:4326",
+		pageSize:   defaultWFSPageSize,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// GetCapabilities discovers the feature type names baseURL offers, used
+// to validate typeName before the first GetFeature request.
+func (s *WFSFeatureSource) GetCapabilities(ctx context.Context) ([]string, error) {
+	params := url.Values{}
+	params.Set("service", "WFS")
+	params.Set("version", "2.0.0")
+	params.Set("request", "GetCapabilities")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s?%s", s.baseURL, params.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("wfs GetCapabilities request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("wfs GetCapabilities failed with status: %d", resp.StatusCode)
+	}
+
+	var caps struct {
+		FeatureTypeList struct {
+			FeatureType []struct {
+				Name string `xml:"Name"`
+			} `xml:"FeatureType"`
+		} `xml:"FeatureTypeList"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&caps); err != nil {
+		return nil, fmt.Errorf("failed to parse wfs capabilities: %w", err)
+	}
+
+	names := make([]string, 0, len(caps.FeatureTypeList.FeatureType))
+	for _, ft := range caps.FeatureTypeList.FeatureType {
+		names = append(names, ft.Name)
+	}
+	return names, nil
+}
+
+// FetchFeatures pages through GetFeature via startIndex/count, filtering
+// to routeIDs with a CQL_FILTER when given, and concatenates every
+// page's features into a single RFC 7946 FeatureCollection.
+func (s *WFSFeatureSource) FetchFeatures(ctx context.Context, routeIDs []string) ([]byte, error) {
+	var allFeatures []json.RawMessage
+
+	for startIndex := 0; ; startIndex += s.pageSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		page, total, err := s.fetchPage(ctx, routeIDs, startIndex)
+		if err != nil {
+			return nil, fmt.Errorf("wfs GetFeature at startIndex %d failed: %w", startIndex, err)
+		}
+		allFeatures = append(allFeatures, page...)
+
+		if len(page) < s.pageSize || (total > 0 && startIndex+len(page) >= total) {
+			break
+		}
+	}
+
+	merged, err := json.Marshal(map[string]any{
+		"type":     "FeatureCollection",
+		"features": allFeatures,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal merged wfs features: %w", err)
+	}
+	return merged, nil
+}
+
+// fetchPage makes a single GetFeature request starting at startIndex,
+// returning its features and the service's reported match count (from
+// totalFeatures or numberMatched, whichever is present).
+func (s *WFSFeatureSource) fetchPage(ctx context.Context, routeIDs []string, startIndex int) ([]json.RawMessage, int, error) {
+	params := url.Values{}
+	params.Set("service", "WFS")
+	params.Set("version", "2.0.0")
+	params.Set("request", "GetFeature")
+	params.Set("typeNames", s.typeName)
+	params.Set("outputFormat", "application/json")
+	params.Set("srsName", s.outputCRS)
+	params.Set("startIndex", strconv.Itoa(startIndex))
+	params.Set("count", strconv.Itoa(s.pageSize))
+	if s.bbox != "" {
+		params.Set("bbox", s.bbox)
+	}
+	if s.sortBy != "" {
+		params.Set("sortBy", s.sortBy)
+	}
+
+	var filters []string
+	if s.filter != "" {
+		filters = append(filters, s.filter)
+	}
+	if len(routeIDs) > 0 {
+		quoted := make([]string, len(routeIDs))
+		for i, id := range routeIDs {
+			quoted[i] = fmt.Sprintf("'%s'", id)
+		}
+		filters = append(filters, fmt.Sprintf("%s IN (%s)", s.routeIDKey, strings.Join(quoted, ",")))
+	}
+	if len(filters) > 0 {
+		params.Set("CQL_FILTER", strings.Join(filters, " AND "))
+	}
+
+	fullURL := fmt.Sprintf("%s?%s", s.baseURL, params.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("wfs GetFeature request failed with status: %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		Features      []json.RawMessage `json:"features"`
+		TotalFeatures int               `json:"totalFeatures"`
+		NumberMatched int               `json:"numberMatched"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal wfs response: %w", err)
+	}
+
+	total := parsed.TotalFeatures
+	if total == 0 {
+		total = parsed.NumberMatched
+	}
+	return parsed.Features, total, nil
+}
+
+// DirectoryFeatureSource reads ESRI JSON or RFC 7946 GeoJSON files out
+// of a local directory, used for offline imports and test fixtures
+// without standing up a live ArcGIS or WFS endpoint. Every .json/.geojson
+// file directly under dir is read, in name order, and their features
+// concatenated; routeIDs (when non-empty) filters features by their
+// LINKID (ESRI) or ROUTEID (RFC 7946) attribute.
+type DirectoryFeatureSource struct {
+	dir string
+}
+
+// NewDirectoryFeatureSource creates a DirectoryFeatureSource reading
+// feature files from dir.
+func NewDirectoryFeatureSource(dir string) *DirectoryFeatureSource {
+	return &DirectoryFeatureSource{dir: dir}
+}
+
+func (s *DirectoryFeatureSource) FetchFeatures(ctx context.Context, routeIDs []string) ([]byte, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feature directory %s: %w", s.dir, err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".json", ".geojson":
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	wantRoutes := make(map[string]bool, len(routeIDs))
+	for _, id := range routeIDs {
+		wantRoutes[id] = true
+	}
+
+	var allFeatures []json.RawMessage
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		var parsed struct {
+			Features []json.RawMessage `json:"features"`
+		}
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		if len(wantRoutes) == 0 {
+			allFeatures = append(allFeatures, parsed.Features...)
+			continue
+		}
+		for _, f := range parsed.Features {
+			if directoryFeatureMatchesRoutes(f, wantRoutes) {
+				allFeatures = append(allFeatures, f)
+			}
+		}
+	}
+
+	merged, err := json.Marshal(map[string]any{
+		"type":     "FeatureCollection",
+		"features": allFeatures,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal merged directory features: %w", err)
+	}
+	return merged, nil
+}
+
+// directoryFeatureMatchesRoutes reports whether raw's LINKID (ESRI
+// attributes) or ROUTEID (RFC 7946 properties) is in wantRoutes.
+func directoryFeatureMatchesRoutes(raw json.RawMessage, wantRoutes map[string]bool) bool {
+	var probe struct {
+		Properties map[string]any `json:"properties"`
+		Attributes map[string]any `json:"attributes"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return false
+	}
+	for _, key := range []string{"LINKID", "ROUTEID"} {
+		if v, ok := probe.Properties[key].(string); ok && wantRoutes[v] {
+			return true
+		}
+		if v, ok := probe.Attributes[key].(string); ok && wantRoutes[v] {
+			return true
+		}
+	}
+	return false
+}
+
+// PostGISFeatureSourceOption configures optional behavior of a
+// PostGISFeatureSource.
+type PostGISFeatureSourceOption func(*PostGISFeatureSource)
+
+// WithPostGISRouteIDColumn sets the column FetchFeatures filters
+// routeIDs against. Defaults to "linkid".
+func WithPostGISRouteIDColumn(col string) PostGISFeatureSourceOption {
+	return func(s *PostGISFeatureSource) { s.routeIDCol = col }
+}
+
+// WithPostGISGeomColumn sets the geometry column FetchFeatures reads via
+// ST_AsGeoJSON. Defaults to "geom".
+func WithPostGISGeomColumn(col string) PostGISFeatureSourceOption {
+	return func(s *PostGISFeatureSource) { s.geomCol = col }
+}
+
+// PostGISFeatureSource fetches features directly from a PostGIS table
+// through DuckDB's postgres_query table function -- the same mechanism
+// LRSRouteRepository's own health/catalog checks use to reach Postgres
+// -- serializing each row as an RFC 7946 Feature with ST_AsGeoJSON.
+type PostGISFeatureSource struct {
+	db         *sql.DB
+	pgConnStr  string
+	attachName string
+	table      string
+	routeIDCol string
+	geomCol    string
+}
+
+// NewPostGISFeatureSource creates a PostGISFeatureSource that queries
+// table over a DuckDB connection attached to pgConnStr. db is typically
+// the same *sql.DB an LRSRouteRepository already uses, so no second
+// DuckDB connection is opened.
+func NewPostGISFeatureSource(db *sql.DB, pgConnStr, table string, opts ...PostGISFeatureSourceOption) *PostGISFeatureSource {
+	s := &PostGISFeatureSource{
+		db:         db,
+		pgConnStr:  pgConnStr,
+		attachName: "postgis_feature_source",
+		table:      table,
+		routeIDCol: "linkid",
+		geomCol:    "geom",
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *PostGISFeatureSource) FetchFeatures(ctx context.Context, routeIDs []string) ([]byte, error) {
+	if _, err := s.db.ExecContext(ctx, "INSTALL postgres; LOAD postgres;"); err != nil {
+		return nil, fmt.Errorf("failed to load postgres extension: %w", err)
+	}
+	attachStmt := fmt.Sprintf("ATTACH IF NOT EXISTS '%s' AS %s (TYPE POSTGRES)", s.pgConnStr, s.attachName)
+	if _, err := s.db.ExecContext(ctx, attachStmt); err != nil {
+		return nil, fmt.Errorf("failed to attach postgis source: %w", err)
+	}
+
+	where := "1=1"
+	if len(routeIDs) > 0 {
+		quoted := make([]string, len(routeIDs))
+		for i, id := range routeIDs {
+			quoted[i] = fmt.Sprintf("'%s'", id)
+		}
+		where = fmt.Sprintf("%s IN (%s)", s.routeIDCol, strings.Join(quoted, ","))
+	}
+
+	query := fmt.Sprintf(
+		`SELECT * FROM postgres_query('%s', $wfs$SELECT row_to_json(t) FROM (SELECT *, ST_AsGeoJSON(%s) AS geojson_geometry FROM %s WHERE %s) t$wfs$)`,
+		s.attachName, s.geomCol, s.table, where,
+	)
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query postgis source: %w", err)
+	}
+	defer rows.Close()
+
+	var features []json.RawMessage
+	for rows.Next() {
+		var rowJSON string
+		if err := rows.Scan(&rowJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan postgis row: %w", err)
+		}
+		feature, err := s.rowToFeature(rowJSON)
+		if err != nil {
+			return nil, err
+		}
+		features = append(features, feature)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating postgis rows: %w", err)
+	}
+
+	merged, err := json.Marshal(map[string]any{
+		"type":     "FeatureCollection",
+		"features": features,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal merged postgis features: %w", err)
+	}
+	return merged, nil
+}
+
+// rowToFeature converts one row_to_json row (every table column plus a
+// geojson_geometry column from ST_AsGeoJSON) into an RFC 7946 Feature,
+// with every remaining column becoming a feature property.
+func (s *PostGISFeatureSource) rowToFeature(rowJSON string) (json.RawMessage, error) {
+	var row map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(rowJSON), &row); err != nil {
+		return nil, fmt.Errorf("failed to parse postgis row json: %w", err)
+	}
+
+	rawGeometry, ok := row["geojson_geometry"]
+	if !ok {
+		return nil, fmt.Errorf("postgis row missing geojson_geometry")
+	}
+	// ST_AsGeoJSON returns text, so row_to_json encodes it as a JSON
+	// string -- unmarshal it once more to get the geometry object itself.
+	var geometryStr string
+	if err := json.Unmarshal(rawGeometry, &geometryStr); err != nil {
+		return nil, fmt.Errorf("failed to parse geojson_geometry: %w", err)
+	}
+
+	delete(row, "geojson_geometry")
+	delete(row, s.geomCol)
+
+	feature := map[string]any{
+		"type":       "Feature",
+		"geometry":   json.RawMessage(geometryStr),
+		"properties": row,
+	}
+	return json.Marshal(feature)
+}
@@ -0,0 +1,187 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirectoryFeatureSourceConcatenatesAndFilters(t *testing.T) {
+	dir := t.TempDir()
+
+	file1 := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{"ROUTEID":"01001"},"geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]}},
+		{"type":"Feature","properties":{"ROUTEID":"01002"},"geometry":{"type":"LineString","coordinates":[[1,1],[2,2]]}}
+	]}`
+	file2 := `{"features":[
+		{"attributes":{"LINKID":"15001"},"geometry":{"paths":[[[0,0,0],[1,1,1]]]}}
+	]}`
+
+	if err := os.WriteFile(filepath.Join(dir, "a.geojson"), []byte(file1), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.json"), []byte(file2), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ignored.txt"), []byte("not json"), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	src := NewDirectoryFeatureSource(dir)
+
+	all, err := src.FetchFeatures(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("FetchFeatures failed: %v", err)
+	}
+	var allParsed struct {
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(all, &allParsed); err != nil {
+		t.Fatalf("failed to parse merged output: %v", err)
+	}
+	if len(allParsed.Features) != 3 {
+		t.Errorf("expected 3 features across both files, got %d", len(allParsed.Features))
+	}
+
+	filtered, err := src.FetchFeatures(context.Background(), []string{"01002"})
+	if err != nil {
+		t.Fatalf("FetchFeatures with filter failed: %v", err)
+	}
+	var filteredParsed struct {
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(filtered, &filteredParsed); err != nil {
+		t.Fatalf("failed to parse filtered output: %v", err)
+	}
+	if len(filteredParsed.Features) != 1 {
+		t.Errorf("expected 1 feature matching ROUTEID 01002, got %d", len(filteredParsed.Features))
+	}
+}
+
+func TestWFSFeatureSourcePagesUntilShortPage(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Query().Get("startIndex"))
+
+		start := r.URL.Query().Get("startIndex")
+		w.Header().Set("Content-Type", "application/json")
+		if start == "0" {
+			fmt.Fprint(w, `{"features":[{"type":"Feature"},{"type":"Feature"}],"numberMatched":3}`)
+			return
+		}
+		fmt.Fprint(w, `{"features":[{"type":"Feature"}],"numberMatched":3}`)
+	}))
+	defer ts.Close()
+
+	src := NewWFSFeatureSource(ts.URL, "lrs:routes", "", WithWFSPageSize(2))
+
+	data, err := src.FetchFeatures(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("FetchFeatures failed: %v", err)
+	}
+
+	var parsed struct {
+		Type     string            `json:"type"`
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("failed to parse merged output: %v", err)
+	}
+	if parsed.Type != "FeatureCollection" {
+		t.Errorf("expected a FeatureCollection, got %s", parsed.Type)
+	}
+	if len(parsed.Features) != 3 {
+		t.Errorf("expected 3 features across 2 pages, got %d", len(parsed.Features))
+	}
+	if len(requests) != 2 {
+		t.Errorf("expected 2 GetFeature requests, got %d", len(requests))
+	}
+}
+
+func TestNewLRSRouteBatchFromWFSGroupsByRouteID(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("request") {
+		case "GetCapabilities":
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprint(w, `<WFS_Capabilities><FeatureTypeList><FeatureType><Name>lrs:routes</Name></FeatureType></FeatureTypeList></WFS_Capabilities>`)
+		default:
+			fmt.Fprint(w, `{"type":"FeatureCollection","features":[
+				{"type":"Feature","properties":{"ROUTEID":"01001"},"geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]}},
+				{"type":"Feature","properties":{"ROUTEID":"01002"},"geometry":{"type":"LineString","coordinates":[[2,2],[3,3]]}},
+				{"type":"Feature","properties":{"ROUTEID":"01001"},"geometry":{"type":"LineString","coordinates":[[1,1],[4,4]]}}
+			],"numberMatched":3}`)
+		}
+	}))
+	defer ts.Close()
+
+	batch, err := NewLRSRouteBatchFromWFS(context.Background(), ts.URL, "lrs:routes", "", "", "ROUTEID", "EPSG:4326")
+	if err != nil {
+		t.Fatalf("NewLRSRouteBatchFromWFS failed: %v", err)
+	}
+	defer batch.Release()
+
+	ids := batch.RouteIDs()
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 distinct routes, got %d: %v", len(ids), ids)
+	}
+
+	_, err = NewLRSRouteFromWFS(context.Background(), ts.URL, "lrs:routes", "", "", "ROUTEID", "EPSG:4326")
+	if err == nil {
+		t.Error("expected NewLRSRouteFromWFS to reject a multi-route feed")
+	}
+}
+
+func TestNewLRSRouteFromWFSUnknownFeatureType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<WFS_Capabilities><FeatureTypeList><FeatureType><Name>lrs:other</Name></FeatureType></FeatureTypeList></WFS_Capabilities>`)
+	}))
+	defer ts.Close()
+
+	_, err := NewLRSRouteFromWFS(context.Background(), ts.URL, "lrs:routes", "", "", "ROUTEID", "EPSG:4326")
+	if err == nil {
+		t.Error("expected an error for a feature type absent from GetCapabilities")
+	}
+}
+
+func TestPostGISFeatureSourceRowToFeature(t *testing.T) {
+	s := NewPostGISFeatureSource(nil, "", "routes", WithPostGISGeomColumn("the_geom"))
+
+	rowJSON := `{"linkid":"01001","link_name":"Jl. Test","the_geom":"...raw wkb...","geojson_geometry":"{\"type\":\"LineString\",\"coordinates\":[[0,0],[1,1]]}"}`
+
+	feature, err := s.rowToFeature(rowJSON)
+	if err != nil {
+		t.Fatalf("rowToFeature failed: %v", err)
+	}
+
+	var parsed struct {
+		Type       string         `json:"type"`
+		Geometry   map[string]any `json:"geometry"`
+		Properties map[string]any `json:"properties"`
+	}
+	if err := json.Unmarshal(feature, &parsed); err != nil {
+		t.Fatalf("failed to parse feature: %v", err)
+	}
+
+	if parsed.Type != "Feature" {
+		t.Errorf("expected type Feature, got %s", parsed.Type)
+	}
+	if parsed.Geometry["type"] != "LineString" {
+		t.Errorf("expected geometry type LineString, got %v", parsed.Geometry["type"])
+	}
+	if _, ok := parsed.Properties["the_geom"]; ok {
+		t.Error("expected the geometry column to be excluded from properties")
+	}
+	if _, ok := parsed.Properties["geojson_geometry"]; ok {
+		t.Error("expected geojson_geometry to be excluded from properties")
+	}
+	if parsed.Properties["linkid"] != "01001" {
+		t.Errorf("expected linkid property to survive, got %v", parsed.Properties["linkid"])
+	}
+}
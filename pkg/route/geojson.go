@@ -0,0 +1,363 @@
+package route
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// Dialect identifies which GeoJSON flavor a document follows.
+type Dialect string
+
+const (
+	// DialectESRI is the ArcGIS "esriJSON" style used by NewLRSRouteFromESRIGeoJSON:
+	// a top-level spatialReference.wkt and features[].geometry.paths with M
+	// embedded as the third coordinate.
+	DialectESRI Dialect = "esri"
+	// DialectRFC7946 is standard GeoJSON per RFC 7946.
+	DialectRFC7946 Dialect = "rfc7946"
+)
+
+// DetectGeoJSONDialect inspects the top-level shape of a JSON document to
+// decide whether it follows the ESRI JSON dialect or RFC 7946 GeoJSON, so
+// callers can route to NewLRSRouteFromESRIGeoJSON or
+// NewLRSRouteFromGeoJSON without special-casing the request body themselves.
+func DetectGeoJSONDialect(data []byte) (Dialect, error) {
+	var probe struct {
+		Type             string         `json:"type"`
+		SpatialReference map[string]any `json:"spatialReference"`
+		Features         []struct {
+			Geometry struct {
+				Paths [][][]float64 `json:"paths"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}
+
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return "", fmt.Errorf("failed to unmarshal geojson: %w", err)
+	}
+
+	if probe.SpatialReference != nil {
+		return DialectESRI, nil
+	}
+	if len(probe.Features) > 0 && len(probe.Features[0].Geometry.Paths) > 0 {
+		return DialectESRI, nil
+	}
+	if probe.Type == "FeatureCollection" || probe.Type == "Feature" {
+		return DialectRFC7946, nil
+	}
+
+	return "", fmt.Errorf("unrecognized GeoJSON dialect")
+}
+
+// rfc7946FeatureCollection models the subset of RFC 7946 needed to build an
+// LRSRoute: LineString/MultiLineString/Polygon geometry plus an M source,
+// either coordinates carrying M as a 4th member ([lon, lat, z, m]), a
+// "m_values" property array parallel to the coordinates, or a "measures"
+// member on the geometry per the CRS-WKT M-values extension.
+type rfc7946FeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []rfc7946Feature `json:"features"`
+	CRS      *rfc7946NamedCRS `json:"crs"`
+}
+
+type rfc7946NamedCRS struct {
+	Type       string `json:"type"`
+	Properties struct {
+		Name string `json:"name"`
+	} `json:"properties"`
+}
+
+type rfc7946Feature struct {
+	Type       string          `json:"type"`
+	Geometry   rfc7946Geometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+type rfc7946Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Measures    [][]float64     `json:"measures"`
+}
+
+// mValuePropertyKey is the default feature property carrying M values
+// parallel to the coordinate array, when the geometry itself has none.
+const mValuePropertyKey = "m_values"
+
+// routeIDPropertyKey is the default feature property holding the route id,
+// matching the ESRI "LINKID" attribute used by NewLRSRouteFromESRIGeoJSON.
+const routeIDPropertyKey = "LINKID"
+
+// defaultGeoJSONCRS is the CRS assumed by NewLRSRouteFromGeoJSON when
+// neither a crsWKT argument nor a GeoJSON "crs" member is present, per the
+// RFC 7946 default of WGS 84 longitude/latitude.
+const defaultGeoJSONCRS = "EPSG:4326"
+
+// NewLRSRouteFromGeoJSON creates an LRSRoute from a standard RFC 7946
+// GeoJSON FeatureCollection, accepting LineString, MultiLineString, or
+// Polygon features (a Polygon's rings are treated as lines, for routes
+// exported as closed loops). routeIDKey names the feature property holding
+// the route id, defaulting to "LINKID" when empty. M values are read, in
+// order of preference, from a 4-member coordinate ([lon, lat, z, m]), the
+// geometry's "measures" member (CRS-WKT M-values extension), or the
+// feature's "m_values" property (an array parallel to the coordinates). The
+// CRS is resolved from crsWKT if non-empty, else from the document's
+// deprecated "crs" member, else defaults to EPSG:4326. The result is
+// normalized into the same Arrow schema used by NewLRSRouteFromESRIGeoJSON
+// (LAT, LON, MVAL, VERTEX_SEQ, ROUTEID).
+func NewLRSRouteFromGeoJSON(data []byte, featureIndex int, routeIDKey string, crsWKT string) (LRSRoute, error) {
+	var fc rfc7946FeatureCollection
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return LRSRoute{}, fmt.Errorf("failed to unmarshal geojson: %w", err)
+	}
+
+	if featureIndex < 0 || featureIndex >= len(fc.Features) {
+		return LRSRoute{}, fmt.Errorf("feature_idx %d out of range", featureIndex)
+	}
+
+	feature := fc.Features[featureIndex]
+
+	if routeIDKey == "" {
+		routeIDKey = routeIDPropertyKey
+	}
+	routeID, ok := feature.Properties[routeIDKey].(string)
+	if !ok {
+		return LRSRoute{}, fmt.Errorf("missing or invalid %q property", routeIDKey)
+	}
+
+	crs := crsWKT
+	if crs == "" {
+		if fc.CRS != nil && fc.CRS.Properties.Name != "" {
+			crs = fc.CRS.Properties.Name
+		} else {
+			crs = defaultGeoJSONCRS
+		}
+	}
+
+	lines, err := lineStringsFromGeometry(feature.Geometry)
+	if err != nil {
+		return LRSRoute{}, err
+	}
+
+	mValues, err := measuresForFeature(feature, lines)
+	if err != nil {
+		return LRSRoute{}, err
+	}
+
+	pool := memory.NewGoAllocator()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "VERTEX_SEQ", Type: arrow.PrimitiveTypes.Int32},
+			{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+		},
+		nil,
+	)
+
+	lat_builder := array.NewFloat64Builder(pool)
+	long_builder := array.NewFloat64Builder(pool)
+	mval_builder := array.NewFloat64Builder(pool)
+	vertex_seq_builder := array.NewInt32Builder(pool)
+	routeid_builder := array.NewStringBuilder(pool)
+
+	defer lat_builder.Release()
+	defer long_builder.Release()
+	defer mval_builder.Release()
+	defer vertex_seq_builder.Release()
+	defer routeid_builder.Release()
+
+	vertexSeq := 0
+	for lineIdx, line := range lines {
+		for ptIdx, coord := range line {
+			if len(coord) < 2 {
+				return LRSRoute{}, fmt.Errorf("coordinate %d of line %d has fewer than 2 members", ptIdx, lineIdx)
+			}
+			long_builder.Append(coord[0])
+			lat_builder.Append(coord[1])
+			mval_builder.Append(mValues[lineIdx][ptIdx])
+			vertex_seq_builder.Append(int32(vertexSeq))
+			routeid_builder.Append(routeID)
+			vertexSeq++
+		}
+	}
+
+	lat_arr := lat_builder.NewArray()
+	long_arr := long_builder.NewArray()
+	mval_arr := mval_builder.NewArray()
+	vertex_seq_arr := vertex_seq_builder.NewArray()
+	routeid_arr := routeid_builder.NewArray()
+
+	defer lat_arr.Release()
+	defer long_arr.Release()
+	defer mval_arr.Release()
+	defer vertex_seq_arr.Release()
+	defer routeid_arr.Release()
+
+	rec := array.NewRecordBatch(
+		schema,
+		[]arrow.Array{lat_arr, long_arr, mval_arr, vertex_seq_arr, routeid_arr},
+		int64(vertex_seq_arr.Len()),
+	)
+
+	return NewLRSRoute(routeID, []arrow.RecordBatch{rec}, crs), nil
+}
+
+// geoJSONFeatureCollection and friends are the write-side mirror of
+// rfc7946FeatureCollection, used by ToGeoJSON to marshal plain coordinate
+// slices instead of json.RawMessage.
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+	CRS      *rfc7946NamedCRS `json:"crs,omitempty"`
+}
+
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string        `json:"type"`
+	Coordinates [][][]float64 `json:"coordinates"`
+}
+
+// ToGeoJSON converts the route to a RFC 7946 GeoJSON FeatureCollection
+// containing a single MultiLineString feature (one line, since LRSRoute
+// does not preserve original multi-part path boundaries past VERTEX_SEQ
+// ordering), with M preserved as a 4th ordinate ([lon, lat, 0, m]) and the
+// route id carried under the "LINKID" property, so the output round-trips
+// through NewLRSRouteFromGeoJSON's default routeIDKey.
+func (l *LRSRoute) ToGeoJSON() ([]byte, error) {
+	vertices, err := collectVertices(l.records, l.latitudeCol, l.longitudeCol, l.mValueCol, l.VertexSeqColumn)
+	if err != nil {
+		return nil, err
+	}
+	sortVertices(vertices)
+
+	line := make([][]float64, len(vertices))
+	for i, v := range vertices {
+		line[i] = []float64{v.lon, v.lat, 0, v.mval}
+	}
+
+	fc := geoJSONFeatureCollection{
+		Type: "FeatureCollection",
+		Features: []geoJSONFeature{
+			{
+				Type: "Feature",
+				Geometry: geoJSONGeometry{
+					Type:        "MultiLineString",
+					Coordinates: [][][]float64{line},
+				},
+				Properties: map[string]any{routeIDPropertyKey: l.route_id},
+			},
+		},
+	}
+	if l.crs != "" {
+		fc.CRS = &rfc7946NamedCRS{Type: "name"}
+		fc.CRS.Properties.Name = l.crs
+	}
+
+	return json.MarshalIndent(fc, "", "  ")
+}
+
+// lineStringsFromGeometry normalizes LineString, MultiLineString, and
+// Polygon geometries into a slice of coordinate lines, one per ring for a
+// Polygon.
+func lineStringsFromGeometry(geometry rfc7946Geometry) ([][][]float64, error) {
+	switch geometry.Type {
+	case "LineString":
+		var coords [][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal LineString coordinates: %w", err)
+		}
+		return [][][]float64{coords}, nil
+	case "MultiLineString", "Polygon":
+		var coords [][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s coordinates: %w", geometry.Type, err)
+		}
+		return coords, nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q, expected LineString, MultiLineString, or Polygon", geometry.Type)
+	}
+}
+
+// embeddedMeasures extracts M as the 4th member of every coordinate
+// ([lon, lat, z, m]), returning ok=false if any coordinate across lines has
+// fewer than 4 members, so the caller can fall back to measures/m_values.
+func embeddedMeasures(lines [][][]float64) (out [][]float64, ok bool) {
+	out = make([][]float64, len(lines))
+	for i, line := range lines {
+		vals := make([]float64, len(line))
+		for j, coord := range line {
+			if len(coord) < 4 {
+				return nil, false
+			}
+			vals[j] = coord[3]
+		}
+		out[i] = vals
+	}
+	return out, true
+}
+
+// measuresForFeature resolves the M value for every vertex in lines: from a
+// 4-member coordinate ([lon, lat, z, m]) if every vertex carries one, else
+// the geometry's "measures" member if present, else the feature's
+// "m_values" property (an array parallel to the coordinates).
+func measuresForFeature(feature rfc7946Feature, lines [][][]float64) ([][]float64, error) {
+	if embedded, ok := embeddedMeasures(lines); ok {
+		return embedded, nil
+	}
+
+	if len(feature.Geometry.Measures) > 0 {
+		if len(feature.Geometry.Measures) != len(lines) {
+			return nil, fmt.Errorf("measures has %d lines, expected %d", len(feature.Geometry.Measures), len(lines))
+		}
+		out := make([][]float64, len(lines))
+		for i, line := range feature.Geometry.Measures {
+			if len(line) != len(lines[i]) {
+				return nil, fmt.Errorf("measures line %d has %d values, expected %d", i, len(line), len(lines[i]))
+			}
+			out[i] = line
+		}
+		return out, nil
+	}
+
+	raw, ok := feature.Properties[mValuePropertyKey]
+	if !ok {
+		return nil, fmt.Errorf("missing M values: no geometry.measures or %q property", mValuePropertyKey)
+	}
+
+	flat, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%q property must be an array", mValuePropertyKey)
+	}
+
+	mvals := make([]float64, len(flat))
+	for i, v := range flat {
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%q[%d] is not a number", mValuePropertyKey, i)
+		}
+		mvals[i] = f
+	}
+
+	out := make([][]float64, len(lines))
+	offset := 0
+	for i, line := range lines {
+		if offset+len(line) > len(mvals) {
+			return nil, fmt.Errorf("%q has fewer values than coordinates", mValuePropertyKey)
+		}
+		out[i] = mvals[offset : offset+len(line)]
+		offset += len(line)
+	}
+
+	return out, nil
+}
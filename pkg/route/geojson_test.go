@@ -0,0 +1,107 @@
+package route
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func newTestLRSRoute(t *testing.T, crs string) LRSRoute {
+	t.Helper()
+
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "VERTEX_SEQ", Type: arrow.PrimitiveTypes.Int32},
+			{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+		},
+		nil,
+	)
+
+	builder := array.NewRecordBuilder(pool, schema)
+	defer builder.Release()
+
+	lats := []float64{5.648, 5.649}
+	lons := []float64{95.421, 95.422}
+	mvals := []float64{0, 10}
+	for i := range lats {
+		builder.Field(0).(*array.Float64Builder).Append(lats[i])
+		builder.Field(1).(*array.Float64Builder).Append(lons[i])
+		builder.Field(2).(*array.Float64Builder).Append(mvals[i])
+		builder.Field(3).(*array.Int32Builder).Append(int32(i))
+		builder.Field(4).(*array.StringBuilder).Append("L01")
+	}
+
+	rec := builder.NewRecordBatch()
+	return NewLRSRoute("L01", []arrow.RecordBatch{rec}, crs)
+}
+
+func TestLRSRouteToGeoJSON(t *testing.T) {
+	lrs := newTestLRSRoute(t, "EPSG:4326")
+	defer lrs.Release()
+
+	data, err := lrs.ToGeoJSON()
+	if err != nil {
+		t.Fatalf("ToGeoJSON failed: %v", err)
+	}
+
+	var fc geoJSONFeatureCollection
+	if err := json.Unmarshal(data, &fc); err != nil {
+		t.Fatalf("failed to unmarshal result: %v", err)
+	}
+
+	if len(fc.Features) != 1 {
+		t.Fatalf("expected 1 feature, got %d", len(fc.Features))
+	}
+	f := fc.Features[0]
+	if f.Geometry.Type != "MultiLineString" {
+		t.Errorf("expected MultiLineString, got %s", f.Geometry.Type)
+	}
+	if len(f.Geometry.Coordinates) != 1 || len(f.Geometry.Coordinates[0]) != 2 {
+		t.Fatalf("expected 1 line with 2 vertices, got %v", f.Geometry.Coordinates)
+	}
+	if got := f.Geometry.Coordinates[0][1]; got[0] != 95.422 || got[1] != 5.649 || got[3] != 10 {
+		t.Errorf("unexpected second vertex: %v", got)
+	}
+	if f.Properties[routeIDPropertyKey] != "L01" {
+		t.Errorf("expected %s L01, got %v", routeIDPropertyKey, f.Properties[routeIDPropertyKey])
+	}
+}
+
+func TestLRSRouteToEsriRouteJSON(t *testing.T) {
+	lrs := newTestLRSRoute(t, "EPSG:4326")
+	defer lrs.Release()
+
+	data, err := lrs.ToEsriRouteJSON()
+	if err != nil {
+		t.Fatalf("ToEsriRouteJSON failed: %v", err)
+	}
+
+	var esri EsriRouteJson
+	if err := json.Unmarshal(data, &esri); err != nil {
+		t.Fatalf("failed to unmarshal result: %v", err)
+	}
+
+	if esri.SpatialReference.WKT != "EPSG:4326" {
+		t.Errorf("expected spatialReference.wkt EPSG:4326, got %s", esri.SpatialReference.WKT)
+	}
+	if esri.FeatureCount() != 1 {
+		t.Fatalf("expected 1 feature, got %d", esri.FeatureCount())
+	}
+	f := esri.Features[0]
+	if !f.Geometry.HasM {
+		t.Error("expected hasM=true")
+	}
+	if len(f.Geometry.Paths) != 1 || len(f.Geometry.Paths[0]) != 2 {
+		t.Fatalf("expected 1 path with 2 vertices, got %v", f.Geometry.Paths)
+	}
+	if f.Attributes["LINKID"] != "L01" {
+		t.Errorf("expected LINKID L01, got %v", f.Attributes["LINKID"])
+	}
+}
@@ -0,0 +1,239 @@
+package route
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// GeoParquetVersion is the version of the GeoParquet spec this package writes.
+const GeoParquetVersion = "1.1.0"
+
+// geoParquetKey is the Parquet file-level key/value metadata key defined by
+// the GeoParquet spec.
+const geoParquetKey = "geo"
+
+// geoParquetColumn describes a single geometry column entry in the "geo"
+// metadata, following the GeoParquet column object spec.
+type geoParquetColumn struct {
+	Encoding      string    `json:"encoding"`
+	GeometryTypes []string  `json:"geometry_types"`
+	CRS           any       `json:"crs"`
+	Edges         string    `json:"edges,omitempty"`
+	Bbox          []float64 `json:"bbox,omitempty"`
+}
+
+// geoParquetMeta is the top level "geo" metadata object.
+type geoParquetMeta struct {
+	Version       string                       `json:"version"`
+	PrimaryColumn string                       `json:"primary_column"`
+	Columns       map[string]*geoParquetColumn `json:"columns"`
+}
+
+// geoBounds accumulates a bounding box over every point passed to extend.
+type geoBounds struct {
+	minX, minY, maxX, maxY float64
+	set                    bool
+}
+
+func (b *geoBounds) extend(x, y float64) {
+	if !b.set {
+		b.minX, b.maxX = x, x
+		b.minY, b.maxY = y, y
+		b.set = true
+		return
+	}
+	b.minX = math.Min(b.minX, x)
+	b.maxX = math.Max(b.maxX, x)
+	b.minY = math.Min(b.minY, y)
+	b.maxY = math.Max(b.maxY, y)
+}
+
+func (b *geoBounds) bbox() []float64 {
+	if !b.set {
+		return nil
+	}
+	return []float64{b.minX, b.minY, b.maxX, b.maxY}
+}
+
+// geoParquetEncoding reports the GeoParquet "geo" metadata encoding and
+// geometry type for a given primary column, following the conventions used
+// by LRSRoute's sinks: the per-vertex point sink writes WKB Point M
+// (geometryColumn, see withPointGeometryColumn), the segment and linestring
+// sinks write WKB linestrings.
+func geoParquetEncoding(primaryColumn string) (encoding string, geometryType string) {
+	switch primaryColumn {
+	case "linestr", "LINESTR":
+		return "WKB", "LineString Z"
+	case "shape", "SHAPE":
+		return "WKB", "LineString Z"
+	default:
+		return "WKB", "Point M"
+	}
+}
+
+// projjsonFromWKT wraps a WKT CRS definition in a PROJJSON-shaped object.
+// No projection library is wired up in this package, so the WKT is not
+// converted: the object is typed ProjectedCRS, named by the verbatim WKT,
+// and carries an "assumed" note so downstream GeoParquet readers don't
+// mistake it for WGS84. An empty wkt gives a null CRS.
+func projjsonFromWKT(wkt string) any {
+	if wkt == "" {
+		return nil
+	}
+
+	return map[string]any{
+		"$schema": "https://proj.org/schemas/v0.7/projjson.schema.json",
+		"type":    "ProjectedCRS",
+		"name":    wkt,
+		"note":    "assumed: PROJJSON derived verbatim from the source WKT, not independently validated",
+	}
+}
+
+// buildGeoParquetMeta assembles the "geo" key/value metadata for a parquet
+// file whose primary geometry column is primaryColumn, built from the
+// accumulated bbox and the CRS WKT of the route.
+func buildGeoParquetMeta(primaryColumn string, crsWKT string, bbox []float64) *geoParquetMeta {
+	encoding, geometryType := geoParquetEncoding(primaryColumn)
+
+	return &geoParquetMeta{
+		Version:       GeoParquetVersion,
+		PrimaryColumn: primaryColumn,
+		Columns: map[string]*geoParquetColumn{
+			primaryColumn: {
+				Encoding:      encoding,
+				GeometryTypes: []string{geometryType},
+				CRS:           projjsonFromWKT(crsWKT),
+				Edges:         "planar",
+				Bbox:          bbox,
+			},
+		},
+	}
+}
+
+// geoParquetKV renders the "geo" metadata as Parquet file-level key/value
+// metadata, ready to be attached to arrow.NewSchema's metadata argument.
+func geoParquetKV(meta *geoParquetMeta) (arrow.Metadata, error) {
+	blob, err := json.Marshal(meta)
+	if err != nil {
+		return arrow.Metadata{}, fmt.Errorf("failed to marshal geo metadata: %v", err)
+	}
+
+	return arrow.NewMetadata([]string{geoParquetKey}, []string{string(blob)}), nil
+}
+
+// wkbPointM is the ISO WKB type code for a 2D Point carrying an M ordinate
+// (base type 1, +2000 for the M dimension) -- mirrors wkbLineStringM.
+const wkbPointM = 2001
+
+// encodeWKBPointM encodes (lon, lat, m) as a little-endian ISO WKB Point M.
+func encodeWKBPointM(lon, lat, m float64) []byte {
+	buf := make([]byte, 29)
+	buf[0] = 1 // little-endian byte order
+	binary.LittleEndian.PutUint32(buf[1:5], wkbPointM)
+	binary.LittleEndian.PutUint64(buf[5:13], math.Float64bits(lon))
+	binary.LittleEndian.PutUint64(buf[13:21], math.Float64bits(lat))
+	binary.LittleEndian.PutUint64(buf[21:29], math.Float64bits(m))
+	return buf
+}
+
+// withPointGeometryColumn returns a copy of rec under newSchema with a WKB
+// Point M geometry column (named geometryColumn) appended, derived from its
+// latCol/lonCol/mValCol columns. This is the per-vertex counterpart to
+// routeLineStringGeometry's whole-route WKB LineString M: it's what lets
+// Sink's WithGeoParquetMetadata "geo" metadata name a real geometry column
+// as primary_column instead of tagging the latitude scalar column as one.
+func withPointGeometryColumn(rec arrow.RecordBatch, newSchema *arrow.Schema, latCol, lonCol, mValCol string) (arrow.RecordBatch, error) {
+	schema := rec.Schema()
+	latIdx := schema.FieldIndices(latCol)
+	lonIdx := schema.FieldIndices(lonCol)
+	mValIdx := schema.FieldIndices(mValCol)
+	if len(latIdx) == 0 || len(lonIdx) == 0 || len(mValIdx) == 0 {
+		return nil, fmt.Errorf("missing %s/%s/%s column to derive geometry", latCol, lonCol, mValCol)
+	}
+	lat, ok := rec.Column(latIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not float64", latCol)
+	}
+	lon, ok := rec.Column(lonIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not float64", lonCol)
+	}
+	mval, ok := rec.Column(mValIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not float64", mValCol)
+	}
+
+	pool := memory.NewGoAllocator()
+	builder := array.NewBinaryBuilder(pool, arrow.BinaryTypes.Binary)
+	defer builder.Release()
+
+	for i := 0; i < int(rec.NumRows()); i++ {
+		if lat.IsNull(i) || lon.IsNull(i) || mval.IsNull(i) {
+			builder.AppendNull()
+			continue
+		}
+		builder.Append(encodeWKBPointM(lon.Value(i), lat.Value(i), mval.Value(i)))
+	}
+	geometry := builder.NewArray()
+	defer geometry.Release()
+
+	cols := make([]arrow.Array, int(rec.NumCols()))
+	for i := range cols {
+		cols[i] = rec.Column(i)
+	}
+	cols = append(cols, geometry)
+	return array.NewRecordBatch(newSchema, cols, rec.NumRows()), nil
+}
+
+// routeBounds computes the LAT/LON bounding box of a record batch using the
+// route's configured latitude/longitude columns.
+func routeBounds(rec arrow.RecordBatch, latCol, lonCol string) (*geoBounds, error) {
+	schema := rec.Schema()
+	latIdx := schema.FieldIndices(latCol)
+	lonIdx := schema.FieldIndices(lonCol)
+	if len(latIdx) == 0 || len(lonIdx) == 0 {
+		return nil, fmt.Errorf("missing %s/%s column for bbox computation", latCol, lonCol)
+	}
+
+	lat, ok := rec.Column(latIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not float64", latCol)
+	}
+	lon, ok := rec.Column(lonIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not float64", lonCol)
+	}
+
+	b := &geoBounds{}
+	for i := 0; i < lat.Len(); i++ {
+		if lat.IsNull(i) || lon.IsNull(i) {
+			continue
+		}
+		b.extend(lon.Value(i), lat.Value(i))
+	}
+
+	return b, nil
+}
+
+// parseGeoParquetKV reads back the "geo" metadata written by buildGeoParquetMeta
+// or sinkGeoParquetGeometry, used by NewLRSRouteFromGeoParquet to find the
+// primary geometry column and its CRS.
+func parseGeoParquetKV(meta arrow.Metadata) (*geoParquetMeta, bool) {
+	for i, k := range meta.Keys() {
+		if k != geoParquetKey {
+			continue
+		}
+		var out geoParquetMeta
+		if err := json.Unmarshal([]byte(meta.Values()[i]), &out); err != nil {
+			return nil, false
+		}
+		return &out, true
+	}
+	return nil, false
+}
@@ -0,0 +1,372 @@
+package route
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// geometryColumn is the name of the WKB geometry column Sink writes when
+// WithGeoParquetGeometry is used, and the primary_column NewLRSRouteFromGeoParquet
+// expects on read.
+const geometryColumn = "geometry"
+
+// wkbLineStringM is the ISO WKB type code for a 2D LineString carrying an M
+// ordinate (base type 2, +2000 for the M dimension).
+const wkbLineStringM = 2002
+
+// WithGeoParquetGeometry enables writing a true GeoParquet file from Sink:
+// instead of the raw per-vertex LAT/LON/MVAL/VERTEX_SEQ table, the output is
+// one row per route carrying a WKB-encoded LineStringM geometry built from
+// the vertex rows, plus the standard "geo" file-level metadata. Mutually
+// exclusive with WithGeoParquetMetadata -- if both are set, this one wins.
+func WithGeoParquetGeometry() SinkOption {
+	return func(l *LRSRoute) {
+		l.geoParquetGeometry = true
+	}
+}
+
+// encodeWKBLineStringM encodes xs/ys/ms (equal length, one per vertex) as a
+// little-endian ISO WKB LineString M.
+func encodeWKBLineStringM(xs, ys, ms []float64) []byte {
+	n := len(xs)
+	buf := make([]byte, 9+n*24)
+	buf[0] = 1 // little-endian byte order
+	binary.LittleEndian.PutUint32(buf[1:5], wkbLineStringM)
+	binary.LittleEndian.PutUint32(buf[5:9], uint32(n))
+	for i := 0; i < n; i++ {
+		off := 9 + i*24
+		binary.LittleEndian.PutUint64(buf[off:off+8], math.Float64bits(xs[i]))
+		binary.LittleEndian.PutUint64(buf[off+8:off+16], math.Float64bits(ys[i]))
+		binary.LittleEndian.PutUint64(buf[off+16:off+24], math.Float64bits(ms[i]))
+	}
+	return buf
+}
+
+// decodeWKBLineStringM decodes a little-endian ISO WKB LineString M back
+// into xs/ys/ms, one entry per vertex in the geometry's original order.
+func decodeWKBLineStringM(b []byte) (xs, ys, ms []float64, err error) {
+	if len(b) < 9 {
+		return nil, nil, nil, fmt.Errorf("WKB linestring too short: %d bytes", len(b))
+	}
+	if b[0] != 1 {
+		return nil, nil, nil, fmt.Errorf("only little-endian WKB is supported")
+	}
+	if geomType := binary.LittleEndian.Uint32(b[1:5]); geomType != wkbLineStringM {
+		return nil, nil, nil, fmt.Errorf("expected WKB LineString M (type %d), got type %d", wkbLineStringM, geomType)
+	}
+	n := int(binary.LittleEndian.Uint32(b[5:9]))
+	if len(b) < 9+n*24 {
+		return nil, nil, nil, fmt.Errorf("WKB linestring truncated: want %d points, have %d bytes", n, len(b)-9)
+	}
+
+	xs = make([]float64, n)
+	ys = make([]float64, n)
+	ms = make([]float64, n)
+	for i := 0; i < n; i++ {
+		off := 9 + i*24
+		xs[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[off : off+8]))
+		ys[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[off+8 : off+16]))
+		ms[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[off+16 : off+24]))
+	}
+	return xs, ys, ms, nil
+}
+
+// looksLikeWKT reports whether crs looks like a WKT CRS definition (as
+// opposed to a short identifier like "EPSG:4326"), by checking for one of
+// the well-known WKT root keywords.
+func looksLikeWKT(crs string) bool {
+	for _, keyword := range []string{"GEOGCS[", "PROJCS[", "GEOGCRS[", "PROJCRS[", "COMPD_CS["} {
+		if strings.Contains(crs, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveGeometryCRS builds the "crs" field of a geoParquetColumn from crs:
+// a WKT definition is converted to PROJJSON via projjsonFromWKT, anything
+// else (an EPSG code, an empty string) is passed through verbatim so it
+// isn't silently misrepresented as WGS84 or mangled into a fake PROJJSON
+// object.
+func resolveGeometryCRS(crs string) any {
+	if crs == "" {
+		return nil
+	}
+	if looksLikeWKT(crs) {
+		return projjsonFromWKT(crs)
+	}
+	return crs
+}
+
+// routeLineStringGeometry builds the WKB LineString M geometry and bbox for
+// every vertex row across l.records, ordered by VertexSeqColumn.
+func (l *LRSRoute) routeLineStringGeometry() ([]byte, []float64, error) {
+	type vertex struct {
+		seq     int32
+		x, y, m float64
+	}
+	var vertices []vertex
+
+	for _, rec := range l.records {
+		schema := rec.Schema()
+		latIdx := schema.FieldIndices(l.latitudeCol)
+		lonIdx := schema.FieldIndices(l.longitudeCol)
+		mvalIdx := schema.FieldIndices(l.mValueCol)
+		seqIdx := schema.FieldIndices(l.VertexSeqColumn)
+		if len(latIdx) == 0 || len(lonIdx) == 0 || len(mvalIdx) == 0 || len(seqIdx) == 0 {
+			return nil, nil, fmt.Errorf("record is missing one of %s/%s/%s/%s", l.latitudeCol, l.longitudeCol, l.mValueCol, l.VertexSeqColumn)
+		}
+
+		lat, ok := rec.Column(latIdx[0]).(*array.Float64)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s column is not float64", l.latitudeCol)
+		}
+		lon, ok := rec.Column(lonIdx[0]).(*array.Float64)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s column is not float64", l.longitudeCol)
+		}
+		mval, ok := rec.Column(mvalIdx[0]).(*array.Float64)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s column is not float64", l.mValueCol)
+		}
+		seq, ok := rec.Column(seqIdx[0]).(*array.Int32)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s column is not int32", l.VertexSeqColumn)
+		}
+
+		for i := 0; i < int(rec.NumRows()); i++ {
+			vertices = append(vertices, vertex{
+				seq: seq.Value(i),
+				x:   lon.Value(i),
+				y:   lat.Value(i),
+				m:   mval.Value(i),
+			})
+		}
+	}
+
+	if len(vertices) == 0 {
+		return nil, nil, fmt.Errorf("no vertex rows to build geometry from")
+	}
+
+	sort.Slice(vertices, func(i, j int) bool { return vertices[i].seq < vertices[j].seq })
+
+	bounds := &geoBounds{}
+	xs := make([]float64, len(vertices))
+	ys := make([]float64, len(vertices))
+	ms := make([]float64, len(vertices))
+	for i, v := range vertices {
+		xs[i], ys[i], ms[i] = v.x, v.y, v.m
+		bounds.extend(v.x, v.y)
+	}
+
+	return encodeWKBLineStringM(xs, ys, ms), bounds.bbox(), nil
+}
+
+// sinkGeoParquetGeometry writes l.records' vertex rows as a single-row
+// GeoParquet file to f: ROUTEID plus a WKB LineString M geometry
+// column built by routeLineStringGeometry, with "geo" file-level metadata
+// describing it.
+func (l *LRSRoute) sinkGeoParquetGeometry(f *os.File) error {
+	wkb, bbox, err := l.routeLineStringGeometry()
+	if err != nil {
+		return fmt.Errorf("failed to build route geometry: %v", err)
+	}
+
+	meta := &geoParquetMeta{
+		Version:       GeoParquetVersion,
+		PrimaryColumn: geometryColumn,
+		Columns: map[string]*geoParquetColumn{
+			geometryColumn: {
+				Encoding:      "WKB",
+				GeometryTypes: []string{"LineString"},
+				CRS:           resolveGeometryCRS(l.crs),
+				Edges:         "planar",
+				Bbox:          bbox,
+			},
+		},
+	}
+	kv, err := geoParquetKV(meta)
+	if err != nil {
+		return fmt.Errorf("failed to build geo metadata: %v", err)
+	}
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+		{Name: geometryColumn, Type: arrow.BinaryTypes.Binary},
+	}, &kv)
+
+	pool := memory.NewGoAllocator()
+	routeIDBuilder := array.NewStringBuilder(pool)
+	defer routeIDBuilder.Release()
+	routeIDBuilder.Append(l.route_id)
+	routeIDArr := routeIDBuilder.NewArray()
+	defer routeIDArr.Release()
+
+	geomBuilder := array.NewBinaryBuilder(pool, arrow.BinaryTypes.Binary)
+	defer geomBuilder.Release()
+	geomBuilder.Append(wkb)
+	geomArr := geomBuilder.NewArray()
+	defer geomArr.Release()
+
+	rec := array.NewRecordBatch(schema, []arrow.Array{routeIDArr, geomArr}, 1)
+	defer rec.Release()
+
+	writer, err := pqarrow.NewFileWriter(
+		schema,
+		f,
+		parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy)),
+		pqarrow.DefaultWriterProps(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create parquet writer: %v", err)
+	}
+	defer writer.Close()
+
+	return writer.WriteBuffered(rec)
+}
+
+// NewLRSRouteFromGeoParquet reads a GeoParquet file written by
+// Sink(WithGeoParquetGeometry()), decoding the WKB LineString M geometry of
+// every row matching routeID back into per-vertex LAT/LON/MVAL/VERTEX_SEQ
+// columns. The returned LRSRoute has source_files.Point set to filePath so
+// pushdown-style queries against it keep working.
+func NewLRSRouteFromGeoParquet(filePath, routeID string) (LRSRoute, error) {
+	pf, err := file.OpenParquetFile(filePath, false)
+	if err != nil {
+		return LRSRoute{}, fmt.Errorf("failed to open parquet file: %v", err)
+	}
+	defer pf.Close()
+
+	reader, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{}, memory.NewGoAllocator())
+	if err != nil {
+		return LRSRoute{}, fmt.Errorf("failed to create arrow reader: %v", err)
+	}
+
+	schema, err := reader.Schema()
+	if err != nil {
+		return LRSRoute{}, fmt.Errorf("failed to read schema: %v", err)
+	}
+
+	meta, ok := parseGeoParquetKV(schema.Metadata())
+	if !ok {
+		return LRSRoute{}, fmt.Errorf("file has no GeoParquet \"geo\" metadata")
+	}
+	col, ok := meta.Columns[meta.PrimaryColumn]
+	if !ok {
+		return LRSRoute{}, fmt.Errorf("geo metadata missing primary column %q", meta.PrimaryColumn)
+	}
+	if col.Encoding != "WKB" {
+		return LRSRoute{}, fmt.Errorf("unsupported GeoParquet encoding %q, only \"WKB\" is supported", col.Encoding)
+	}
+
+	crs := ""
+	if wkt, ok := col.CRS.(string); ok {
+		crs = wkt
+	} else if col.CRS != nil {
+		if m, ok := col.CRS.(map[string]any); ok {
+			if name, ok := m["name"].(string); ok {
+				crs = name
+			}
+		}
+	}
+
+	routeIDIdx := schema.FieldIndices("ROUTEID")
+	geomIdx := schema.FieldIndices(meta.PrimaryColumn)
+	if len(routeIDIdx) == 0 || len(geomIdx) == 0 {
+		return LRSRoute{}, fmt.Errorf("file missing ROUTEID or %q column", meta.PrimaryColumn)
+	}
+
+	recordReader, err := reader.GetRecordReader(context.Background(), nil, nil)
+	if err != nil {
+		return LRSRoute{}, fmt.Errorf("failed to get record reader: %v", err)
+	}
+	defer recordReader.Release()
+
+	var xs, ys, ms []float64
+	found := false
+	for recordReader.Next() {
+		rec := recordReader.RecordBatch()
+		routeIDs, ok := rec.Column(routeIDIdx[0]).(*array.String)
+		if !ok {
+			return LRSRoute{}, fmt.Errorf("ROUTEID column is not a string array")
+		}
+		geometry, ok := rec.Column(geomIdx[0]).(*array.Binary)
+		if !ok {
+			return LRSRoute{}, fmt.Errorf("%s column is not a binary array", meta.PrimaryColumn)
+		}
+
+		for i := 0; i < int(rec.NumRows()); i++ {
+			if routeIDs.Value(i) != routeID {
+				continue
+			}
+			rowXs, rowYs, rowMs, err := decodeWKBLineStringM(geometry.Value(i))
+			if err != nil {
+				return LRSRoute{}, fmt.Errorf("failed to decode geometry for route %q: %v", routeID, err)
+			}
+			xs = append(xs, rowXs...)
+			ys = append(ys, rowYs...)
+			ms = append(ms, rowMs...)
+			found = true
+		}
+	}
+	if err := recordReader.Err(); err != nil {
+		return LRSRoute{}, fmt.Errorf("error reading records: %v", err)
+	}
+	if !found {
+		return LRSRoute{}, fmt.Errorf("route %q not found in %s", routeID, filePath)
+	}
+
+	pool := memory.NewGoAllocator()
+	latBuilder := array.NewFloat64Builder(pool)
+	defer latBuilder.Release()
+	lonBuilder := array.NewFloat64Builder(pool)
+	defer lonBuilder.Release()
+	mvalBuilder := array.NewFloat64Builder(pool)
+	defer mvalBuilder.Release()
+	seqBuilder := array.NewInt32Builder(pool)
+	defer seqBuilder.Release()
+	routeIDBuilder := array.NewStringBuilder(pool)
+	defer routeIDBuilder.Release()
+
+	for i := range xs {
+		latBuilder.Append(ys[i])
+		lonBuilder.Append(xs[i])
+		mvalBuilder.Append(ms[i])
+		seqBuilder.Append(int32(i))
+		routeIDBuilder.Append(routeID)
+	}
+
+	vertexSchema := arrow.NewSchema([]arrow.Field{
+		{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "VERTEX_SEQ", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+	}, nil)
+
+	latArr := latBuilder.NewArray()
+	lonArr := lonBuilder.NewArray()
+	mvalArr := mvalBuilder.NewArray()
+	seqArr := seqBuilder.NewArray()
+	routeIDArr := routeIDBuilder.NewArray()
+
+	rec := array.NewRecordBatch(vertexSchema, []arrow.Array{latArr, lonArr, mvalArr, seqArr, routeIDArr}, int64(len(xs)))
+
+	out := NewLRSRoute(routeID, []arrow.RecordBatch{rec}, crs)
+	out.source_files = &sourceFiles{Point: &filePath}
+	out.setPushDown(true)
+	return out, nil
+}
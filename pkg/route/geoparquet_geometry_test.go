@@ -0,0 +1,66 @@
+package route
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSinkGeoParquetGeometryRoundTrip(t *testing.T) {
+	lrs := newTestLRSRoute(t, "EPSG:4326")
+	defer lrs.Release()
+
+	if err := lrs.Sink(WithGeoParquetGeometry()); err != nil {
+		t.Fatalf("Sink failed: %v", err)
+	}
+
+	pointFile := lrs.GetPointFile()
+	if pointFile == nil {
+		t.Fatal("expected Sink to set a point file")
+	}
+
+	// Copy the file out of Sink's temp dir, which Release below will remove.
+	filePath := filepath.Join(t.TempDir(), "route_geom.parquet")
+	data, err := os.ReadFile(*pointFile)
+	if err != nil {
+		t.Fatalf("failed to read sunk file: %v", err)
+	}
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatalf("failed to copy sunk file: %v", err)
+	}
+
+	out, err := NewLRSRouteFromGeoParquet(filePath, "L01")
+	if err != nil {
+		t.Fatalf("NewLRSRouteFromGeoParquet failed: %v", err)
+	}
+	defer out.Release()
+
+	if out.GetRouteID() != "L01" {
+		t.Errorf("expected route id L01, got %s", out.GetRouteID())
+	}
+	if out.GetCRS() != "EPSG:4326" {
+		t.Errorf("expected crs EPSG:4326, got %s", out.GetCRS())
+	}
+	if out.GetPointFile() == nil || *out.GetPointFile() != filePath {
+		t.Errorf("expected point file to be set to %s", filePath)
+	}
+
+	recs := out.GetRecords()
+	if len(recs) != 1 || recs[0].NumRows() != 2 {
+		t.Fatalf("expected 2 decoded vertex rows, got %+v", recs)
+	}
+}
+
+func TestNewLRSRouteFromGeoParquetUnknownRoute(t *testing.T) {
+	lrs := newTestLRSRoute(t, "EPSG:4326")
+	defer lrs.Release()
+
+	if err := lrs.Sink(WithGeoParquetGeometry()); err != nil {
+		t.Fatalf("Sink failed: %v", err)
+	}
+
+	_, err := NewLRSRouteFromGeoParquet(*lrs.GetPointFile(), "NOPE")
+	if err == nil {
+		t.Fatal("expected an error for an unknown route id")
+	}
+}
@@ -0,0 +1,245 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CheckFunc is a single health probe: it returns a non-nil error when the
+// dependency it tests is unreachable or otherwise unhealthy.
+type CheckFunc func(ctx context.Context) error
+
+// checkStatus is one check's outcome in a health response.
+type checkStatus struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"` // "ok" or "error"
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// healthResponse is HealthChecker's JSON response body.
+type healthResponse struct {
+	Status string        `json:"status"` // "ok" or "error"
+	Checks []checkStatus `json:"checks"`
+}
+
+// HealthChecker aggregates liveness and readiness probes for an
+// LRSRouteRepository behind a single http.Handler: readiness runs
+// Postgres and DuckDB reachability, LRS_DATA_DIR writability, and any
+// caller-registered checks, following the check-aggregation pattern
+// common to health frameworks (return 200 only once every check passes,
+// 503 with per-check detail otherwise). Liveness only confirms the
+// process is up to serve requests at all -- it never touches Postgres,
+// DuckDB, or ArcGIS.
+type HealthChecker struct {
+	repo *LRSRouteRepository
+
+	mu     sync.Mutex
+	names  []string
+	checks map[string]CheckFunc
+
+	// Deep, when true, adds HEAD probes of featureServiceURL and
+	// tokenURL to readiness. Off by default since those endpoints sit
+	// behind ArcGIS and can be slow or rate-limited; callers can also
+	// opt in per-request with ?deep=true.
+	Deep bool
+}
+
+// NewHealthChecker creates a HealthChecker wired to repo's Postgres,
+// DuckDB, and LRS_DATA_DIR. Use RegisterCheck to add further probes
+// before mounting it on a mux.
+func NewHealthChecker(repo *LRSRouteRepository) *HealthChecker {
+	return &HealthChecker{
+		repo:   repo,
+		checks: make(map[string]CheckFunc),
+	}
+}
+
+// HealthHandler returns an http.Handler reporting repo's health: a
+// liveness probe at any path ending in "live" that only confirms the
+// process is up, and a readiness probe everywhere else that runs
+// Postgres, DuckDB, LRS_DATA_DIR, and any registered checks. Mount the
+// same handler at both a liveness and a readiness path, e.g.:
+//
+//	h := route.HealthHandler(repo)
+//	mux.Handle("/healthz/live", h)
+//	mux.Handle("/healthz/ready", h)
+//
+// Callers that need RegisterCheck should keep NewHealthChecker's
+// *HealthChecker instead, which also implements http.Handler.
+func HealthHandler(repo *LRSRouteRepository) http.Handler {
+	return NewHealthChecker(repo)
+}
+
+// RegisterCheck adds a named readiness probe, run alongside the built-in
+// Postgres/DuckDB/LRS_DATA_DIR checks. Registering a name that's already
+// registered replaces it.
+func (h *HealthChecker) RegisterCheck(name string, fn CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, exists := h.checks[name]; !exists {
+		h.names = append(h.names, name)
+	}
+	h.checks[name] = fn
+}
+
+// ServeHTTP serves liveness at any path ending in "live" and readiness
+// everywhere else.
+func (h *HealthChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "live") {
+		h.serveLiveness(w)
+		return
+	}
+	h.serveReadiness(w, r)
+}
+
+// serveLiveness always reports ok: reaching this handler at all means
+// the process is up.
+func (h *HealthChecker) serveLiveness(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(healthResponse{
+		Status: "ok",
+		Checks: []checkStatus{{Name: "process", Status: "ok"}},
+	})
+}
+
+// serveReadiness runs every check -- built-in, deep, then registered in
+// registration order -- and reports 200 only if all of them pass.
+func (h *HealthChecker) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	checks := []checkStatus{
+		runCheck(ctx, "postgres", h.repo.checkPostgres),
+		runCheck(ctx, "duckdb", h.repo.checkDuckDB),
+		runCheck(ctx, "data-dir", checkDataDir),
+	}
+
+	if h.Deep || r.URL.Query().Get("deep") == "true" {
+		checks = append(checks,
+			runCheck(ctx, "arcgis-feature-service", h.repo.checkArcGISReachable(h.repo.featureServiceURL)),
+			runCheck(ctx, "arcgis-token-service", h.repo.checkArcGISReachable(h.repo.tokenURL)),
+		)
+	}
+
+	h.mu.Lock()
+	names := append([]string(nil), h.names...)
+	fns := make(map[string]CheckFunc, len(h.checks))
+	for name, fn := range h.checks {
+		fns[name] = fn
+	}
+	h.mu.Unlock()
+
+	for _, name := range names {
+		checks = append(checks, runCheck(ctx, name, fns[name]))
+	}
+
+	status := "ok"
+	statusCode := http.StatusOK
+	for _, c := range checks {
+		if c.Status != "ok" {
+			status = "error"
+			statusCode = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(healthResponse{Status: status, Checks: checks})
+}
+
+// runCheck runs fn, timing it and translating its error (if any) into a
+// checkStatus.
+func runCheck(ctx context.Context, name string, fn CheckFunc) checkStatus {
+	start := time.Now()
+	err := fn(ctx)
+	cs := checkStatus{
+		Name:      name,
+		Status:    "ok",
+		LatencyMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		cs.Status = "error"
+		cs.Error = err.Error()
+	}
+	return cs
+}
+
+// checkPostgres verifies r's Postgres catalog is reachable by running
+// SELECT 1 against it through DuckDB's postgres_query table function,
+// rather than SELECT 1 against r.db directly, which would only ever
+// exercise DuckDB itself (see checkDuckDB).
+func (r *LRSRouteRepository) checkPostgres(ctx context.Context) error {
+	if err := r.attachCatalogDB(ctx); err != nil {
+		return fmt.Errorf("failed to attach postgres catalog: %w", err)
+	}
+	var one int
+	row := r.db.QueryRowContext(ctx, "SELECT * FROM postgres_query('postgres_db', 'SELECT 1')")
+	if err := row.Scan(&one); err != nil {
+		return fmt.Errorf("postgres SELECT 1 failed: %w", err)
+	}
+	return nil
+}
+
+// checkDuckDB verifies r's DuckDB connection itself is alive, independent
+// of whether the postgres_db attachment succeeds.
+func (r *LRSRouteRepository) checkDuckDB(ctx context.Context) error {
+	var fortyTwo int
+	row := r.db.QueryRowContext(ctx, "SELECT 42")
+	if err := row.Scan(&fortyTwo); err != nil {
+		return fmt.Errorf("duckdb SELECT 42 failed: %w", err)
+	}
+	if fortyTwo != 42 {
+		return fmt.Errorf("duckdb SELECT 42 returned unexpected value %d", fortyTwo)
+	}
+	return nil
+}
+
+// checkDataDir verifies LRS_DATA_DIR (falling back to ./data, same as
+// Import and mergeWithExisting) exists and is writable, by creating and
+// removing a probe file in it.
+func checkDataDir(ctx context.Context) error {
+	dataDir := os.Getenv("LRS_DATA_DIR")
+	if dataDir == "" {
+		dataDir = "./data"
+	}
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("LRS_DATA_DIR %s is not accessible: %w", dataDir, err)
+	}
+	probe := filepath.Join(dataDir, fmt.Sprintf(".health_probe_%d", time.Now().UnixNano()))
+	f, err := os.Create(probe)
+	if err != nil {
+		return fmt.Errorf("LRS_DATA_DIR %s is not writable: %w", dataDir, err)
+	}
+	f.Close()
+	return os.Remove(probe)
+}
+
+// checkArcGISReachable returns a CheckFunc issuing a HEAD request against
+// url, used for the deep ArcGIS checks Deep/?deep=true enables. A HEAD
+// is used instead of a token or feature request so the probe doesn't
+// spend ArcGIS rate limit or require credentials just to report
+// reachability.
+func (r *LRSRouteRepository) checkArcGISReachable(url string) CheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return err
+		}
+		client := &http.Client{Timeout: 5 * time.Second}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		return nil
+	}
+}
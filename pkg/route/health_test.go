@@ -0,0 +1,175 @@
+package route
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/duckdb/duckdb-go/v2"
+)
+
+func newTestRepoForHealth(t *testing.T) *LRSRouteRepository {
+	t.Helper()
+	connector, err := duckdb.NewConnector("", nil)
+	if err != nil {
+		t.Fatalf("Failed to create DuckDB connector: %v", err)
+	}
+	t.Cleanup(func() { connector.Close() })
+
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() { db.Close() })
+
+	return NewLRSRouteRepository(connector, "dbname=does-not-exist", db)
+}
+
+func TestHealthCheckerLivenessAlwaysOK(t *testing.T) {
+	repo := newTestRepoForHealth(t)
+	h := HealthHandler(repo)
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz/live", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 from liveness, got %d", rec.Code)
+	}
+
+	var resp healthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode liveness response: %v", err)
+	}
+	if resp.Status != "ok" {
+		t.Errorf("expected liveness status ok, got %s", resp.Status)
+	}
+}
+
+func TestHealthCheckerReadinessFailsOnBadPostgres(t *testing.T) {
+	repo := newTestRepoForHealth(t)
+	t.Setenv("LRS_DATA_DIR", t.TempDir())
+
+	h := HealthHandler(repo)
+	req := httptest.NewRequest(http.MethodGet, "/healthz/ready", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 with an unreachable postgres, got %d", rec.Code)
+	}
+
+	var resp healthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode readiness response: %v", err)
+	}
+	if resp.Status != "error" {
+		t.Errorf("expected readiness status error, got %s", resp.Status)
+	}
+
+	var sawDuckDB, sawPostgresFailure bool
+	for _, c := range resp.Checks {
+		if c.Name == "duckdb" && c.Status == "ok" {
+			sawDuckDB = true
+		}
+		if c.Name == "postgres" && c.Status == "error" {
+			sawPostgresFailure = true
+		}
+	}
+	if !sawDuckDB {
+		t.Error("expected the duckdb check to still pass on its own")
+	}
+	if !sawPostgresFailure {
+		t.Error("expected the postgres check to report failure for an unreachable database")
+	}
+}
+
+func TestHealthCheckerRegisterCheck(t *testing.T) {
+	repo := newTestRepoForHealth(t)
+	t.Setenv("LRS_DATA_DIR", t.TempDir())
+
+	checker := NewHealthChecker(repo)
+	checker.RegisterCheck("custom", func(ctx context.Context) error {
+		return errors.New("custom probe failed")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz/ready", nil)
+	rec := httptest.NewRecorder()
+	checker.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 with a failing registered check, got %d", rec.Code)
+	}
+
+	var resp healthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode readiness response: %v", err)
+	}
+
+	var sawCustom bool
+	for _, c := range resp.Checks {
+		if c.Name == "custom" {
+			sawCustom = true
+			if c.Status != "error" || c.Error != "custom probe failed" {
+				t.Errorf("expected custom check to report its error, got %+v", c)
+			}
+		}
+	}
+	if !sawCustom {
+		t.Error("expected the registered custom check to appear in the response")
+	}
+}
+
+func TestHealthCheckerDeepArcGISCheck(t *testing.T) {
+	var headRequests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			headRequests++
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	repo := newTestRepoForHealth(t)
+	repo.featureServiceURL = ts.URL
+	repo.tokenURL = ts.URL
+	t.Setenv("LRS_DATA_DIR", t.TempDir())
+
+	checker := NewHealthChecker(repo)
+	checker.Deep = true
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz/ready", nil)
+	rec := httptest.NewRecorder()
+	checker.ServeHTTP(rec, req)
+
+	if headRequests != 2 {
+		t.Errorf("expected 2 HEAD requests (feature service + token service), got %d", headRequests)
+	}
+
+	var resp healthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode readiness response: %v", err)
+	}
+
+	var sawFeatureCheck, sawTokenCheck bool
+	for _, c := range resp.Checks {
+		if c.Name == "arcgis-feature-service" {
+			sawFeatureCheck = true
+		}
+		if c.Name == "arcgis-token-service" {
+			sawTokenCheck = true
+		}
+	}
+	if !sawFeatureCheck || !sawTokenCheck {
+		t.Error("expected Deep to add both arcgis checks to the response")
+	}
+}
+
+func TestCheckDataDirOKForWritableDir(t *testing.T) {
+	t.Setenv("LRS_DATA_DIR", t.TempDir())
+
+	if err := checkDataDir(context.Background()); err != nil {
+		t.Errorf("expected checkDataDir to pass against a writable directory, got %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package route
+
+import (
+	"bm-lrs/pkg/geom"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// routeIndexCache holds the lazily-built spatial index behind a reference
+// so that LRSRoute itself stays an ordinary, copyable value type: the
+// sync.Once lives here, not on LRSRoute, and LRSRoute only ever holds a
+// pointer to one.
+type routeIndexCache struct {
+	once  sync.Once
+	index *geom.STRTree
+	err   error
+}
+
+// indexCacheMu guards the lazy allocation of an LRSRoute's indexCache
+// pointer; the build itself is still serialized per-route by that cache's
+// own sync.Once.
+var indexCacheMu sync.Mutex
+
+// Segment is the Candidate.Item held by an LRSRoute's spatial index: one
+// vertex-to-vertex leg of the route polyline, carrying enough of each
+// endpoint's attributes to interpolate an M-Value once a point has been
+// snapped to it.
+type Segment struct {
+	StartLat, StartLon, StartMVal float64
+	EndLat, EndLon, EndMVal       float64
+}
+
+// RouteIndexer is implemented by an LRSRouteInterface value that can hand
+// back the segment index of one of its constituent routes by ROUTEID
+// without a DuckDB round trip. mvalue.CalculatePointsMValue's EngineGeodesic
+// uses it to walk vertices in Go instead of pushing the nearest-segment
+// search down to the spatial extension.
+type RouteIndexer interface {
+	RouteIndex(routeID string) (*geom.STRTree, error)
+}
+
+// RouteIndex implements RouteIndexer for a single route: it returns this
+// route's own Index, erroring if routeID doesn't match GetRouteID so a
+// point tagged with the wrong ROUTEID fails loudly instead of silently
+// snapping to this route.
+func (l *LRSRoute) RouteIndex(routeID string) (*geom.STRTree, error) {
+	if routeID != l.route_id {
+		return nil, fmt.Errorf("route index requested for %q but this route is %q", routeID, l.route_id)
+	}
+	return l.Index()
+}
+
+// Index lazily builds, on first call, an STR-tree over the route's
+// vertex-to-vertex segments and returns it; later calls reuse the same
+// tree, since a route's vertices never change after construction.
+//
+// Index is exposed for in-process point-to-LRS snapping. The DuckDB-backed
+// path used by mvalue.CalculatePointsMValue pushes the equivalent nearest-
+// segment search down to DuckDB's spatial extension (itself R-tree backed)
+// instead of scanning vertices in Go, so it does not consume this index;
+// it's here for callers that want to snap without a DuckDB round trip.
+func (l *LRSRoute) Index() (*geom.STRTree, error) {
+	indexCacheMu.Lock()
+	if l.indexCache == nil {
+		l.indexCache = &routeIndexCache{}
+	}
+	cache := l.indexCache
+	indexCacheMu.Unlock()
+
+	cache.once.Do(func() {
+		cache.index, cache.err = buildSegmentIndex(l.records, l.latitudeCol, l.longitudeCol, l.mValueCol, l.VertexSeqColumn)
+	})
+	return cache.index, cache.err
+}
+
+// MValueLocator is implemented by an LRSRouteInterface value that can
+// interpolate (lat, lon) for a given M-Value on one of its constituent
+// routes -- the reverse of RouteIndexer's forward point-to-M-Value snap.
+type MValueLocator interface {
+	LocateMValue(routeID string, target float64) (lat, lon float64, err error)
+}
+
+// LocateMValue implements MValueLocator for a single route: it walks the
+// route's vertices ordered by VERTEX_SEQ to find the segment whose M-Value
+// range brackets target, then linearly interpolates (lat, lon) on it. It
+// errors the same way RouteIndex does when routeID doesn't match this
+// route's own ID, and when target falls outside the route's M-Value range.
+func (l *LRSRoute) LocateMValue(routeID string, target float64) (lat, lon float64, err error) {
+	if routeID != l.route_id {
+		return 0, 0, fmt.Errorf("m-value location requested for %q but this route is %q", routeID, l.route_id)
+	}
+
+	vertices, err := collectVertices(l.records, l.latitudeCol, l.longitudeCol, l.mValueCol, l.VertexSeqColumn)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(vertices) == 0 {
+		return 0, 0, fmt.Errorf("route %q has no vertices", routeID)
+	}
+	sortVertices(vertices)
+
+	first, last := vertices[0], vertices[len(vertices)-1]
+	if target < min2(first.mval, last.mval) || target > max2(first.mval, last.mval) {
+		return 0, 0, fmt.Errorf("m-value %g is outside route %q's range [%g, %g]", target, routeID, first.mval, last.mval)
+	}
+
+	for i := 0; i+1 < len(vertices); i++ {
+		a, b := vertices[i], vertices[i+1]
+		lo, hi := min2(a.mval, b.mval), max2(a.mval, b.mval)
+		if target < lo || target > hi {
+			continue
+		}
+
+		t := 0.0
+		if b.mval != a.mval {
+			t = (target - a.mval) / (b.mval - a.mval)
+		}
+		return a.lat + t*(b.lat-a.lat), a.lon + t*(b.lon-a.lon), nil
+	}
+
+	return last.lat, last.lon, nil
+}
+
+// BBox returns the axis-aligned bounding box over every vertex in l,
+// used to build a coarse route-to-route spatial index (see
+// RouteBBoxIndex) before falling back to Index()'s finer per-segment
+// search.
+func (l *LRSRoute) BBox() (geom.BBox, error) {
+	box := geom.BBox{}
+	set := false
+
+	for _, rec := range l.records {
+		bounds, err := routeBounds(rec, l.latitudeCol, l.longitudeCol)
+		if err != nil {
+			return geom.BBox{}, err
+		}
+		if !bounds.set {
+			continue
+		}
+		if !set {
+			box = geom.BBox{MinX: bounds.minX, MinY: bounds.minY, MaxX: bounds.maxX, MaxY: bounds.maxY}
+			set = true
+			continue
+		}
+		box.MinX = min2(box.MinX, bounds.minX)
+		box.MinY = min2(box.MinY, bounds.minY)
+		box.MaxX = max2(box.MaxX, bounds.maxX)
+		box.MaxY = max2(box.MaxY, bounds.maxY)
+	}
+
+	if !set {
+		return geom.BBox{}, fmt.Errorf("route %q has no vertices", l.route_id)
+	}
+	return box, nil
+}
+
+type vertex struct {
+	seq            float64
+	lat, lon, mval float64
+}
+
+// buildSegmentIndex extracts every vertex from records, orders them by
+// seqCol (record batches aren't guaranteed to already be vertex-ordered),
+// and builds an STR-tree over the consecutive legs between them.
+func buildSegmentIndex(records []arrow.RecordBatch, latCol, lonCol, mValCol, seqCol string) (*geom.STRTree, error) {
+	vertices, err := collectVertices(records, latCol, lonCol, mValCol, seqCol)
+	if err != nil {
+		return nil, err
+	}
+
+	return geom.NewSTRTree(candidatesFromVertices(vertices)), nil
+}
+
+// collectVertices extracts every vertex from records in schema column
+// order, without ordering them by seqCol; callers that need vertex order
+// (buildSegmentIndex, LRSRoute.ToGeoJSON, LRSRoute.ToEsriRouteJSON) sort the
+// result themselves by seq.
+func collectVertices(records []arrow.RecordBatch, latCol, lonCol, mValCol, seqCol string) ([]vertex, error) {
+	var vertices []vertex
+
+	for _, rec := range records {
+		schema := rec.Schema()
+
+		latIdx := schema.FieldIndices(latCol)
+		lonIdx := schema.FieldIndices(lonCol)
+		mValIdx := schema.FieldIndices(mValCol)
+		seqIdx := schema.FieldIndices(seqCol)
+		if len(latIdx) == 0 || len(lonIdx) == 0 || len(mValIdx) == 0 || len(seqIdx) == 0 {
+			return nil, fmt.Errorf("route records missing one of %s, %s, %s, %s columns", latCol, lonCol, mValCol, seqCol)
+		}
+
+		lat, ok := rec.Column(latIdx[0]).(*array.Float64)
+		if !ok {
+			return nil, fmt.Errorf("%s column is not float64", latCol)
+		}
+		lon, ok := rec.Column(lonIdx[0]).(*array.Float64)
+		if !ok {
+			return nil, fmt.Errorf("%s column is not float64", lonCol)
+		}
+		mval, ok := rec.Column(mValIdx[0]).(*array.Float64)
+		if !ok {
+			return nil, fmt.Errorf("%s column is not float64", mValCol)
+		}
+		seqAt, err := seqValueFunc(rec.Column(seqIdx[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%s column: %w", seqCol, err)
+		}
+
+		for i := 0; i < int(rec.NumRows()); i++ {
+			vertices = append(vertices, vertex{
+				seq:  seqAt(i),
+				lat:  lat.Value(i),
+				lon:  lon.Value(i),
+				mval: mval.Value(i),
+			})
+		}
+	}
+
+	return vertices, nil
+}
+
+// seqValueFunc returns a function reading col[i] as a float64, supporting
+// Int32 VERTEX_SEQ columns (what every constructor in this package
+// builds) and Float64 ones.
+func seqValueFunc(col arrow.Array) (func(i int) float64, error) {
+	switch c := col.(type) {
+	case *array.Float64:
+		return c.Value, nil
+	case *array.Int32:
+		return func(i int) float64 { return float64(c.Value(i)) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", col)
+	}
+}
+
+// sortVertices orders vertices by vertex sequence number in place.
+func sortVertices(vertices []vertex) {
+	sort.Slice(vertices, func(i, j int) bool { return vertices[i].seq < vertices[j].seq })
+}
+
+// candidatesFromVertices builds one Candidate per consecutive vertex pair,
+// ordered by vertex sequence number.
+func candidatesFromVertices(vertices []vertex) []geom.Candidate {
+	sortVertices(vertices)
+
+	candidates := make([]geom.Candidate, 0, len(vertices)-1)
+	for i := 0; i+1 < len(vertices); i++ {
+		a, b := vertices[i], vertices[i+1]
+		seg := Segment{
+			StartLat: a.lat, StartLon: a.lon, StartMVal: a.mval,
+			EndLat: b.lat, EndLon: b.lon, EndMVal: b.mval,
+		}
+		candidates = append(candidates, geom.Candidate{
+			Item: seg,
+			Box: geom.BBox{
+				MinX: min2(a.lon, b.lon), MaxX: max2(a.lon, b.lon),
+				MinY: min2(a.lat, b.lat), MaxY: max2(a.lat, b.lat),
+			},
+		})
+	}
+	return candidates
+}
+
+func min2(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max2(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
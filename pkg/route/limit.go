@@ -0,0 +1,144 @@
+package route
+
+import (
+	"bm-lrs/pkg/geom"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// FilterOption configures LRSRoute.Filter.
+type FilterOption func(*filterConfig)
+
+type filterConfig struct {
+	renumber bool
+}
+
+// WithRenumberedVertexSeq renumbers the surviving vertices' VERTEX_SEQ
+// contiguously from 0, instead of preserving their original values, which
+// otherwise develop gaps wherever a vertex was dropped.
+func WithRenumberedVertexSeq() FilterOption {
+	return func(c *filterConfig) { c.renumber = true }
+}
+
+// Filter returns a copy of l containing only the vertices that fall
+// inside lim's boundary (reprojected into l's CRS on demand). By default
+// surviving vertices keep their original VERTEX_SEQ, leaving gaps where
+// vertices were dropped; WithRenumberedVertexSeq renumbers them instead.
+func (l *LRSRoute) Filter(lim *geom.Limiter, opts ...FilterOption) (LRSRoute, error) {
+	cfg := &filterConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	filtered, err := lim.FilterRecords(l.records, l.latitudeCol, l.longitudeCol, l.crs)
+	if err != nil {
+		return LRSRoute{}, err
+	}
+
+	if cfg.renumber {
+		filtered, err = renumberVertexSeq(filtered, l.latitudeCol, l.longitudeCol, l.mValueCol, l.VertexSeqColumn)
+		if err != nil {
+			return LRSRoute{}, err
+		}
+	}
+
+	return NewLRSRoute(l.route_id, filtered, l.crs), nil
+}
+
+// Clip splits l's vertices at lim's boundary (reprojected into l's CRS on
+// demand) via DuckDB's ST_Intersection, returning one LRSRoute per
+// contiguous piece that falls inside the boundary -- a route crossing the
+// boundary more than once clips into more than one piece. Each piece
+// keeps l's RouteID and CRS, with VERTEX_SEQ renumbered from 0; M values
+// are preserved on original vertices and linearly interpolated along the
+// crossed segment for the new vertices introduced where the line crosses
+// the boundary.
+func (l *LRSRoute) Clip(lim *geom.Limiter) ([]LRSRoute, error) {
+	vertices, err := collectVertices(l.records, l.latitudeCol, l.longitudeCol, l.mValueCol, l.VertexSeqColumn)
+	if err != nil {
+		return nil, err
+	}
+	sortVertices(vertices)
+
+	line := make([]geom.Vertex, len(vertices))
+	for i, v := range vertices {
+		line[i] = geom.Vertex{Lat: v.lat, Lon: v.lon, M: v.mval}
+	}
+
+	pieces, err := lim.ClipLine(line, l.crs)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]LRSRoute, 0, len(pieces))
+	for _, piece := range pieces {
+		records, err := recordsFromVertices(piece, l.latitudeCol, l.longitudeCol, l.mValueCol, l.VertexSeqColumn)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, NewLRSRoute(l.route_id, records, l.crs))
+	}
+	return out, nil
+}
+
+// renumberVertexSeq rebuilds records -- already filtered to surviving
+// vertices only -- into a single batch with seqCol reassigned as a
+// contiguous 0-based sequence in vertex order, using latCol/lonCol/mValCol
+// and seqCol, the same canonical columns collectVertices and ToGeoJSON
+// round-trip.
+func renumberVertexSeq(records []arrow.RecordBatch, latCol, lonCol, mValCol, seqCol string) ([]arrow.RecordBatch, error) {
+	vertices, err := collectVertices(records, latCol, lonCol, mValCol, seqCol)
+	if err != nil {
+		return nil, err
+	}
+	sortVertices(vertices)
+
+	line := make([]geom.Vertex, len(vertices))
+	for i, v := range vertices {
+		line[i] = geom.Vertex{Lat: v.lat, Lon: v.lon, M: v.mval}
+	}
+	return recordsFromVertices(line, latCol, lonCol, mValCol, seqCol)
+}
+
+// recordsFromVertices builds a single-batch LRSRoute record set from
+// vertices, already in the order the output route should expose, numbering
+// seqCol sequentially from 0.
+func recordsFromVertices(vertices []geom.Vertex, latCol, lonCol, mValCol, seqCol string) ([]arrow.RecordBatch, error) {
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: latCol, Type: arrow.PrimitiveTypes.Float64},
+			{Name: lonCol, Type: arrow.PrimitiveTypes.Float64},
+			{Name: mValCol, Type: arrow.PrimitiveTypes.Float64},
+			{Name: seqCol, Type: arrow.PrimitiveTypes.Int32},
+		},
+		nil,
+	)
+
+	lat := array.NewFloat64Builder(pool)
+	lon := array.NewFloat64Builder(pool)
+	mval := array.NewFloat64Builder(pool)
+	seq := array.NewInt32Builder(pool)
+	defer lat.Release()
+	defer lon.Release()
+	defer mval.Release()
+	defer seq.Release()
+
+	for i, v := range vertices {
+		lat.Append(v.Lat)
+		lon.Append(v.Lon)
+		mval.Append(v.M)
+		seq.Append(int32(i))
+	}
+
+	latArr, lonArr, mvalArr, seqArr := lat.NewArray(), lon.NewArray(), mval.NewArray(), seq.NewArray()
+	defer latArr.Release()
+	defer lonArr.Release()
+	defer mvalArr.Release()
+	defer seqArr.Release()
+
+	rec := array.NewRecordBatch(schema, []arrow.Array{latArr, lonArr, mvalArr, seqArr}, int64(len(vertices)))
+	return []arrow.RecordBatch{rec}, nil
+}
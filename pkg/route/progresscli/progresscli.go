@@ -0,0 +1,49 @@
+// Package progresscli adapts route.ProgressUpdate callbacks to a simple
+// terminal progress display for the CLI.
+package progresscli
+
+import (
+	"bm-lrs/pkg/route"
+	"fmt"
+	"os"
+)
+
+// IsTerminal reports whether f is connected to a terminal, used to decide
+// whether NewCLIProgressBar's output can redraw a line in place instead of
+// printing one line per update.
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return (fi.Mode() & os.ModeCharDevice) != 0
+}
+
+// NewCLIProgressBar returns a SyncOptions.FetchProgress hook that renders
+// SyncPhaseFetch updates as a single line on stderr, redrawn in place when
+// stderr is a terminal, and prints a short label at every other phase
+// transition.
+func NewCLIProgressBar() func(route.ProgressUpdate) {
+	interactive := IsTerminal(os.Stderr)
+
+	return func(u route.ProgressUpdate) {
+		if u.Phase != route.SyncPhaseFetch {
+			fmt.Fprintf(os.Stderr, "%s...\n", u.Phase)
+			return
+		}
+
+		var pct float64
+		if u.TotalFeatures > 0 {
+			pct = float64(u.FetchedFeatures) / float64(u.TotalFeatures) * 100
+		}
+
+		line := fmt.Sprintf("fetching features: %d/%d (%.0f%%), %d bytes downloaded",
+			u.FetchedFeatures, u.TotalFeatures, pct, u.BytesDownloaded)
+
+		if interactive {
+			fmt.Fprintf(os.Stderr, "\r%s", line)
+		} else {
+			fmt.Fprintln(os.Stderr, line)
+		}
+	}
+}
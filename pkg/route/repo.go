@@ -2,16 +2,17 @@ package route
 
 import (
 	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/progress"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/duckdb/duckdb-go/v2"
@@ -23,37 +24,169 @@ type LRSRouteRepository struct {
 	db                *sql.DB
 	tokenURL          string
 	featureServiceURL string
+
+	// arcgisFetchLimit is SyncAll's page size (resultRecordCount). Zero
+	// (the default) falls back to defaultArcGISFetchLimit.
+	arcgisFetchLimit int
+
+	// arcgisMaxRetries bounds how many attempts withArcGISRetry makes for
+	// a single ArcGIS token or feature request. Zero (the default) falls
+	// back to defaultArcGISMaxRetries.
+	arcgisMaxRetries int
+
+	// arcgisRetryBaseDelay is withArcGISRetry's first backoff wait,
+	// doubling on each subsequent attempt. Zero (the default) falls back
+	// to defaultArcGISRetryBaseDelay.
+	arcgisRetryBaseDelay time.Duration
+
+	// arcgisHTTPTimeout bounds a single ArcGIS HTTP round trip. Zero (the
+	// default) falls back to defaultArcGISHTTPTimeout.
+	arcgisHTTPTimeout time.Duration
+
+	// arcgisRetryJitter is the max fraction of a backoff delay randomly
+	// added or subtracted. Zero (the default) falls back to
+	// defaultArcGISRetryJitter; set negative to disable jitter entirely.
+	arcgisRetryJitter float64
+
+	// featureSource is what Sync fetches features from. Defaults to an
+	// ArcGIS-backed source built from tokenURL/featureServiceURL; set
+	// WithFeatureSource to sync from WFS, a local directory, or PostGIS
+	// instead. SyncAll, which paginates the ArcGIS feature service
+	// directly, is unaffected by this and always targets ArcGIS.
+	featureSource FeatureSource
+
+	eventMu     sync.Mutex
+	eventSubs   map[uint64]chan *LRSEvent
+	eventSubSeq uint64
+}
+
+// LRSRouteRepositoryOption configures optional behavior of
+// LRSRouteRepository, keeping per-source credentials, endpoints, and
+// auth out of the repository core.
+type LRSRouteRepositoryOption func(*LRSRouteRepository)
+
+// WithFeatureSource sets the FeatureSource Sync fetches features from,
+// in place of the default ArcGIS REST source.
+func WithFeatureSource(src FeatureSource) LRSRouteRepositoryOption {
+	return func(r *LRSRouteRepository) {
+		r.featureSource = src
+	}
 }
 
-func NewLRSRouteRepository(connector *duckdb.Connector, pgConnStr string, db *sql.DB) *LRSRouteRepository {
-	return &LRSRouteRepository{
+// HTTPPolicy configures the per-call deadline and retry/backoff behavior
+// shared by every ArcGIS HTTP call (token, count, feature page), in
+// place of the hard-coded client timeouts and retry constants each call
+// site used to carry individually. A zero field falls back to its
+// package default -- see resolvedHTTPPolicy. Set repo-wide via
+// WithHTTPPolicy, or pass one to FetchArcGISFeatures to override it for
+// that call alone.
+type HTTPPolicy struct {
+	// Timeout bounds a single HTTP round trip. Defaults to
+	// defaultArcGISHTTPTimeout.
+	Timeout time.Duration
+
+	// MaxRetries bounds how many attempts withArcGISRetry/
+	// withArcGISTokenRetry make before giving up. Defaults to
+	// defaultArcGISMaxRetries.
+	MaxRetries int
+
+	// BaseDelay is the first backoff wait, doubling each subsequent
+	// attempt before jitter is applied. Defaults to
+	// defaultArcGISRetryBaseDelay.
+	BaseDelay time.Duration
+
+	// Jitter is the max fraction of the computed backoff delay randomly
+	// added or subtracted, so concurrent callers don't retry in
+	// lockstep. Defaults to defaultArcGISRetryJitter; set a negative
+	// value to disable jitter entirely.
+	Jitter float64
+}
+
+// WithHTTPPolicy sets the deadline and retry/backoff behavior for every
+// ArcGIS HTTP call this repository makes, in place of the package
+// defaults.
+func WithHTTPPolicy(policy HTTPPolicy) LRSRouteRepositoryOption {
+	return func(r *LRSRouteRepository) {
+		r.arcgisHTTPTimeout = policy.Timeout
+		r.arcgisMaxRetries = policy.MaxRetries
+		r.arcgisRetryBaseDelay = policy.BaseDelay
+		r.arcgisRetryJitter = policy.Jitter
+	}
+}
+
+func NewLRSRouteRepository(connector *duckdb.Connector, pgConnStr string, db *sql.DB, opts ...LRSRouteRepositoryOption) *LRSRouteRepository {
+	r := &LRSRouteRepository{
 		connector:         connector,
 		pgConnStr:         pgConnStr,
 		db:                db,
 		tokenURL:          "https://gisportal.binamarga.pu.go.id/portal/sharing/rest/generateToken",
 		featureServiceURL: "https://gisportal.binamarga.pu.go.id/arcgis/rest/services/Jalan/BinaMargaLRS/MapServer/0/query",
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.featureSource == nil {
+		r.featureSource = &arcgisFeatureSource{repo: r}
+	}
+	return r
 }
 
 // SyncOptions contains options for syncing LRS data
 type SyncOptions struct {
 	Author    string
 	CommitMsg string
+
+	// Progress reports feature-processing progress from SyncFromGeoJSON.
+	// Defaults to progress.NoOp when left nil.
+	Progress progress.Progress
+
+	// FetchProgress, if set, reports SyncAll's ArcGIS pagination progress
+	// and the write-parquet/commit-catalog phases every Sync entry point
+	// shares via mergeWithExisting. Left nil, no updates are reported.
+	FetchProgress func(ProgressUpdate)
+
+	// DryRun, honored only by Import, validates an archive's manifest
+	// against its contents without extracting files or writing to
+	// lrs_catalogs.
+	DryRun bool
+
+	// Summary, if set, is populated with a per-phase timing breakdown of
+	// the call -- a post-hoc complement to FetchProgress's streaming
+	// updates, for callers that just want "how long did each stage
+	// take" once Sync/SyncAll/SyncFromGeoJSON returns.
+	Summary *SyncSummary
+
+	// Mode controls how mergeWithExisting treats routes already present
+	// in the previous catalog version. Defaults to SyncModeFull. See
+	// SyncMode.
+	Mode SyncMode
+
+	// Report, if set, is populated with a per-route Added/Modified/
+	// Unchanged/Removed breakdown of the merge -- mirrors Summary's
+	// pointer-output pattern. Only populated when Mode is
+	// SyncModeChangedOnly or SyncModeDryRun; left untouched under
+	// SyncModeFull.
+	Report *SyncReport
 }
 
-// Sync fetches data from ArcGIS, processes it into Parquet files, and updates the Postgres catalog.
-func (r *LRSRouteRepository) Sync(ctx context.Context, routeIDs []string, opts SyncOptions) error {
-	// 1. Generate ArcGIS Token
-	token, err := r.GenerateArcGISToken(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to generate arcgis token: %w", err)
+// emitProgress reports update via opts.FetchProgress, a no-op if unset.
+func emitProgress(opts SyncOptions, update ProgressUpdate) {
+	if opts.FetchProgress != nil {
+		opts.FetchProgress(update)
 	}
+}
 
-	// 2. Fetch GeoJSON from ArcGIS
-	geoJSON, err := r.FetchArcGISFeatures(ctx, token, routeIDs)
+// Sync fetches data from r.featureSource (ArcGIS REST by default; see
+// WithFeatureSource), processes it into Parquet files, and updates the
+// Postgres catalog.
+func (r *LRSRouteRepository) Sync(ctx context.Context, routeIDs []string, opts SyncOptions) error {
+	fetchStart := time.Now()
+	geoJSON, err := r.featureSource.FetchFeatures(ctx, routeIDs)
+	recordSummaryPhase(opts, SyncPhaseFetch, time.Since(fetchStart))
 	if err != nil {
-		return fmt.Errorf("failed to fetch arcgis features: %w", err)
+		return fmt.Errorf("failed to fetch features: %w", err)
 	}
+	emitProgress(opts, ProgressUpdate{Phase: SyncPhaseFetch})
 
 	return r.SyncFromGeoJSON(ctx, geoJSON, opts)
 }
@@ -70,24 +203,62 @@ func (r *LRSRouteRepository) SyncFromGeoJSON(ctx context.Context, geoJSON []byte
 	// The total count of features included in the ESRI JSON
 	featuresCount := len(jsonContent["features"].([]any))
 
-	// Create LRSBatch
-	lrsBatch := LRSRouteBatch{
-		latitudeCol:  "LAT",
-		longitudeCol: "LON",
+	prog := opts.Progress
+	if prog == nil {
+		prog = progress.NoOp
 	}
+	prog.Start(int64(featuresCount))
+
+	// Create LRSBatch
+	lrsBatch := NewLRSRouteBatch()
+	lrsBatch.latitudeCol = "LAT"
+	lrsBatch.longitudeCol = "LON"
 	defer lrsBatch.Release()
 
+	parseStart := time.Now()
 	for idx := range featuresCount {
-		lrsRoute := NewLRSRouteFromESRIGeoJSON(geoJSON, idx, geom.LAMBERT_WKT)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		lrsRoute, err := NewLRSRouteFromESRIGeoJSON(geoJSON, idx, geom.LAMBERT_WKT)
+		if err != nil {
+			return fmt.Errorf("failed to parse feature %d: %w", idx, err)
+		}
 		defer lrsRoute.Release()
 
 		lrsBatch.AddRoute(lrsRoute)
+		prog.Add(1)
+
+		emitProgress(opts, ProgressUpdate{
+			Phase:           SyncPhaseParseFeatures,
+			FetchedFeatures: idx + 1,
+			TotalFeatures:   featuresCount,
+		})
 	}
+	recordSummaryPhase(opts, SyncPhaseParseFeatures, time.Since(parseStart))
+
+	prog.Finish()
 
-	return r.mergeWithExisting(ctx, &lrsBatch, opts)
+	return r.mergeWithExisting(ctx, lrsBatch, opts)
 }
 
-func (r *LRSRouteRepository) mergeWithExisting(ctx context.Context, lrsBatch *LRSRouteBatch, opts SyncOptions) error {
+func (r *LRSRouteRepository) mergeWithExisting(ctx context.Context, lrsBatch *LRSRouteBatch, opts SyncOptions) (err error) {
+	emitProgress(opts, ProgressUpdate{Phase: SyncPhaseWriteParquet})
+
+	// staged collects every parquet file this call writes, so that any
+	// error path below -- a COPY failure, a cancelled ctx, or the
+	// catalog commit itself failing -- can remove the half-written
+	// merge instead of leaving orphaned files in dataDir.
+	var staged []string
+	defer func() {
+		if err != nil {
+			for _, f := range staged {
+				os.Remove(f)
+			}
+		}
+	}()
+
 	// DuckDB Processing
 	conn, err := r.connector.Connect(ctx)
 	if err != nil {
@@ -101,18 +272,24 @@ func (r *LRSRouteRepository) mergeWithExisting(ctx context.Context, lrsBatch *LR
 	}
 
 	// Paths for parquet files
-	dataDir := os.Getenv("LRS_DATA_DIR")
-	if dataDir == "" {
-		dataDir = "./data"
+	dataDir, err := lrsDataDir()
+	if err != nil {
+		return err
 	}
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		return fmt.Errorf("failed to create data dir: %w", err)
+	stagingDir, err := lrsStagingDir(dataDir)
+	if err != nil {
+		return err
 	}
 
 	nanoStamp := time.Now().UnixNano()
-	mergedPointFile := filepath.Join(dataDir, fmt.Sprintf("lrs_point_merged_%d.parquet", nanoStamp))
-	mergedSegmentFile := filepath.Join(dataDir, fmt.Sprintf("lrs_segment_merged_%d.parquet", nanoStamp))
-	mergedLinestringFile := filepath.Join(dataDir, fmt.Sprintf("lrs_linestring_merged_%d.parquet", nanoStamp))
+	finalFiles := stagedFileSet{
+		Point:      filepath.Join(dataDir, fmt.Sprintf("lrs_point_merged_%d.parquet", nanoStamp)),
+		Segment:    filepath.Join(dataDir, fmt.Sprintf("lrs_segment_merged_%d.parquet", nanoStamp)),
+		LineString: filepath.Join(dataDir, fmt.Sprintf("lrs_linestring_merged_%d.parquet", nanoStamp)),
+	}
+	mergedPointFile := filepath.Join(stagingDir, fmt.Sprintf("lrs_point_merged_%d.parquet", nanoStamp))
+	mergedSegmentFile := filepath.Join(stagingDir, fmt.Sprintf("lrs_segment_merged_%d.parquet", nanoStamp))
+	mergedLinestringFile := filepath.Join(stagingDir, fmt.Sprintf("lrs_linestring_merged_%d.parquet", nanoStamp))
 
 	// Get latest merged files to merge with
 	latestLRS, err := r.GetLatest(ctx, "")
@@ -141,10 +318,34 @@ func (r *LRSRouteRepository) mergeWithExisting(ctx context.Context, lrsBatch *LR
 		}
 	}
 
+	// Diff the incoming batch against the previous catalog version under
+	// SyncModeChangedOnly/SyncModeDryRun. changedIDs defaults to every
+	// route in the batch (SyncModeFull's behavior, unchanged from
+	// before); report is only populated, and DryRun only returns early,
+	// when a mode opts into it.
+	currentIDs := lrsBatch.RouteIDs()
+	changedIDs := currentIDs
+	if opts.Mode != SyncModeFull {
+		report, changed, err := r.diffRouteGeometry(ctx, currentIDs, lrsBatch.ViewName(), prevPointFile, hasPrev)
+		if err != nil {
+			return fmt.Errorf("failed to diff route geometry: %w", err)
+		}
+		changedIDs = changed
+		if opts.Report != nil {
+			*opts.Report = report
+		}
+		if opts.Mode == SyncModeDryRun {
+			return nil
+		}
+	}
+
 	// 1. Merge Points
 	// Logic: Union (Previous - CurrentRoute) + CurrentRoute
 	var queryPoint string
-	if hasPrev {
+	var queryPointArgs []any
+	if opts.Mode == SyncModeChangedOnly && hasPrev {
+		queryPoint, queryPointArgs = mergeChangedQuery(prevPointFile, lrsBatch.ViewName(), changedIDs)
+	} else if hasPrev {
 		queryPoint = fmt.Sprintf(`
 			SELECT * FROM '%s' WHERE ROUTEID NOT IN (SELECT DISTINCT(ROUTEID) FROM %s)
 			UNION ALL
@@ -161,17 +362,28 @@ func (r *LRSRouteRepository) mergeWithExisting(ctx context.Context, lrsBatch *LR
 		copyPointSQL = fmt.Sprintf("COPY (%s) TO '%s' (FORMAT PARQUET)", queryPoint, mergedPointFile)
 	}
 
-	_, err = r.db.ExecContext(ctx, copyPointSQL)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	mergePointsStart := time.Now()
+	res, err := r.db.ExecContext(ctx, copyPointSQL, queryPointArgs...)
 	if err != nil {
 		return fmt.Errorf("failed to export merged point parquet: %w", err)
 	}
+	staged = append(staged, mergedPointFile)
+	rows, _ := res.RowsAffected()
+	recordSummaryPhase(opts, SyncPhaseMergePoints, time.Since(mergePointsStart))
+	emitProgress(opts, ProgressUpdate{Phase: SyncPhaseMergePoints, RowsWritten: rows})
 
 	// 2. Merge Segments
 	// Current route segment query
 	currentSegmentQuery := fmt.Sprintf(`SELECT * FROM (%s)`, lrsBatch.SegmentQuery())
 
 	var querySegment string
-	if hasPrev && prevSegmentFile != "" {
+	var querySegmentArgs []any
+	if opts.Mode == SyncModeChangedOnly && hasPrev && prevSegmentFile != "" {
+		querySegment, querySegmentArgs = mergeChangedQuery(prevSegmentFile, currentSegmentQuery, changedIDs)
+	} else if hasPrev && prevSegmentFile != "" {
 		querySegment = fmt.Sprintf(`
 			SELECT * FROM '%s' WHERE ROUTEID NOT IN (SELECT DISTINCT(ROUTEID) FROM (%s))
 			UNION ALL
@@ -185,10 +397,18 @@ func (r *LRSRouteRepository) mergeWithExisting(ctx context.Context, lrsBatch *LR
 		copySegmentSQL = fmt.Sprintf("COPY (%s) TO '%s' (FORMAT PARQUET)", querySegment, mergedSegmentFile)
 	}
 
-	_, err = r.db.ExecContext(ctx, copySegmentSQL)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	mergeSegmentsStart := time.Now()
+	res, err = r.db.ExecContext(ctx, copySegmentSQL, querySegmentArgs...)
 	if err != nil {
 		return fmt.Errorf("failed to export merged segment parquet: %w", err)
 	}
+	staged = append(staged, mergedSegmentFile)
+	rows, _ = res.RowsAffected()
+	recordSummaryPhase(opts, SyncPhaseMergeSegments, time.Since(mergeSegmentsStart))
+	emitProgress(opts, ProgressUpdate{Phase: SyncPhaseMergeSegments, RowsWritten: rows})
 
 	// 3. Merge Linestrings
 	// Current route linestring query
@@ -196,7 +416,10 @@ func (r *LRSRouteRepository) mergeWithExisting(ctx context.Context, lrsBatch *LR
 	currentLinestrQuery := fmt.Sprintf(`SELECT * FROM (%s)`, lrsBatch.LinestringQuery())
 
 	var queryLinestr string
-	if hasPrev && prevLinestrFile != "" {
+	var queryLinestrArgs []any
+	if opts.Mode == SyncModeChangedOnly && hasPrev && prevLinestrFile != "" {
+		queryLinestr, queryLinestrArgs = mergeChangedQuery(prevLinestrFile, currentLinestrQuery, changedIDs)
+	} else if hasPrev && prevLinestrFile != "" {
 		queryLinestr = fmt.Sprintf(`
 			SELECT * FROM '%s' WHERE ROUTEID NOT IN (SELECT DISTINCT(ROUTEID) FROM (%s))
 			UNION ALL
@@ -211,27 +434,55 @@ func (r *LRSRouteRepository) mergeWithExisting(ctx context.Context, lrsBatch *LR
 		copyLinestrSQL = fmt.Sprintf("COPY (%s) TO '%s' (FORMAT PARQUET)", queryLinestr, mergedLinestringFile)
 	}
 
-	_, err = r.db.ExecContext(ctx, copyLinestrSQL)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	mergeLinestringsStart := time.Now()
+	res, err = r.db.ExecContext(ctx, copyLinestrSQL, queryLinestrArgs...)
 	if err != nil {
 		return fmt.Errorf("failed to export merged linestring parquet: %w", err)
 	}
+	staged = append(staged, mergedLinestringFile)
+	rows, _ = res.RowsAffected()
+	recordSummaryPhase(opts, SyncPhaseMergeLinestrings, time.Since(mergeLinestringsStart))
+	emitProgress(opts, ProgressUpdate{Phase: SyncPhaseMergeLinestrings, RowsWritten: rows})
 
-	// 5. Postgres Transaction
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	emitProgress(opts, ProgressUpdate{Phase: SyncPhaseCommitCatalog})
+
+	commitStart := time.Now()
+	stagedFiles := stagedFileSet{Point: mergedPointFile, Segment: mergedSegmentFile, LineString: mergedLinestringFile}
+	_, err = r.writeCatalogVersion(ctx, stagedFiles, finalFiles, opts, lrsBatch.RouteIDs())
+	recordSummaryPhase(opts, SyncPhaseCommitCatalog, time.Since(commitStart))
+	return err
+}
+
+// writeCatalogVersion attaches the catalog database, cuts a new
+// lrs_catalogs version pointing at final's files, publishes an
+// EventTypeRouteSynced per routeID plus one EventTypeCatalogVersionCreated,
+// and -- only once that transaction commits -- renames staged's files into
+// final's paths. Committing the row before publishing the files means a
+// failure partway through COPY'ing staged never touches the active
+// catalog; the two-phase handoff is the version-cut shared by
+// mergeWithExisting and Rollback. Returns the version number written.
+func (r *LRSRouteRepository) writeCatalogVersion(ctx context.Context, staged, final stagedFileSet, opts SyncOptions, routeIDs []string) (int, error) {
+	pointFile, segmentFile, linestrFile := final.Point, final.Segment, final.LineString
 	// Install and load postgres extension
 	if _, err := r.db.ExecContext(ctx, "INSTALL postgres; LOAD postgres;"); err != nil {
-		return fmt.Errorf("failed to load postgres extension: %w", err)
+		return 0, fmt.Errorf("failed to load postgres extension: %w", err)
 	}
 
 	// Attach Postgres database
-	_, err = r.db.ExecContext(ctx, fmt.Sprintf("ATTACH IF NOT EXISTS '%s' AS postgres_db (TYPE POSTGRES)", r.pgConnStr))
-	if err != nil {
-		return fmt.Errorf("failed to attach postgres: %w", err)
+	if _, err := r.db.ExecContext(ctx, fmt.Sprintf("ATTACH IF NOT EXISTS '%s' AS postgres_db (TYPE POSTGRES)", r.pgConnStr)); err != nil {
+		return 0, fmt.Errorf("failed to attach postgres: %w", err)
 	}
 
 	// Begin transaction
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback() // Rollback if not committed
 
@@ -249,38 +500,61 @@ func (r *LRSRouteRepository) mergeWithExisting(ctx context.Context, lrsBatch *LR
 	)`
 	_, err = tx.ExecContext(ctx, createTable)
 	if err != nil {
-		return fmt.Errorf("failed to create catalog table: %w", err)
+		return 0, fmt.Errorf("failed to create catalog table: %w", err)
 	}
 
 	// Update END_DATE for previous latest version if exists
 	_, err = tx.ExecContext(ctx, "UPDATE postgres_db.lrs_catalogs SET END_DATE = CURRENT_DATE WHERE END_DATE IS NULL")
 	if err != nil {
-		return fmt.Errorf("failed to update previous catalog entries: %w", err)
+		return 0, fmt.Errorf("failed to update previous catalog entries: %w", err)
 	}
 
 	// Get next version number
 	var nextVersion int
 	err = tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(VERSION), 0) + 1 FROM postgres_db.lrs_catalogs").Scan(&nextVersion)
 	if err != nil {
-		return fmt.Errorf("failed to get next version: %w", err)
+		return 0, fmt.Errorf("failed to get next version: %w", err)
 	}
 
 	// Insert catalog record
-	insertQuery := `INSERT INTO postgres_db.lrs_catalogs 
-		(VERSION, START_DATE, END_DATE, LRS_POINT_FILE, LRS_SEGMENT_FILE, LRS_LINESTR_FILE, AUTHOR, COMMIT_MSG) 
+	insertQuery := `INSERT INTO postgres_db.lrs_catalogs
+		(VERSION, START_DATE, END_DATE, LRS_POINT_FILE, LRS_SEGMENT_FILE, LRS_LINESTR_FILE, AUTHOR, COMMIT_MSG)
 		VALUES (?, CURRENT_DATE, NULL, ?, ?, ?, ?, ?)`
 
-	_, err = tx.ExecContext(ctx, insertQuery, nextVersion, mergedPointFile, mergedSegmentFile, mergedLinestringFile, opts.Author, opts.CommitMsg)
+	_, err = tx.ExecContext(ctx, insertQuery, nextVersion, pointFile, segmentFile, linestrFile, opts.Author, opts.CommitMsg)
 	if err != nil {
-		return fmt.Errorf("failed to insert catalog record: %w", err)
+		return 0, fmt.Errorf("failed to insert catalog record: %w", err)
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return nil
+	if err := staged.publish(final); err != nil {
+		return nextVersion, fmt.Errorf("catalog version %d committed but: %w", nextVersion, err)
+	}
+
+	now := time.Now()
+	for _, routeID := range routeIDs {
+		r.publish(&LRSEvent{
+			Type:      EventTypeRouteSynced,
+			RouteID:   routeID,
+			Version:   nextVersion,
+			Author:    opts.Author,
+			CommitMsg: opts.CommitMsg,
+			Timestamp: now,
+		})
+	}
+	r.publish(&LRSEvent{
+		Type:      EventTypeCatalogVersionCreated,
+		Version:   nextVersion,
+		Author:    opts.Author,
+		CommitMsg: opts.CommitMsg,
+		Timestamp: now,
+	})
+
+	return nextVersion, nil
 }
 
 // GetLatest retrieves the latest LRSRoute data from the catalog.
@@ -331,8 +605,66 @@ func (r *LRSRouteRepository) GetLatest(ctx context.Context, routeID string) (*LR
 	return out, nil
 }
 
-// GenerateArcGISToken generates a token for ArcGIS Portal
+// ListRouteIDs returns the distinct ROUTEIDs present in the latest active
+// catalog's point file, used by callers (e.g. Flight's ListFlights) that
+// need to discover per-route partitions without loading any route data.
+func (r *LRSRouteRepository) ListRouteIDs(ctx context.Context) ([]string, error) {
+	latest, err := r.GetLatest(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest catalog entry: %w", err)
+	}
+	pointFile := latest.GetPointFile()
+	if pointFile == nil {
+		return nil, fmt.Errorf("latest catalog entry has no point file")
+	}
+
+	conn, err := r.connector.Connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get db connection: %w", err)
+	}
+	defer conn.Close()
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`SELECT DISTINCT ROUTEID FROM read_parquet('%s')`, *pointFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query distinct route ids: %w", err)
+	}
+	defer rows.Close()
+
+	var routeIDs []string
+	for rows.Next() {
+		var routeID string
+		if err := rows.Scan(&routeID); err != nil {
+			return nil, fmt.Errorf("failed to scan route id: %w", err)
+		}
+		routeIDs = append(routeIDs, routeID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating route ids: %w", err)
+	}
+
+	return routeIDs, nil
+}
+
+// GenerateArcGISToken generates a token for ArcGIS Portal, retrying
+// transient failures (429, 5xx, connection errors) with exponential
+// backoff via withArcGISRetry.
 func (r *LRSRouteRepository) GenerateArcGISToken(ctx context.Context) (string, error) {
+	var token string
+	err := r.withArcGISRetry(ctx, "generate arcgis token", func() error {
+		t, err := r.generateArcGISTokenOnce(ctx)
+		if err != nil {
+			return err
+		}
+		token = t
+		return nil
+	})
+	return token, err
+}
+
+// generateArcGISTokenOnce makes a single token request, the unit
+// withArcGISRetry retries. It must build the request fresh each call:
+// the POST body reader is consumed on use.
+func (r *LRSRouteRepository) generateArcGISTokenOnce(ctx context.Context) (string, error) {
 	username := os.Getenv("ARCGIS_USER")
 	password := os.Getenv("ARCGIS_PASSWORD")
 
@@ -351,15 +683,18 @@ func (r *LRSRouteRepository) GenerateArcGISToken(ctx context.Context) (string, e
 	}
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := r.httpClient(r.resolvedHTTPPolicy(nil)).Do(req)
 	if err != nil {
-		return "", err
+		return "", retryable(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("token request failed with status: %d", resp.StatusCode)
+		err := fmt.Errorf("token request failed with status: %d", resp.StatusCode)
+		if isRetryableStatus(resp.StatusCode) {
+			return "", retryable(err)
+		}
+		return "", err
 	}
 
 	var result struct {
@@ -381,48 +716,79 @@ func (r *LRSRouteRepository) GenerateArcGISToken(ctx context.Context) (string, e
 	return result.Token, nil
 }
 
-// FetchArcGISFeatures fetches GeoJSON features for the given route IDs
-func (r *LRSRouteRepository) FetchArcGISFeatures(ctx context.Context, token string, routeIDs []string) ([]byte, error) {
-	// Construct WHERE clause: RouteId IN ('id1', 'id2', ...)
-	var where string
-	if len(routeIDs) == 1 {
-		where = fmt.Sprintf("RouteId='%s'", routeIDs[0])
-	} else if len(routeIDs) > 1 {
-		quotedIDs := make([]string, len(routeIDs))
-		for i, id := range routeIDs {
-			quotedIDs[i] = fmt.Sprintf("'%s'", id)
+// FetchArcGISFeatures fetches every feature matching routeIDs (every
+// feature, if routeIDs is empty), paginating via resultOffset/
+// resultRecordCount so a routeIDs set larger than the service's
+// maxRecordCount isn't silently truncated. Transient failures (429,
+// 5xx, connection errors) are retried with exponential backoff, and a
+// response reporting an invalid/expired token (498/499) regenerates one
+// mid-fetch -- both via withArcGISTokenRetry. forceTokenRefresh
+// regenerates token before the first request instead of trusting the
+// caller's copy. policy overrides the repository's configured
+// HTTPPolicy for this call alone; pass nil to use it unmodified.
+func (r *LRSRouteRepository) FetchArcGISFeatures(ctx context.Context, token string, routeIDs []string, forceTokenRefresh bool, policy *HTTPPolicy) ([]byte, error) {
+	resolved := r.resolvedHTTPPolicy(policy)
+	where := arcgisRouteIDsWhereClause(routeIDs)
+
+	tok := token
+	if forceTokenRefresh {
+		fresh, err := r.GenerateArcGISToken(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to refresh arcgis token: %w", err)
 		}
-		where = fmt.Sprintf("RouteId IN (%s)", strings.Join(quotedIDs, ","))
-	} else {
-		where = "1=1"
+		tok = fresh
 	}
 
-	params := url.Values{}
-	params.Set("where", where)
-	params.Set("outfields", "LINKID,LINK_NAME,SK_LENGTH") // Including necessary fields for LRSRoute
-	params.Set("f", "json")
-	params.Set("token", token)
-	params.Set("returnGeometry", "true")
-	params.Set("returnM", "true")
-	params.Set("returnZ", "true")
-
-	fullURL := fmt.Sprintf("%s?%s", r.featureServiceURL, params.Encode())
-
-	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
+	var total int
+	err := r.withArcGISTokenRetry(ctx, "count arcgis features", resolved, &tok, func(t string) error {
+		c, err := r.fetchArcGISFeatureCountOnce(ctx, t, where, resolved)
+		if err != nil {
+			return err
+		}
+		total = c
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to count arcgis features: %w", err)
 	}
 
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	limit := r.arcgisFetchLimit
+	if limit <= 0 {
+		limit = defaultArcGISFetchLimit
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("feature request failed with status: %d", resp.StatusCode)
+	features := []json.RawMessage{}
+	var spatialRef json.RawMessage
+	for offset := 0; offset < total; offset += limit {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var page []byte
+		err := r.withArcGISTokenRetry(ctx, "fetch arcgis features page", resolved, &tok, func(t string) error {
+			p, err := r.fetchArcGISFeaturesPageOnce(ctx, t, where, offset, limit, resolved)
+			if err != nil {
+				return err
+			}
+			page = p
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch arcgis features at offset %d: %w", offset, err)
+		}
+
+		var parsed struct {
+			SpatialReference json.RawMessage   `json:"spatialReference"`
+			Features         []json.RawMessage `json:"features"`
+		}
+		if err := json.Unmarshal(page, &parsed); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal arcgis page at offset %d: %w", offset, err)
+		}
+		if spatialRef == nil {
+			spatialRef = parsed.SpatialReference
+		}
+		features = append(features, parsed.Features...)
 	}
 
-	return io.ReadAll(resp.Body)
+	return json.Marshal(map[string]any{"spatialReference": spatialRef, "features": features})
 }
@@ -0,0 +1,203 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// defaultArcGISFetchLimit is SyncAll's page size when arcgisFetchLimit is
+// left at its zero value.
+const defaultArcGISFetchLimit = 500
+
+// SyncPhase identifies which stage of a sync a ProgressUpdate describes.
+type SyncPhase string
+
+const (
+	// SyncPhaseToken reports SyncAll acquiring an ArcGIS token before it
+	// can count or page through features.
+	SyncPhaseToken SyncPhase = "token"
+
+	// SyncPhaseFetch reports SyncAll paginating the ArcGIS feature
+	// service, one update per page.
+	SyncPhaseFetch SyncPhase = "fetch"
+
+	// SyncPhaseParseFeatures reports SyncFromGeoJSON parsing the fetched
+	// GeoJSON into an LRSRouteBatch, one route at a time.
+	SyncPhaseParseFeatures SyncPhase = "parse-features"
+
+	// SyncPhaseWriteParquet reports mergeWithExisting writing the merged
+	// point/segment/linestring parquet files. Superseded in granularity
+	// by SyncPhaseMergePoints/SyncPhaseMergeSegments/
+	// SyncPhaseMergeLinestrings but still emitted once at the start of
+	// the phase for callers that only distinguish write-parquet from
+	// commit-catalog.
+	SyncPhaseWriteParquet SyncPhase = "write-parquet"
+
+	// SyncPhaseMergePoints reports mergeWithExisting's point parquet
+	// COPY completing, with RowsWritten set from the COPY's row count.
+	SyncPhaseMergePoints SyncPhase = "merge-points"
+
+	// SyncPhaseMergeSegments reports mergeWithExisting's segment parquet
+	// COPY completing, with RowsWritten set from the COPY's row count.
+	SyncPhaseMergeSegments SyncPhase = "merge-segments"
+
+	// SyncPhaseMergeLinestrings reports mergeWithExisting's linestring
+	// parquet COPY completing, with RowsWritten set from the COPY's row
+	// count.
+	SyncPhaseMergeLinestrings SyncPhase = "merge-linestrings"
+
+	// SyncPhaseCommitCatalog reports mergeWithExisting's version-cut
+	// write to lrs_catalogs.
+	SyncPhaseCommitCatalog SyncPhase = "commit-catalog"
+)
+
+// ProgressUpdate reports progress from SyncAll and the
+// parse-features/write-parquet/merge-*/commit-catalog phases every Sync
+// entry point shares via SyncFromGeoJSON and mergeWithExisting, via
+// SyncOptions.FetchProgress. Fields that don't apply to Phase are left
+// zero -- FetchedFeatures/TotalFeatures/CurrentOffset/BytesDownloaded
+// only carry meaningful values during SyncPhaseFetch and
+// SyncPhaseParseFeatures (as FetchedFeatures/TotalFeatures); RowsWritten
+// is only set by the merge-points/merge-segments/merge-linestrings
+// phases.
+type ProgressUpdate struct {
+	Phase           SyncPhase
+	FetchedFeatures int
+	TotalFeatures   int
+	CurrentOffset   int
+	BytesDownloaded int64
+	RowsWritten     int64
+}
+
+// SyncSummary reports how long each phase of a Sync/SyncAll call took,
+// for callers that want a post-hoc timing breakdown rather than (or in
+// addition to) streaming ProgressUpdates. Populate SyncOptions.Summary
+// to receive one.
+type SyncSummary struct {
+	PhaseDurations map[SyncPhase]time.Duration
+	TotalDuration  time.Duration
+}
+
+// recordSummaryPhase adds d to opts.Summary's running total for phase, a
+// no-op when opts.Summary is nil.
+func recordSummaryPhase(opts SyncOptions, phase SyncPhase, d time.Duration) {
+	if opts.Summary == nil {
+		return
+	}
+	if opts.Summary.PhaseDurations == nil {
+		opts.Summary.PhaseDurations = make(map[SyncPhase]time.Duration)
+	}
+	opts.Summary.PhaseDurations[phase] += d
+	opts.Summary.TotalDuration += d
+}
+
+// SyncAll paginates through every feature in the ArcGIS feature service,
+// with no RouteId filter, and merges the full result into a single new
+// catalog version -- unlike Sync, which only fetches the RouteIDs the
+// caller names. ctx cancellation is honored between pages: SyncAll
+// returns ctx.Err() before fetching the next page, truncating cleanly
+// without writing any parquet output or lrs_catalogs row for a partial
+// fetch.
+func (r *LRSRouteRepository) SyncAll(ctx context.Context, opts SyncOptions) error {
+	policy := r.resolvedHTTPPolicy(nil)
+
+	tokenStart := time.Now()
+	token, err := r.GenerateArcGISToken(ctx)
+	recordSummaryPhase(opts, SyncPhaseToken, time.Since(tokenStart))
+	if err != nil {
+		return fmt.Errorf("failed to generate arcgis token: %w", err)
+	}
+
+	total, err := r.fetchArcGISFeatureCount(ctx, &token, policy)
+	if err != nil {
+		return fmt.Errorf("failed to count arcgis features: %w", err)
+	}
+
+	limit := r.arcgisFetchLimit
+	if limit <= 0 {
+		limit = defaultArcGISFetchLimit
+	}
+
+	var features []json.RawMessage
+	var spatialRef json.RawMessage
+	var bytesDownloaded int64
+	fetchStart := time.Now()
+	for offset := 0; offset < total; offset += limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		page, err := r.fetchArcGISFeaturesPage(ctx, &token, offset, limit, policy)
+		if err != nil {
+			return fmt.Errorf("failed to fetch arcgis features at offset %d: %w", offset, err)
+		}
+		bytesDownloaded += int64(len(page))
+
+		var parsed struct {
+			SpatialReference json.RawMessage   `json:"spatialReference"`
+			Features         []json.RawMessage `json:"features"`
+		}
+		if err := json.Unmarshal(page, &parsed); err != nil {
+			return fmt.Errorf("failed to unmarshal arcgis page at offset %d: %w", offset, err)
+		}
+		if spatialRef == nil {
+			spatialRef = parsed.SpatialReference
+		}
+		features = append(features, parsed.Features...)
+
+		emitProgress(opts, ProgressUpdate{
+			Phase:           SyncPhaseFetch,
+			FetchedFeatures: len(features),
+			TotalFeatures:   total,
+			CurrentOffset:   offset + len(parsed.Features),
+			BytesDownloaded: bytesDownloaded,
+		})
+	}
+	recordSummaryPhase(opts, SyncPhaseFetch, time.Since(fetchStart))
+
+	merged, err := json.Marshal(map[string]any{"spatialReference": spatialRef, "features": features})
+	if err != nil {
+		return fmt.Errorf("failed to marshal merged arcgis features: %w", err)
+	}
+
+	return r.SyncFromGeoJSON(ctx, merged, opts)
+}
+
+// fetchArcGISFeatureCount asks the feature service for its total feature
+// count via returnCountOnly, used by SyncAll to know when pagination is
+// done. Transient failures (429, 5xx, connection errors) are retried
+// with exponential backoff, and an invalid/expired token regenerates one
+// mid-count -- both via withArcGISTokenRetry, which may update *token.
+func (r *LRSRouteRepository) fetchArcGISFeatureCount(ctx context.Context, token *string, policy HTTPPolicy) (int, error) {
+	var count int
+	err := r.withArcGISTokenRetry(ctx, "count arcgis features", policy, token, func(t string) error {
+		c, err := r.fetchArcGISFeatureCountOnce(ctx, t, "1=1", policy)
+		if err != nil {
+			return err
+		}
+		count = c
+		return nil
+	})
+	return count, err
+}
+
+// fetchArcGISFeaturesPage fetches a single page of every feature (no
+// RouteId filter), starting at offset and asking for at most limit
+// features, used by SyncAll to paginate the whole feature service.
+// Transient failures (429, 5xx, connection errors) are retried with
+// exponential backoff, and an invalid/expired token regenerates one
+// mid-fetch -- both via withArcGISTokenRetry, which may update *token.
+func (r *LRSRouteRepository) fetchArcGISFeaturesPage(ctx context.Context, token *string, offset, limit int, policy HTTPPolicy) ([]byte, error) {
+	var page []byte
+	err := r.withArcGISTokenRetry(ctx, "fetch arcgis features page", policy, token, func(t string) error {
+		p, err := r.fetchArcGISFeaturesPageOnce(ctx, t, "1=1", offset, limit, policy)
+		if err != nil {
+			return err
+		}
+		page = p
+		return nil
+	})
+	return page, err
+}
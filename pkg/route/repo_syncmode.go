@@ -0,0 +1,179 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SyncMode controls how mergeWithExisting treats routes that already
+// exist in the previous catalog version. Defaults to SyncModeFull, which
+// merges every route in the incoming batch into the new parquet files
+// the same way mergeWithExisting always has.
+type SyncMode string
+
+const (
+	// SyncModeFull merges every route in the incoming batch into the new
+	// parquet files, regardless of whether its geometry changed.
+	SyncModeFull SyncMode = ""
+
+	// SyncModeChangedOnly skips re-copying routes whose geometry hash
+	// matches the previous catalog version's. Unchanged routes are still
+	// present in the merged output -- carried over from the previous
+	// file's rows rather than re-derived from the incoming batch -- but
+	// SyncReport reports them as Unchanged rather than Modified.
+	SyncModeChangedOnly SyncMode = "changed-only"
+
+	// SyncModeDryRun computes the same SyncReport as SyncModeChangedOnly
+	// but writes no parquet files and opens no Postgres transaction,
+	// leaving the active catalog version untouched.
+	SyncModeDryRun SyncMode = "dry-run"
+)
+
+// SyncReport categorizes every route in a Sync/SyncAll/SyncFromGeoJSON
+// call's incoming batch against the previous catalog version, populated
+// by mergeWithExisting when SyncOptions.Mode is SyncModeChangedOnly or
+// SyncModeDryRun and mirrored into SyncOptions.Report. Removed is always
+// empty today -- mergeWithExisting only ever sees the routes the
+// incoming batch actually carries, not ones that disappeared from the
+// upstream source entirely.
+type SyncReport struct {
+	Added     []string
+	Modified  []string
+	Unchanged []string
+	Removed   []string
+}
+
+// routeGeometryHash is the per-route fingerprint diffRouteGeometry
+// compares between the incoming batch and the previous catalog version:
+// vertex count plus a WKB checksum, so a route whose points moved
+// without changing count still shows up as Modified.
+type routeGeometryHash struct {
+	VertexCount int64
+	Checksum    string
+}
+
+// routeGeometryHashes computes one routeGeometryHash per ROUTEID in
+// routeIDs from source, a point-table query or file path producing
+// ROUTEID/LAT/LON/VERTEX_SEQ rows (lrsBatch.ViewName() or a previous
+// merged point parquet path). fromFile wraps source as a file literal;
+// otherwise it's used as a DuckDB query/view expression verbatim.
+func (r *LRSRouteRepository) routeGeometryHashes(ctx context.Context, source string, fromFile bool, routeIDs []string) (map[string]routeGeometryHash, error) {
+	hashes := make(map[string]routeGeometryHash, len(routeIDs))
+	if len(routeIDs) == 0 {
+		return hashes, nil
+	}
+
+	from := source
+	if fromFile {
+		from = fmt.Sprintf("'%s'", source)
+	}
+	query := fmt.Sprintf(`
+		SELECT ROUTEID, COUNT(*) AS VERTEX_COUNT,
+			MD5(STRING_AGG(TO_HEX(ST_AsWKB(ST_Point(LON, LAT))), ',' ORDER BY VERTEX_SEQ)) AS GEOM_HASH
+		FROM %s
+		WHERE ROUTEID IN (%s)
+		GROUP BY ROUTEID
+	`, from, placeholderList(len(routeIDs)))
+
+	rows, err := r.db.QueryContext(ctx, query, routeIDArgs(routeIDs)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash route geometry: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var routeID, checksum string
+		var count int64
+		if err := rows.Scan(&routeID, &count, &checksum); err != nil {
+			return nil, fmt.Errorf("failed to scan route geometry hash: %w", err)
+		}
+		hashes[routeID] = routeGeometryHash{VertexCount: count, Checksum: checksum}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating route geometry hashes: %w", err)
+	}
+	return hashes, nil
+}
+
+// diffRouteGeometry categorizes every route in currentIDs as Added,
+// Modified, or Unchanged by comparing its geometry hash in currentQuery
+// against its hash in the previous catalog version's point file (skipped
+// entirely, everything Added, when hasPrev is false). changed is every
+// route mergeWithExisting still needs to copy from the incoming batch --
+// Added and Modified routes; Unchanged routes are not copied from the
+// incoming batch -- mergeChangedQuery carries their rows over from the
+// previous file into the new merged files instead.
+func (r *LRSRouteRepository) diffRouteGeometry(ctx context.Context, currentIDs []string, currentQuery, prevPointFile string, hasPrev bool) (SyncReport, []string, error) {
+	var report SyncReport
+	if !hasPrev {
+		report.Added = currentIDs
+		return report, currentIDs, nil
+	}
+
+	current, err := r.routeGeometryHashes(ctx, currentQuery, false, currentIDs)
+	if err != nil {
+		return report, nil, err
+	}
+	previous, err := r.routeGeometryHashes(ctx, prevPointFile, true, currentIDs)
+	if err != nil {
+		return report, nil, err
+	}
+
+	changed := make([]string, 0, len(currentIDs))
+	for _, routeID := range currentIDs {
+		prev, existed := previous[routeID]
+		if !existed {
+			report.Added = append(report.Added, routeID)
+			changed = append(changed, routeID)
+			continue
+		}
+		if current[routeID] != prev {
+			report.Modified = append(report.Modified, routeID)
+			changed = append(changed, routeID)
+			continue
+		}
+		report.Unchanged = append(report.Unchanged, routeID)
+	}
+	return report, changed, nil
+}
+
+// mergeChangedQuery builds the UNION ALL query SyncModeChangedOnly COPYs
+// into a merged parquet file: every row from prevFile whose ROUTEID
+// isn't one of changedIDs (unchanged routes, carried over untouched
+// rather than re-copied from currentQuery), plus every row from
+// currentQuery belonging to changedIDs. An empty changedIDs (every route
+// in the batch came back Unchanged) collapses to prevFile verbatim.
+// changedIDs appears twice in the returned query (the NOT IN and IN
+// clauses), so the returned args repeat it twice in the same order --
+// callers must pass args to the COPY/ExecContext call that embeds query
+// verbatim, the same way routeGeometryHashes does.
+func mergeChangedQuery(prevFile, currentQuery string, changedIDs []string) (string, []any) {
+	if len(changedIDs) == 0 {
+		return fmt.Sprintf(`SELECT * FROM '%s'`, prevFile), nil
+	}
+	placeholders := placeholderList(len(changedIDs))
+	query := fmt.Sprintf(`
+		SELECT * FROM '%s' WHERE ROUTEID NOT IN (%s)
+		UNION ALL
+		SELECT * FROM (%s) WHERE ROUTEID IN (%s)
+	`, prevFile, placeholders, currentQuery, placeholders)
+	args := append(routeIDArgs(changedIDs), routeIDArgs(changedIDs)...)
+	return query, args
+}
+
+// placeholderList renders n comma-separated "?" placeholders for a
+// parameterized IN clause.
+func placeholderList(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// routeIDArgs converts routeIDs into the []any QueryContext/ExecContext
+// expect for a placeholderList's "?" arguments.
+func routeIDArgs(routeIDs []string) []any {
+	args := make([]any, len(routeIDs))
+	for i, id := range routeIDs {
+		args[i] = id
+	}
+	return args
+}
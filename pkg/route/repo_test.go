@@ -3,12 +3,14 @@ package route
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -183,6 +185,55 @@ func TestSyncFromGeoJSON(t *testing.T) {
 			}
 		})
 
+		t.Run("rollback and diff across versions", func(t *testing.T) {
+			// Shift the first vertex's longitude and resync 01001, giving
+			// us a third catalog version with a known, isolated change.
+			var geoJSON map[string]any
+			if err := json.Unmarshal(jsonBytes, &geoJSON); err != nil {
+				t.Fatalf("Failed to unmarshal test JSON: %v", err)
+			}
+			path := geoJSON["features"].([]any)[0].(map[string]any)["geometry"].(map[string]any)["paths"].([]any)[0].([]any)
+			firstVertex := path[0].([]any)
+			firstVertex[0] = firstVertex[0].(float64) + 0.01
+
+			movedJSON, err := json.Marshal(geoJSON)
+			if err != nil {
+				t.Fatalf("Failed to marshal modified test JSON: %v", err)
+			}
+
+			if err := repo.SyncFromGeoJSON(ctx, movedJSON, SyncOptions{Author: "SYSTEM", CommitMsg: "TEST3"}); err != nil {
+				t.Fatalf("Failed to resync modified route: %v", err)
+			}
+
+			diff, err := repo.Diff(ctx, "01001", 1, 3)
+			if err != nil {
+				t.Fatalf("Diff failed: %v", err)
+			}
+			if len(diff.Moved) == 0 {
+				t.Error("expected at least one moved vertex after resyncing with a shifted coordinate")
+			}
+
+			versions, err := repo.ListVersions(ctx, "01001")
+			if err != nil {
+				t.Fatalf("ListVersions failed: %v", err)
+			}
+			if len(versions) != 3 {
+				t.Errorf("expected 01001 to appear in all 3 catalog versions so far, got %d", len(versions))
+			}
+
+			if err := repo.Rollback(ctx, "01001", 1, SyncOptions{Author: "SYSTEM", CommitMsg: "ROLLBACK"}); err != nil {
+				t.Fatalf("Rollback failed: %v", err)
+			}
+
+			rolledBack, err := repo.Diff(ctx, "01001", 1, 4)
+			if err != nil {
+				t.Fatalf("Diff after rollback failed: %v", err)
+			}
+			if len(rolledBack.Added) != 0 || len(rolledBack.Removed) != 0 || len(rolledBack.Moved) != 0 {
+				t.Errorf("expected rollback to restore version 1's geometry exactly, got diff %+v", rolledBack)
+			}
+		})
+
 		// Cleanup: delete test data from postgres
 		_, err = db.ExecContext(ctx, "install postgres; load postgres;")
 		_, err = db.ExecContext(ctx, fmt.Sprintf("ATTACH '%s' AS postgres_db (TYPE POSTGRES)", repo.pgConnStr))
@@ -328,6 +379,88 @@ func TestSync(t *testing.T) {
 	db.ExecContext(ctx, "DROP TABLE IF EXISTS postgres_db.lrs_routes")
 }
 
+func TestSyncChangedOnlyReportAndDryRun(t *testing.T) {
+	// Setup DuckDB
+	connector, err := duckdb.NewConnector("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connector.Close()
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	// Initial cleanup
+	ctx := context.Background()
+	_, _ = db.ExecContext(ctx, "install postgres; load postgres;")
+	_, _ = db.ExecContext(ctx, fmt.Sprintf("ATTACH IF NOT EXISTS '%s' AS postgres_db (TYPE POSTGRES)", testPgConnStr))
+	_, _ = db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs")
+
+	// Temp dir
+	tempDir, _ := os.MkdirTemp("", "sync_changed_only_test_*")
+	defer os.RemoveAll(tempDir)
+	os.Setenv("LRS_DATA_DIR", tempDir)
+	defer os.Unsetenv("LRS_DATA_DIR")
+
+	repo := NewLRSRouteRepository(connector, testPgConnStr, db)
+
+	// First sync establishes a baseline version.
+	err = repo.Sync(ctx, []string{"01001", "01002"}, SyncOptions{Author: "TESTER", CommitMsg: "BASELINE"})
+	if err != nil {
+		t.Fatalf("baseline Sync failed: %v", err)
+	}
+
+	// Re-syncing the same routes in DryRun mode should report everything
+	// Unchanged and leave the catalog untouched.
+	var dryReport SyncReport
+	err = repo.Sync(ctx, []string{"01001", "01002"}, SyncOptions{
+		Author: "TESTER", CommitMsg: "DRY RUN", Mode: SyncModeDryRun, Report: &dryReport,
+	})
+	if err != nil {
+		t.Fatalf("dry-run Sync failed: %v", err)
+	}
+	if len(dryReport.Unchanged) != 2 || len(dryReport.Added) != 0 || len(dryReport.Modified) != 0 {
+		t.Errorf("expected all routes Unchanged, got %+v", dryReport)
+	}
+
+	var versionCount int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM postgres_db.lrs_catalogs WHERE AUTHOR = 'TESTER'").Scan(&versionCount)
+	if err != nil {
+		t.Fatalf("failed to count catalog versions: %v", err)
+	}
+	if versionCount != 1 {
+		t.Errorf("expected DryRun to leave the catalog at 1 version, got %d", versionCount)
+	}
+
+	// Syncing a new route in ChangedOnly mode should report it Added and
+	// the previously-synced routes Unchanged, while still cutting a new
+	// version.
+	var changedReport SyncReport
+	err = repo.Sync(ctx, []string{"01001", "01002", "15001"}, SyncOptions{
+		Author: "TESTER", CommitMsg: "CHANGED ONLY", Mode: SyncModeChangedOnly, Report: &changedReport,
+	})
+	if err != nil {
+		t.Fatalf("changed-only Sync failed: %v", err)
+	}
+	if len(changedReport.Added) != 1 || changedReport.Added[0] != "15001" {
+		t.Errorf("expected 15001 reported Added, got %+v", changedReport)
+	}
+	if len(changedReport.Unchanged) != 2 {
+		t.Errorf("expected 01001/01002 reported Unchanged, got %+v", changedReport)
+	}
+
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM postgres_db.lrs_catalogs WHERE AUTHOR = 'TESTER'").Scan(&versionCount)
+	if err != nil {
+		t.Fatalf("failed to count catalog versions: %v", err)
+	}
+	if versionCount != 2 {
+		t.Errorf("expected ChangedOnly to cut a new version, got %d", versionCount)
+	}
+
+	// Cleanup
+	db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs WHERE AUTHOR = 'TESTER'")
+	db.ExecContext(ctx, "DROP TABLE IF EXISTS postgres_db.lrs_routes")
+}
+
 func TestSyncAll(t *testing.T) {
 	// Setup DuckDB
 	connector, err := duckdb.NewConnector("", nil)
@@ -455,3 +588,372 @@ func TestSyncAll(t *testing.T) {
 	db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs WHERE AUTHOR = 'TESTER_ALL'")
 	db.ExecContext(ctx, "DROP TABLE IF EXISTS postgres_db.lrs_routes")
 }
+
+func TestSyncAllProgress(t *testing.T) {
+	connector, err := duckdb.NewConnector("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connector.Close()
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, _ = db.ExecContext(ctx, "install postgres; load postgres;")
+	_, _ = db.ExecContext(ctx, fmt.Sprintf("ATTACH IF NOT EXISTS '%s' AS postgres_db (TYPE POSTGRES)", testPgConnStr))
+	_, _ = db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs")
+
+	tempDir, _ := os.MkdirTemp("", "sync_all_progress_test_*")
+	defer os.RemoveAll(tempDir)
+	os.Setenv("LRS_DATA_DIR", tempDir)
+	defer os.Unsetenv("LRS_DATA_DIR")
+
+	featureCount := 500
+	limit := 250
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.Contains(r.URL.Path, "generateToken") {
+			fmt.Fprint(w, `{"token": "mock-token", "expires": 123456789}`)
+			return
+		}
+
+		if r.URL.Query().Get("returnCountOnly") == "true" {
+			fmt.Fprintf(w, `{"count": %d}`, featureCount)
+			return
+		}
+
+		offset, _ := strconv.Atoi(r.URL.Query().Get("resultOffset"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("resultRecordCount"))
+		if count == 0 {
+			count = limit
+		}
+
+		featureTpl := `{
+			"attributes": {
+				"OBJECTID": %d,
+				"RouteId": "R%d",
+				"LINKID": "L%d",
+				"LINK_NAME": "Link %d",
+				"SK_LENGTH": 100.0,
+				"LAT": 0.0,
+				"LON": 0.0,
+				"MVAL": 0.0,
+				"VERTEX_SEQ": 0
+			},
+			"geometry": {
+				"paths": [
+					[
+						[110.0, -7.0, 0, 0],
+						[110.1, -7.1, 10, 1]
+					]
+				]
+			}
+		}`
+
+		end := offset + count
+		if end > featureCount {
+			end = featureCount
+		}
+
+		var features []string
+		for i := offset; i < end; i++ {
+			features = append(features, fmt.Sprintf(featureTpl, i, i, i, i))
+		}
+
+		responseTpl := `{
+			"spatialReference": {"wkt": "GEOGCS[\"GCS_WGS_1984\",DATUM[\"D_WGS_1984\",SPHEROID[\"WGS_1984\",6378137.0,298.257223563]],PRIMEM[\"Greenwich\",0.0],UNIT[\"Degree\",0.0174532925199433]]"},
+			"features": [%s]
+		}`
+		fmt.Fprintf(w, responseTpl, strings.Join(features, ","))
+	}))
+	defer mockServer.Close()
+
+	repo := NewLRSRouteRepository(connector, testPgConnStr, db)
+	repo.tokenURL = mockServer.URL + "/generateToken"
+	repo.featureServiceURL = mockServer.URL + "/query"
+	repo.arcgisFetchLimit = limit
+
+	var updates []ProgressUpdate
+	var summary SyncSummary
+	err = repo.SyncAll(ctx, SyncOptions{
+		Author:    "TESTER_PROGRESS",
+		CommitMsg: "MOCK SYNC ALL PROGRESS",
+		FetchProgress: func(u ProgressUpdate) {
+			updates = append(updates, u)
+		},
+		Summary: &summary,
+	})
+	if err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+
+	var fetchUpdates, parseUpdates int
+	var sawWriteParquet, sawMergePoints, sawMergeSegments, sawMergeLinestrings, sawCommitCatalog bool
+	for _, u := range updates {
+		switch u.Phase {
+		case SyncPhaseFetch:
+			fetchUpdates++
+			if u.TotalFeatures != featureCount {
+				t.Errorf("expected TotalFeatures %d, got %d", featureCount, u.TotalFeatures)
+			}
+		case SyncPhaseParseFeatures:
+			parseUpdates++
+		case SyncPhaseWriteParquet:
+			sawWriteParquet = true
+		case SyncPhaseMergePoints:
+			sawMergePoints = true
+		case SyncPhaseMergeSegments:
+			sawMergeSegments = true
+		case SyncPhaseMergeLinestrings:
+			sawMergeLinestrings = true
+		case SyncPhaseCommitCatalog:
+			sawCommitCatalog = true
+		}
+	}
+
+	if fetchUpdates != featureCount/limit {
+		t.Errorf("expected %d fetch progress updates (one per page), got %d", featureCount/limit, fetchUpdates)
+	}
+	if parseUpdates != featureCount {
+		t.Errorf("expected %d parse-features progress updates (one per feature), got %d", featureCount, parseUpdates)
+	}
+	if !sawWriteParquet {
+		t.Error("expected a write-parquet phase update")
+	}
+	if !sawMergePoints || !sawMergeSegments || !sawMergeLinestrings {
+		t.Error("expected merge-points, merge-segments, and merge-linestrings phase updates")
+	}
+	if !sawCommitCatalog {
+		t.Error("expected a commit-catalog phase update")
+	}
+
+	for _, phase := range []SyncPhase{SyncPhaseToken, SyncPhaseFetch, SyncPhaseParseFeatures, SyncPhaseMergePoints, SyncPhaseMergeSegments, SyncPhaseMergeLinestrings, SyncPhaseCommitCatalog} {
+		if _, ok := summary.PhaseDurations[phase]; !ok {
+			t.Errorf("expected summary to record a duration for phase %q", phase)
+		}
+	}
+	if summary.TotalDuration <= 0 {
+		t.Error("expected a positive summary.TotalDuration")
+	}
+
+	db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs WHERE AUTHOR = 'TESTER_PROGRESS'")
+}
+
+func TestSyncAllCancellation(t *testing.T) {
+	connector, err := duckdb.NewConnector("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connector.Close()
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, _ = db.ExecContext(ctx, "install postgres; load postgres;")
+	_, _ = db.ExecContext(ctx, fmt.Sprintf("ATTACH IF NOT EXISTS '%s' AS postgres_db (TYPE POSTGRES)", testPgConnStr))
+	_, _ = db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs")
+
+	tempDir, _ := os.MkdirTemp("", "sync_all_cancel_test_*")
+	defer os.RemoveAll(tempDir)
+	os.Setenv("LRS_DATA_DIR", tempDir)
+	defer os.Unsetenv("LRS_DATA_DIR")
+
+	featureCount := 500
+	limit := 250
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(r.URL.Path, "generateToken") {
+			fmt.Fprint(w, `{"token": "mock-token", "expires": 123456789}`)
+			return
+		}
+		if r.URL.Query().Get("returnCountOnly") == "true" {
+			fmt.Fprintf(w, `{"count": %d}`, featureCount)
+			return
+		}
+		fmt.Fprint(w, `{"features": []}`)
+	}))
+	defer mockServer.Close()
+
+	repo := NewLRSRouteRepository(connector, testPgConnStr, db)
+	repo.tokenURL = mockServer.URL + "/generateToken"
+	repo.featureServiceURL = mockServer.URL + "/query"
+	repo.arcgisFetchLimit = limit
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	err = repo.SyncAll(cancelCtx, SyncOptions{Author: "TESTER_CANCEL", CommitMsg: "SHOULD NOT COMMIT"})
+	if err == nil {
+		t.Fatal("expected SyncAll to return an error for an already-cancelled context")
+	}
+
+	var count int
+	err = db.QueryRowContext(ctx, "SELECT count(*) FROM postgres_db.lrs_catalogs WHERE AUTHOR = 'TESTER_CANCEL'").Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to query catalog: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected no catalog row for a cancelled SyncAll, found %d", count)
+	}
+}
+
+func TestSyncFromGeoJSONCleansUpStagedFilesOnCancellation(t *testing.T) {
+	connector, err := duckdb.NewConnector("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connector.Close()
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, _ = db.ExecContext(ctx, "install postgres; load postgres;")
+	_, _ = db.ExecContext(ctx, fmt.Sprintf("ATTACH IF NOT EXISTS '%s' AS postgres_db (TYPE POSTGRES)", testPgConnStr))
+	_, _ = db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs")
+
+	tempDir, _ := os.MkdirTemp("", "sync_merge_cancel_test_*")
+	defer os.RemoveAll(tempDir)
+	os.Setenv("LRS_DATA_DIR", tempDir)
+	defer os.Unsetenv("LRS_DATA_DIR")
+
+	esriJSON := `{
+		"spatialReference": {"wkt": "GEOGCS[\"GCS_WGS_1984\",DATUM[\"D_WGS_1984\",SPHEROID[\"WGS_1984\",6378137.0,298.257223563]],PRIMEM[\"Greenwich\",0.0],UNIT[\"Degree\",0.0174532925199433]]"},
+		"features": [{
+			"attributes": {"OBJECTID": 1, "RouteId": "R1", "LINKID": "L1", "LINK_NAME": "Link 1", "SK_LENGTH": 100.0},
+			"geometry": {"paths": [[[110.0, -7.0, 0, 0], [110.1, -7.1, 10, 1]]]}
+		}]
+	}`
+
+	repo := NewLRSRouteRepository(connector, testPgConnStr, db)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	err = repo.SyncFromGeoJSON(cancelCtx, []byte(esriJSON), SyncOptions{
+		Author:    "TESTER_CANCEL_MERGE",
+		CommitMsg: "SHOULD NOT COMMIT",
+		FetchProgress: func(u ProgressUpdate) {
+			// Cancel right after the point parquet is staged, before
+			// the segment/linestring merges or the catalog commit run.
+			if u.Phase == SyncPhaseMergePoints {
+				cancel()
+			}
+		},
+	})
+	if err == nil {
+		t.Fatal("expected an error from cancelling mid-merge")
+	}
+
+	leftover, _ := filepath.Glob(filepath.Join(tempDir, "staging", "lrs_point_merged_*.parquet"))
+	if len(leftover) != 0 {
+		t.Errorf("expected the staged point parquet file to be cleaned up, found %v", leftover)
+	}
+	published, _ := filepath.Glob(filepath.Join(tempDir, "lrs_point_merged_*.parquet"))
+	if len(published) != 0 {
+		t.Errorf("expected no published point parquet file for a cancelled merge, found %v", published)
+	}
+
+	var count int
+	err = db.QueryRowContext(ctx, "SELECT count(*) FROM postgres_db.lrs_catalogs WHERE AUTHOR = 'TESTER_CANCEL_MERGE'").Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to query catalog: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected no catalog row for a cancelled merge, found %d", count)
+	}
+}
+
+func TestRollbackVersion(t *testing.T) {
+	connector, err := duckdb.NewConnector("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connector.Close()
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, _ = db.ExecContext(ctx, "install postgres; load postgres;")
+	_, _ = db.ExecContext(ctx, fmt.Sprintf("ATTACH IF NOT EXISTS '%s' AS postgres_db (TYPE POSTGRES)", testPgConnStr))
+	_, _ = db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs")
+
+	tempDir, _ := os.MkdirTemp("", "rollback_version_test_*")
+	defer os.RemoveAll(tempDir)
+	os.Setenv("LRS_DATA_DIR", tempDir)
+	defer os.Unsetenv("LRS_DATA_DIR")
+
+	esriJSON := `{
+		"spatialReference": {"wkt": "GEOGCS[\"GCS_WGS_1984\",DATUM[\"D_WGS_1984\",SPHEROID[\"WGS_1984\",6378137.0,298.257223563]],PRIMEM[\"Greenwich\",0.0],UNIT[\"Degree\",0.0174532925199433]]"},
+		"features": [{
+			"attributes": {"OBJECTID": 1, "RouteId": "R1", "LINKID": "L1", "LINK_NAME": "Link 1", "SK_LENGTH": 100.0},
+			"geometry": {"paths": [[[110.0, -7.0, 0, 0], [110.1, -7.1, 10, 1]]]}
+		}]
+	}`
+
+	repo := NewLRSRouteRepository(connector, testPgConnStr, db)
+
+	if err := repo.SyncFromGeoJSON(ctx, []byte(esriJSON), SyncOptions{Author: "TESTER_ROLLBACK", CommitMsg: "V1"}); err != nil {
+		t.Fatalf("failed to sync version 1: %v", err)
+	}
+	if err := repo.SyncFromGeoJSON(ctx, []byte(esriJSON), SyncOptions{Author: "TESTER_ROLLBACK", CommitMsg: "V2 (bad)"}); err != nil {
+		t.Fatalf("failed to sync version 2: %v", err)
+	}
+
+	versions, err := repo.ListVersions(ctx, "L1")
+	if err != nil {
+		t.Fatalf("ListVersions failed: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 catalog versions before rollback, got %d", len(versions))
+	}
+	badVersion := versions[1]
+
+	if err := repo.RollbackVersion(ctx, badVersion.Version, true); err != nil {
+		t.Fatalf("RollbackVersion failed: %v", err)
+	}
+
+	var endDate sql.NullTime
+	err = db.QueryRowContext(ctx, "SELECT END_DATE FROM postgres_db.lrs_catalogs WHERE VERSION = ?", versions[0].Version).Scan(&endDate)
+	if err != nil {
+		t.Fatalf("failed to query prior version: %v", err)
+	}
+	if endDate.Valid {
+		t.Error("expected the prior version to be re-opened (END_DATE NULL) after rollback")
+	}
+
+	var count int
+	err = db.QueryRowContext(ctx, "SELECT count(*) FROM postgres_db.lrs_catalogs WHERE VERSION = ?", badVersion.Version).Scan(&count)
+	if err != nil {
+		t.Fatalf("failed to query rolled-back version: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected the bad version's row to be removed, found %d", count)
+	}
+
+	if _, err := os.Stat(badVersion.PointFile); !os.IsNotExist(err) {
+		t.Errorf("expected the bad version's point parquet to be deleted, stat returned: %v", err)
+	}
+
+	if err := repo.RollbackVersion(ctx, 999999, true); err == nil {
+		t.Error("expected RollbackVersion to fail for a nonexistent version")
+	}
+
+	if err := repo.SyncFromGeoJSON(ctx, []byte(esriJSON), SyncOptions{Author: "TESTER_ROLLBACK", CommitMsg: "V3"}); err != nil {
+		t.Fatalf("failed to sync version 3: %v", err)
+	}
+	if err := repo.SyncFromGeoJSON(ctx, []byte(esriJSON), SyncOptions{Author: "TESTER_ROLLBACK", CommitMsg: "V4"}); err != nil {
+		t.Fatalf("failed to sync version 4: %v", err)
+	}
+	versions, err = repo.ListVersions(ctx, "L1")
+	if err != nil {
+		t.Fatalf("ListVersions failed: %v", err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("expected 3 catalog versions before the non-active rollback attempt, got %d", len(versions))
+	}
+	nonActiveVersion := versions[0]
+	if err := repo.RollbackVersion(ctx, nonActiveVersion.Version, false); err == nil {
+		t.Error("expected RollbackVersion to fail for a non-active (already closed) version")
+	}
+
+	db.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs WHERE AUTHOR = 'TESTER_ROLLBACK'")
+}
@@ -0,0 +1,213 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// defaultArcGISMaxRetries bounds how many attempts withArcGISRetry makes
+// for a single ArcGIS token or feature request when arcgisMaxRetries is
+// left at its zero value.
+const defaultArcGISMaxRetries = 3
+
+// defaultArcGISRetryBaseDelay is withArcGISRetry's first backoff wait
+// when arcgisRetryBaseDelay is left at its zero value; it doubles on
+// each subsequent attempt.
+const defaultArcGISRetryBaseDelay = 500 * time.Millisecond
+
+// defaultArcGISRetryJitter is the max fraction of a backoff delay
+// randomly added or subtracted when arcgisRetryJitter is left at its
+// zero value.
+const defaultArcGISRetryJitter = 0.2
+
+// defaultArcGISHTTPTimeout bounds a single ArcGIS HTTP round trip when
+// arcgisHTTPTimeout is left at its zero value.
+const defaultArcGISHTTPTimeout = 60 * time.Second
+
+// retryableError marks an error as worth retrying: a transient ArcGIS
+// failure (429, 5xx, or a connection-level error) rather than a
+// permanent one (a bad token, malformed JSON, any other 4xx).
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// retryable wraps err so withArcGISRetry/withArcGISTokenRetry know to
+// retry it. Call sites use this on request-level errors (client.Do
+// failures) and on responses with isRetryableStatus; every other error
+// is returned unwrapped and the retry loop gives up on it immediately.
+func retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err}
+}
+
+// isRetryableStatus reports whether an ArcGIS HTTP response status is
+// worth retrying: 429 (rate limited) and every 5xx (transient upstream
+// failure).
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// tokenExpiredError marks an ArcGIS response as failing because the
+// token used was invalid or expired (error code 498/499), reported by
+// ArcGIS as an "error" object in an otherwise-200 response body. Unlike
+// retryableError, the fix withArcGISTokenRetry applies is a fresh token,
+// not a backoff wait.
+type tokenExpiredError struct {
+	code int
+}
+
+func (e *tokenExpiredError) Error() string {
+	return fmt.Sprintf("arcgis token invalid or expired (code %d)", e.code)
+}
+
+// isTokenExpiredCode reports whether code is ArcGIS's invalid-token
+// (498) or expired-token (499) error code.
+func isTokenExpiredCode(code int) bool {
+	return code == 498 || code == 499
+}
+
+// resolvedHTTPPolicy merges override onto r's configured HTTPPolicy (set
+// via WithHTTPPolicy), falling back to the package defaults for any
+// field left at its zero value in both -- override may be nil.
+func (r *LRSRouteRepository) resolvedHTTPPolicy(override *HTTPPolicy) HTTPPolicy {
+	p := HTTPPolicy{
+		Timeout:    r.arcgisHTTPTimeout,
+		MaxRetries: r.arcgisMaxRetries,
+		BaseDelay:  r.arcgisRetryBaseDelay,
+		Jitter:     r.arcgisRetryJitter,
+	}
+	if override != nil {
+		if override.Timeout != 0 {
+			p.Timeout = override.Timeout
+		}
+		if override.MaxRetries != 0 {
+			p.MaxRetries = override.MaxRetries
+		}
+		if override.BaseDelay != 0 {
+			p.BaseDelay = override.BaseDelay
+		}
+		if override.Jitter != 0 {
+			p.Jitter = override.Jitter
+		}
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = defaultArcGISHTTPTimeout
+	}
+	if p.MaxRetries <= 0 {
+		p.MaxRetries = defaultArcGISMaxRetries
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaultArcGISRetryBaseDelay
+	}
+	if p.Jitter == 0 {
+		p.Jitter = defaultArcGISRetryJitter
+	}
+	return p
+}
+
+// backoffDelay returns the wait before retrying attempt (0-indexed),
+// exponential off policy.BaseDelay with +/- policy.Jitter applied so
+// concurrent callers don't retry in lockstep. A negative Jitter
+// disables it.
+func backoffDelay(policy HTTPPolicy, attempt int) time.Duration {
+	delay := float64(policy.BaseDelay) * math.Pow(2, float64(attempt))
+	if policy.Jitter > 0 {
+		delay += delay * policy.Jitter * (2*rand.Float64() - 1)
+		if delay < 0 {
+			delay = 0
+		}
+	}
+	return time.Duration(delay)
+}
+
+// withArcGISRetry calls fn, retrying with exponential backoff (base
+// delay, doubling each attempt, plus jitter) up to the repository's
+// configured MaxRetries total attempts when fn returns a
+// retryableError. Any other error is returned immediately without
+// retrying. op names the operation for the error returned once attempts
+// are exhausted. ctx cancellation is honored between attempts.
+func (r *LRSRouteRepository) withArcGISRetry(ctx context.Context, op string, fn func() error) error {
+	policy := r.resolvedHTTPPolicy(nil)
+
+	var lastErr error
+	for attempt := 0; attempt < policy.MaxRetries; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		var re *retryableError
+		if !errors.As(err, &re) {
+			return err
+		}
+		lastErr = err
+
+		if attempt == policy.MaxRetries-1 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoffDelay(policy, attempt)):
+		}
+	}
+
+	return fmt.Errorf("%s failed after %d attempts: %w", op, policy.MaxRetries, lastErr)
+}
+
+// withArcGISTokenRetry is withArcGISRetry plus automatic token
+// regeneration: fn is called with *token, and if it returns a
+// tokenExpiredError, a fresh token is generated via GenerateArcGISToken
+// and *token is updated before the next attempt -- counted against the
+// same MaxRetries budget as an ordinary retryableError. Used by the
+// feature and count/page fetchers, which may still be running long
+// after the token used to start them was minted.
+func (r *LRSRouteRepository) withArcGISTokenRetry(ctx context.Context, op string, policy HTTPPolicy, token *string, fn func(token string) error) error {
+	var lastErr error
+	for attempt := 0; attempt < policy.MaxRetries; attempt++ {
+		err := fn(*token)
+		if err == nil {
+			return nil
+		}
+
+		var te *tokenExpiredError
+		if errors.As(err, &te) {
+			newToken, tokenErr := r.GenerateArcGISToken(ctx)
+			if tokenErr != nil {
+				return fmt.Errorf("%s: failed to regenerate expired token: %w", op, tokenErr)
+			}
+			*token = newToken
+			lastErr = err
+			continue
+		}
+
+		var re *retryableError
+		if !errors.As(err, &re) {
+			return err
+		}
+		lastErr = err
+
+		if attempt == policy.MaxRetries-1 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoffDelay(policy, attempt)):
+		}
+	}
+
+	return fmt.Errorf("%s failed after %d attempts: %w", op, policy.MaxRetries, lastErr)
+}
@@ -0,0 +1,111 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGenerateArcGISTokenRetriesOnServerError(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"token": "retried-token"}`)
+	}))
+	defer ts.Close()
+
+	repo := &LRSRouteRepository{
+		tokenURL:             ts.URL,
+		arcgisMaxRetries:     3,
+		arcgisRetryBaseDelay: time.Millisecond,
+	}
+
+	token, err := repo.GenerateArcGISToken(context.Background())
+	if err != nil {
+		t.Fatalf("GenerateArcGISToken failed: %v", err)
+	}
+	if token != "retried-token" {
+		t.Errorf("expected token retried-token, got %s", token)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestGenerateArcGISTokenGivesUpAfterMaxRetries(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	repo := &LRSRouteRepository{
+		tokenURL:             ts.URL,
+		arcgisMaxRetries:     2,
+		arcgisRetryBaseDelay: time.Millisecond,
+	}
+
+	_, err := repo.GenerateArcGISToken(context.Background())
+	if err == nil {
+		t.Fatal("expected an error after exhausting retries")
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestGenerateArcGISTokenDoesNotRetryPermanentError(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	repo := &LRSRouteRepository{
+		tokenURL:             ts.URL,
+		arcgisMaxRetries:     3,
+		arcgisRetryBaseDelay: time.Millisecond,
+	}
+
+	_, err := repo.GenerateArcGISToken(context.Background())
+	if err == nil {
+		t.Fatal("expected an error for a 401 response")
+	}
+	if attempts != 1 {
+		t.Errorf("expected no retries for a non-retryable status, got %d attempts", attempts)
+	}
+}
+
+func TestWithArcGISRetryHonorsContextCancellation(t *testing.T) {
+	repo := &LRSRouteRepository{
+		arcgisMaxRetries:     5,
+		arcgisRetryBaseDelay: 50 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var attempts int
+	err := repo.withArcGISRetry(ctx, "test op", func() error {
+		attempts++
+		return retryable(errors.New("transient failure"))
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected exactly 1 attempt before the cancellation check, got %d", attempts)
+	}
+}
@@ -0,0 +1,255 @@
+package route
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CatalogEntry is one lrs_catalogs row, returned by ListVersions for
+// discovery of a route's sync history.
+type CatalogEntry struct {
+	Version        int
+	StartDate      time.Time
+	EndDate        *time.Time
+	Author         string
+	CommitMsg      string
+	PointFile      string
+	SegmentFile    string
+	LineStringFile string
+}
+
+// ListVersions returns every catalog version whose point file contains
+// routeID, oldest first -- the versions Diff and Rollback can target for
+// that route. lrs_catalogs itself is catalog-wide, so a version only
+// appears here if routeID was present in that version's merged data.
+func (r *LRSRouteRepository) ListVersions(ctx context.Context, routeID string) ([]CatalogEntry, error) {
+	if err := r.attachCatalogDB(ctx); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT VERSION, START_DATE, END_DATE, AUTHOR, COMMIT_MSG, LRS_POINT_FILE, LRS_SEGMENT_FILE, LRS_LINESTR_FILE
+		FROM postgres_db.lrs_catalogs
+		WHERE EXISTS (
+			SELECT 1 FROM read_parquet(LRS_POINT_FILE) WHERE ROUTEID = '%s'
+		)
+		ORDER BY VERSION ASC
+	`, routeID)
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lrs_catalogs: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []CatalogEntry
+	for rows.Next() {
+		var e CatalogEntry
+		var endDate sql.NullTime
+		if err := rows.Scan(&e.Version, &e.StartDate, &endDate, &e.Author, &e.CommitMsg, &e.PointFile, &e.SegmentFile, &e.LineStringFile); err != nil {
+			return nil, fmt.Errorf("failed to scan lrs_catalogs row: %w", err)
+		}
+		if endDate.Valid {
+			e.EndDate = &endDate.Time
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lrs_catalogs rows: %w", err)
+	}
+
+	return entries, nil
+}
+
+// catalogEntryAtVersion returns the lrs_catalogs row for version.
+func (r *LRSRouteRepository) catalogEntryAtVersion(ctx context.Context, version int) (*CatalogEntry, error) {
+	if err := r.attachCatalogDB(ctx); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT VERSION, START_DATE, END_DATE, AUTHOR, COMMIT_MSG, LRS_POINT_FILE, LRS_SEGMENT_FILE, LRS_LINESTR_FILE
+		FROM postgres_db.lrs_catalogs
+		WHERE VERSION = ?
+	`
+	var e CatalogEntry
+	var endDate sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, version).Scan(&e.Version, &e.StartDate, &endDate, &e.Author, &e.CommitMsg, &e.PointFile, &e.SegmentFile, &e.LineStringFile)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no catalog entry found for version %d", version)
+		}
+		return nil, fmt.Errorf("failed to query catalog entry for version %d: %w", version, err)
+	}
+	if endDate.Valid {
+		e.EndDate = &endDate.Time
+	}
+	return &e, nil
+}
+
+// Rollback reverts routeID to the geometry it had at toVersion, writing a
+// new catalog version whose point/segment/linestring files union every
+// other route's current data with routeID's data as it was at toVersion --
+// the same per-route UNION ALL replace mergeWithExisting uses for a fresh
+// sync. toVersion's parquet files are only read from, never rewritten, so
+// rolling back never deletes history. Like mergeWithExisting, the rewritten
+// files are staged and only published once the catalog commit referencing
+// them succeeds; staged files left over from a failed commit are removed.
+func (r *LRSRouteRepository) Rollback(ctx context.Context, routeID string, toVersion int, opts SyncOptions) (err error) {
+	old, err := r.catalogEntryAtVersion(ctx, toVersion)
+	if err != nil {
+		return fmt.Errorf("failed to look up rollback target version: %w", err)
+	}
+
+	latest, err := r.GetLatest(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to look up current catalog entry: %w", err)
+	}
+	currentPointFile := latest.GetPointFile()
+	if currentPointFile == nil {
+		return fmt.Errorf("current catalog entry has no point file")
+	}
+	var currentSegmentFile, currentLinestrFile string
+	if f := latest.GetSegmentFile(); f != nil {
+		currentSegmentFile = *f
+	}
+	if f := latest.GetLineFile(); f != nil {
+		currentLinestrFile = *f
+	}
+
+	conn, err := r.connector.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get db connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := r.db.ExecContext(ctx, "INSTALL spatial; LOAD spatial;"); err != nil {
+		return fmt.Errorf("failed to load spatial extension: %w", err)
+	}
+
+	dataDir, err := lrsDataDir()
+	if err != nil {
+		return err
+	}
+	stagingDir, err := lrsStagingDir(dataDir)
+	if err != nil {
+		return err
+	}
+
+	nanoStamp := time.Now().UnixNano()
+	final := stagedFileSet{
+		Point:      filepath.Join(dataDir, fmt.Sprintf("lrs_point_rollback_%d.parquet", nanoStamp)),
+		Segment:    filepath.Join(dataDir, fmt.Sprintf("lrs_segment_rollback_%d.parquet", nanoStamp)),
+		LineString: filepath.Join(dataDir, fmt.Sprintf("lrs_linestr_rollback_%d.parquet", nanoStamp)),
+	}
+	staged := stagedFileSet{
+		Point:      filepath.Join(stagingDir, fmt.Sprintf("lrs_point_rollback_%d.parquet", nanoStamp)),
+		Segment:    filepath.Join(stagingDir, fmt.Sprintf("lrs_segment_rollback_%d.parquet", nanoStamp)),
+		LineString: filepath.Join(stagingDir, fmt.Sprintf("lrs_linestr_rollback_%d.parquet", nanoStamp)),
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(staged.Point)
+			os.Remove(staged.Segment)
+			os.Remove(staged.LineString)
+		}
+	}()
+
+	queryPoint := fmt.Sprintf(`
+		SELECT * FROM '%s' WHERE ROUTEID != '%s'
+		UNION ALL
+		SELECT * FROM '%s' WHERE ROUTEID = '%s'
+	`, *currentPointFile, routeID, old.PointFile, routeID)
+	if _, err := r.db.ExecContext(ctx, fmt.Sprintf("COPY (%s) TO '%s' (FORMAT PARQUET)", queryPoint, staged.Point)); err != nil {
+		return fmt.Errorf("failed to export rolled-back point parquet: %w", err)
+	}
+
+	querySegment := fmt.Sprintf(`
+		SELECT * FROM '%s' WHERE ROUTEID != '%s'
+		UNION ALL
+		SELECT * FROM '%s' WHERE ROUTEID = '%s'
+	`, currentSegmentFile, routeID, old.SegmentFile, routeID)
+	if _, err := r.db.ExecContext(ctx, fmt.Sprintf("COPY (%s) TO '%s' (FORMAT PARQUET)", querySegment, staged.Segment)); err != nil {
+		return fmt.Errorf("failed to export rolled-back segment parquet: %w", err)
+	}
+
+	queryLinestr := fmt.Sprintf(`
+		SELECT * FROM '%s' WHERE ROUTEID != '%s'
+		UNION ALL
+		SELECT * FROM '%s' WHERE ROUTEID = '%s'
+	`, currentLinestrFile, routeID, old.LineStringFile, routeID)
+	if _, err := r.db.ExecContext(ctx, fmt.Sprintf("COPY (%s) TO '%s' (FORMAT PARQUET)", queryLinestr, staged.LineString)); err != nil {
+		return fmt.Errorf("failed to export rolled-back linestring parquet: %w", err)
+	}
+
+	_, err = r.writeCatalogVersion(ctx, staged, final, opts, []string{routeID})
+	return err
+}
+
+// RollbackVersion undoes a bad catalog commit: it re-opens the version
+// before version (clearing its END_DATE) and removes version's
+// lrs_catalogs row, both inside one transaction, so a reader never
+// observes either no active version or two. Unlike Rollback, which
+// layers a corrected version for one route on top of history,
+// RollbackVersion erases version outright -- use it to recover from a
+// commit whose parquet files turned out to be bad, not to revert a
+// single route's data. If deleteParquet is set, version's point,
+// segment, and linestring files are removed after the transaction
+// commits; leave it false to keep them for inspection.
+func (r *LRSRouteRepository) RollbackVersion(ctx context.Context, version int, deleteParquet bool) error {
+	bad, err := r.catalogEntryAtVersion(ctx, version)
+	if err != nil {
+		return fmt.Errorf("failed to look up version %d: %w", version, err)
+	}
+	if bad.EndDate != nil {
+		return fmt.Errorf("version %d is not the active version (closed at %s), only the active version can be rolled back", version, bad.EndDate)
+	}
+
+	if err := r.attachCatalogDB(ctx); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var prevVersion sql.NullInt64
+	err = tx.QueryRowContext(ctx, "SELECT MAX(VERSION) FROM postgres_db.lrs_catalogs WHERE VERSION < ?", version).Scan(&prevVersion)
+	if err != nil {
+		return fmt.Errorf("failed to look up prior version: %w", err)
+	}
+	if !prevVersion.Valid {
+		return fmt.Errorf("version %d has no prior version to roll back to", version)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE postgres_db.lrs_catalogs SET END_DATE = NULL WHERE VERSION = ?", prevVersion.Int64); err != nil {
+		return fmt.Errorf("failed to re-open version %d: %w", prevVersion.Int64, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM postgres_db.lrs_catalogs WHERE VERSION = ?", version); err != nil {
+		return fmt.Errorf("failed to remove catalog version %d: %w", version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rollback transaction: %w", err)
+	}
+
+	if deleteParquet {
+		os.Remove(bad.PointFile)
+		os.Remove(bad.SegmentFile)
+		os.Remove(bad.LineStringFile)
+	}
+
+	r.publish(&LRSEvent{
+		Type:      EventTypeCatalogVersionRolledBack,
+		Version:   version,
+		Timestamp: time.Now(),
+	})
+
+	return nil
+}
@@ -3,7 +3,6 @@ package route
 import (
 	"bm-lrs/pkg/geom"
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -11,8 +10,6 @@ import (
 	"text/template"
 
 	"github.com/apache/arrow-go/v18/arrow"
-	"github.com/apache/arrow-go/v18/arrow/array"
-	"github.com/apache/arrow-go/v18/arrow/memory"
 	"github.com/apache/arrow-go/v18/parquet"
 	"github.com/apache/arrow-go/v18/parquet/compress"
 	"github.com/apache/arrow-go/v18/parquet/pqarrow"
@@ -25,16 +22,36 @@ type sourceFiles struct {
 }
 
 type LRSRoute struct {
-	route_id        string
-	records         []arrow.RecordBatch
-	latitudeCol     string
-	longitudeCol    string
-	mValueCol       string
-	VertexSeqColumn string
-	crs             string
-	source_files    *sourceFiles
-	temp_dir        string
-	push_down       bool
+	route_id           string
+	records            []arrow.RecordBatch
+	latitudeCol        string
+	longitudeCol       string
+	mValueCol          string
+	VertexSeqColumn    string
+	crs                string
+	source_files       *sourceFiles
+	temp_dir           string
+	push_down          bool
+	geoParquet         bool
+	geoParquetGeometry bool
+
+	// indexCache is a reference to this route's lazily-built spatial index,
+	// not the index itself: LRSRoute is copied by value throughout this
+	// package (NewLRSRoute returns one, AddRoute takes one, ...), so the
+	// cache can't embed a sync.Once directly without making every such copy
+	// illegal to copy. See routeIndexCache in index.go.
+	indexCache *routeIndexCache
+}
+
+// SinkOption configures optional behavior of LRSRoute.Sink.
+type SinkOption func(*LRSRoute)
+
+// WithGeoParquetMetadata enables writing the standard GeoParquet "geo"
+// file-level key/value metadata alongside the point Parquet output.
+func WithGeoParquetMetadata() SinkOption {
+	return func(l *LRSRoute) {
+		l.geoParquet = true
+	}
 }
 
 type LRSRouteInterface interface {
@@ -65,95 +82,6 @@ func (l *LRSRoute) setPushDown(enable bool) {
 	l.push_down = enable
 }
 
-// Create LRSRoute from ESRI GeoJSON
-func NewLRSRouteFromESRIGeoJSON(route_id string, jsonbyte []byte, feature_idx int, crs string) LRSRoute {
-	var jsonContent map[string]any
-
-	json.Unmarshal([]byte(jsonbyte), &jsonContent)
-
-	// Parse the LRS Vertex
-	var vertexes []any
-	WKT := jsonContent["spatialReference"].(map[string]any)["wkt"].(string)
-	features := jsonContent["features"].([]any)
-	feature := features[feature_idx].(map[string]any)["geometry"].(map[string]any)
-	vertexes = feature["paths"].([]any)[0].([]any)
-
-	pool := memory.NewGoAllocator()
-
-	// Schema
-	schema := arrow.NewSchema(
-		[]arrow.Field{
-			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
-			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
-			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
-			{Name: "VERTEX_SEQ", Type: arrow.PrimitiveTypes.Int32},
-			{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
-		},
-		nil,
-	)
-
-	// Builder
-	lat_builder := array.NewFloat64Builder(pool)
-	long_builder := array.NewFloat64Builder(pool)
-	mval_builder := array.NewFloat64Builder(pool)
-	vertex_seq_builder := array.NewInt32Builder(pool)
-	routeid_builder := array.NewStringBuilder(pool)
-
-	defer lat_builder.Release()
-	defer long_builder.Release()
-	defer mval_builder.Release()
-	defer vertex_seq_builder.Release()
-	defer routeid_builder.Release()
-
-	// Append data
-	var lat_rows []float64
-	var long_rows []float64
-	var mval_rows []float64
-	var vertex_seq_rows []int32
-	var route_id_rows []string
-
-	for i, vertex := range vertexes {
-		long_rows = append(long_rows, vertex.([]any)[0].(float64))
-		lat_rows = append(lat_rows, vertex.([]any)[1].(float64))
-		mval_rows = append(mval_rows, vertex.([]any)[2].(float64))
-		vertex_seq_rows = append(vertex_seq_rows, int32(i))
-		route_id_rows = append(route_id_rows, route_id)
-	}
-
-	lat_builder.AppendValues(lat_rows, nil)
-	long_builder.AppendValues(long_rows, nil)
-	mval_builder.AppendValues(mval_rows, nil)
-	vertex_seq_builder.AppendValues(vertex_seq_rows, nil)
-	routeid_builder.AppendValues(route_id_rows, nil)
-
-	// Arrays
-	lat_arr := lat_builder.NewArray()
-	long_arr := long_builder.NewArray()
-	mval_arr := mval_builder.NewArray()
-	vertex_seq_arr := vertex_seq_builder.NewArray()
-	routeid_arr := routeid_builder.NewArray()
-
-	rec := array.NewRecordBatch(
-		schema,
-		[]arrow.Array{
-			lat_arr,
-			long_arr,
-			mval_arr,
-			vertex_seq_arr,
-			routeid_arr,
-		},
-		int64(vertex_seq_arr.Len()),
-	)
-
-	lrs := NewLRSRoute(
-		route_id,
-		[]arrow.RecordBatch{rec},
-		WKT,
-	)
-
-	return lrs
-}
-
 // Get Apache Arrow Records of the LRS Route
 func (l *LRSRoute) GetRecords() []arrow.RecordBatch {
 	return l.records
@@ -256,7 +184,11 @@ func (l *LRSRoute) IsMaterialized() bool {
 }
 
 // Sink the source record batch into parquet file
-func (l *LRSRoute) Sink() error {
+func (l *LRSRoute) Sink(opts ...SinkOption) error {
+	for _, opt := range opts {
+		opt(l)
+	}
+
 	// Create temporary directory
 	tempDir, err := os.MkdirTemp("", "lrs_route_*")
 	if err != nil {
@@ -276,7 +208,40 @@ func (l *LRSRoute) Sink() error {
 		return fmt.Errorf("records are empty")
 	}
 
+	if l.geoParquetGeometry {
+		if err := l.sinkGeoParquetGeometry(f); err != nil {
+			return err
+		}
+		if l.source_files == nil {
+			l.source_files = &sourceFiles{}
+		}
+		l.source_files.Point = &filePath
+		return nil
+	}
+
 	schema := l.records[0].Schema()
+	if l.geoParquet {
+		bounds := &geoBounds{}
+		for _, rec := range l.records {
+			b, err := routeBounds(rec, l.latitudeCol, l.longitudeCol)
+			if err != nil {
+				return fmt.Errorf("failed to compute bbox for geo metadata: %v", err)
+			}
+			if b.set {
+				bounds.extend(b.minX, b.minY)
+				bounds.extend(b.maxX, b.maxY)
+			}
+		}
+
+		meta := buildGeoParquetMeta(geometryColumn, l.crs, bounds.bbox())
+		kv, err := geoParquetKV(meta)
+		if err != nil {
+			return fmt.Errorf("failed to build geo metadata: %v", err)
+		}
+		fields := append(append([]arrow.Field{}, schema.Fields()...), arrow.Field{Name: geometryColumn, Type: arrow.BinaryTypes.Binary})
+		schema = arrow.NewSchema(fields, &kv)
+	}
+
 	writer, err := pqarrow.NewFileWriter(
 		schema,
 		f,
@@ -291,7 +256,19 @@ func (l *LRSRoute) Sink() error {
 	defer writer.Close()
 
 	for _, rec := range l.records {
-		if err := writer.WriteBuffered(rec); err != nil {
+		toWrite := rec
+		if l.geoParquet {
+			geomRec, err := withPointGeometryColumn(rec, schema, l.latitudeCol, l.longitudeCol, l.mValueCol)
+			if err != nil {
+				return fmt.Errorf("failed to build geometry column: %v", err)
+			}
+			toWrite = geomRec
+		}
+		err := writer.WriteBuffered(toWrite)
+		if toWrite != rec {
+			toWrite.Release()
+		}
+		if err != nil {
 			return fmt.Errorf("Failed to write record batch: %v", err)
 		}
 	}
@@ -131,24 +131,20 @@ func TestLRSRoute(t *testing.T) {
 
 	t.Run(
 		"initialize from geojson", func(t *testing.T) {
-			lrs := NewLRSRouteFromESRIGeoJSON(
-				"01001",
-				jsonByte,
-				0,
-				WKT,
-			)
+			lrs, err := NewLRSRouteFromESRIGeoJSON(jsonByte, 0, WKT)
+			if err != nil {
+				t.Fatalf("NewLRSRouteFromESRIGeoJSON failed: %v", err)
+			}
 			defer lrs.Release()
 		},
 	)
 
 	t.Run(
 		"geometry type test", func(t *testing.T) {
-			lrs := NewLRSRouteFromESRIGeoJSON(
-				"01001",
-				jsonByte,
-				0,
-				WKT,
-			)
+			lrs, err := NewLRSRouteFromESRIGeoJSON(jsonByte, 0, WKT)
+			if err != nil {
+				t.Fatalf("NewLRSRouteFromESRIGeoJSON failed: %v", err)
+			}
 			defer lrs.Release()
 
 			if lrs.GetGeometryType() != geom.LRS {
@@ -158,15 +154,37 @@ func TestLRSRoute(t *testing.T) {
 	)
 
 	t.Run(
-		"segment table view test", func(t *testing.T) {
-			lrs := NewLRSRouteFromESRIGeoJSON(
+		"build segment index", func(t *testing.T) {
+			lrs := NewLRSRoute(
 				"01001",
-				jsonByte,
-				0,
+				[]arrow.RecordBatch{rec},
 				WKT,
 			)
 			defer lrs.Release()
 
+			idx, err := lrs.Index()
+			if err != nil {
+				t.Fatalf("failed to build index: %v", err)
+			}
+
+			candidates := idx.NearestK(lat_rows[0], long_rows[0], 1)
+			if len(candidates) != 1 {
+				t.Fatalf("expected 1 candidate, got %d", len(candidates))
+			}
+			if _, ok := candidates[0].Item.(Segment); !ok {
+				t.Errorf("expected candidate item to be a Segment, got %T", candidates[0].Item)
+			}
+		},
+	)
+
+	t.Run(
+		"segment table view test", func(t *testing.T) {
+			lrs, err := NewLRSRouteFromESRIGeoJSON(jsonByte, 0, WKT)
+			if err != nil {
+				t.Fatalf("NewLRSRouteFromESRIGeoJSON failed: %v", err)
+			}
+			defer lrs.Release()
+
 			rr, err := array.NewRecordReader(lrs.GetRecords()[0].Schema(), lrs.GetRecords())
 			if err != nil {
 				t.Error(err)
@@ -195,12 +213,10 @@ func TestLRSRoute(t *testing.T) {
 
 	t.Run(
 		"linestring table view test", func(t *testing.T) {
-			lrs := NewLRSRouteFromESRIGeoJSON(
-				"01001",
-				jsonByte,
-				0,
-				WKT,
-			)
+			lrs, err := NewLRSRouteFromESRIGeoJSON(jsonByte, 0, WKT)
+			if err != nil {
+				t.Fatalf("NewLRSRouteFromESRIGeoJSON failed: %v", err)
+			}
 			defer lrs.Release()
 
 			rr, err := array.NewRecordReader(lrs.GetRecords()[0].Schema(), lrs.GetRecords())
@@ -222,12 +238,10 @@ func TestLRSRoute(t *testing.T) {
 
 	t.Run(
 		"sink function test", func(t *testing.T) {
-			lrs := NewLRSRouteFromESRIGeoJSON(
-				"01001",
-				jsonByte,
-				0,
-				WKT,
-			)
+			lrs, err := NewLRSRouteFromESRIGeoJSON(jsonByte, 0, WKT)
+			if err != nil {
+				t.Fatalf("NewLRSRouteFromESRIGeoJSON failed: %v", err)
+			}
 			defer lrs.Release()
 
 			lrs.Sink()
@@ -0,0 +1,63 @@
+package route
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lrsDataDir returns the directory mergeWithExisting and Rollback write
+// merged parquet files under, defaulting to ./data when LRS_DATA_DIR is
+// unset, creating it if necessary.
+func lrsDataDir() (string, error) {
+	dataDir := os.Getenv("LRS_DATA_DIR")
+	if dataDir == "" {
+		dataDir = "./data"
+	}
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create data dir: %w", err)
+	}
+	return dataDir, nil
+}
+
+// lrsStagingDir returns the staging subdirectory of dataDir that
+// mergeWithExisting and Rollback COPY parquet files into before they're
+// renamed to their final path, so a failed catalog commit never leaves a
+// half-written file at a path an active lrs_catalogs row could reference.
+func lrsStagingDir(dataDir string) (string, error) {
+	stagingDir := filepath.Join(dataDir, "staging")
+	if err := os.MkdirAll(stagingDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create staging dir: %w", err)
+	}
+	return stagingDir, nil
+}
+
+// stagedFileSet names a point/segment/linestring parquet triple.
+// writeCatalogVersion takes one rooted in the staging dir (where
+// mergeWithExisting/Rollback just COPY'd the merged data) and one rooted
+// in the data dir (where the committed catalog row should point), and
+// renames staged to final only after the row referencing final commits.
+type stagedFileSet struct {
+	Point      string
+	Segment    string
+	LineString string
+}
+
+// publish renames each of staged's files to its counterpart in final,
+// called by writeCatalogVersion only after the transaction referencing
+// final has committed. A rename failure here leaves a committed catalog
+// row pointing at a file that was never published -- writeCatalogVersion
+// surfaces that as an error, but can't roll back a commit that already
+// succeeded; RollbackVersion is the operator's recourse.
+func (staged stagedFileSet) publish(final stagedFileSet) error {
+	for _, pair := range [][2]string{
+		{staged.Point, final.Point},
+		{staged.Segment, final.Segment},
+		{staged.LineString, final.LineString},
+	} {
+		if err := os.Rename(pair[0], pair[1]); err != nil {
+			return fmt.Errorf("failed to publish staged file %s -> %s: %w", pair[0], pair[1], err)
+		}
+	}
+	return nil
+}
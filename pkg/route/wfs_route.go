@@ -0,0 +1,204 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// NewLRSRouteFromWFS fetches featureType from the WFS 2.0 service at
+// capabilitiesURL, validating it against GetCapabilities, then pages
+// through GetFeature (filter is an optional CQL_FILTER, sortBy makes
+// paging deterministic against services that don't otherwise guarantee
+// stable ordering), flattening every returned feature's LineString/
+// MultiLineString/Polygon geometry into LAT/LON/MVAL/VERTEX_SEQ rows, and
+// building Arrow RecordBatches exactly like NewLRSRouteFromGeoJSON.
+// routeIDField names the feature property holding the route id; every
+// fetched feature must share a single value, or use
+// NewLRSRouteBatchFromWFS to ingest multiple routes in one call.
+func NewLRSRouteFromWFS(ctx context.Context, capabilitiesURL, featureType, filter, sortBy, routeIDField, crs string) (LRSRoute, error) {
+	fc, err := fetchWFSFeatureCollection(ctx, capabilitiesURL, featureType, filter, sortBy)
+	if err != nil {
+		return LRSRoute{}, err
+	}
+
+	groups, order := groupFeaturesByRouteID(fc.Features, routeIDField)
+	if len(order) != 1 {
+		return LRSRoute{}, fmt.Errorf("expected a single route for %q, got %d distinct values; use NewLRSRouteBatchFromWFS", routeIDField, len(order))
+	}
+
+	return buildLRSRouteFromFeatures(order[0], groups[order[0]], crs, fc.CRS)
+}
+
+// NewLRSRouteBatchFromWFS is NewLRSRouteFromWFS's multi-route sibling: it
+// fetches featureType the same way, then returns one LRSRoute per
+// distinct routeIDField value as a populated LRSRouteBatch.
+func NewLRSRouteBatchFromWFS(ctx context.Context, capabilitiesURL, featureType, filter, sortBy, routeIDField, crs string, opts ...BatchOption) (*LRSRouteBatch, error) {
+	fc, err := fetchWFSFeatureCollection(ctx, capabilitiesURL, featureType, filter, sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, order := groupFeaturesByRouteID(fc.Features, routeIDField)
+
+	batch := NewLRSRouteBatch(opts...)
+	for _, routeID := range order {
+		lrs, err := buildLRSRouteFromFeatures(routeID, groups[routeID], crs, fc.CRS)
+		if err != nil {
+			batch.Release()
+			return nil, err
+		}
+		if err := batch.AddRoute(lrs); err != nil {
+			batch.Release()
+			return nil, fmt.Errorf("failed to add route %q to batch: %w", routeID, err)
+		}
+	}
+	return batch, nil
+}
+
+// fetchWFSFeatureCollection validates featureType against
+// capabilitiesURL's GetCapabilities, then pages through GetFeature via a
+// WFSFeatureSource, merging every page into a single
+// rfc7946FeatureCollection.
+func fetchWFSFeatureCollection(ctx context.Context, capabilitiesURL, featureType, filter, sortBy string) (*rfc7946FeatureCollection, error) {
+	source := NewWFSFeatureSource(capabilitiesURL, featureType, "", WithWFSFilter(filter), WithWFSSortBy(sortBy))
+
+	types, err := source.GetCapabilities(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch wfs capabilities: %w", err)
+	}
+	found := false
+	for _, t := range types {
+		if t == featureType {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("feature type %q not offered by %s", featureType, capabilitiesURL)
+	}
+
+	data, err := source.FetchFeatures(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch wfs features: %w", err)
+	}
+
+	var fc rfc7946FeatureCollection
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal wfs feature collection: %w", err)
+	}
+	return &fc, nil
+}
+
+// groupFeaturesByRouteID buckets features by their routeIDField property,
+// preserving first-seen order so NewLRSRouteBatchFromWFS's output order
+// matches the service's paging order. Features missing routeIDField are
+// skipped.
+func groupFeaturesByRouteID(features []rfc7946Feature, routeIDField string) (map[string][]rfc7946Feature, []string) {
+	groups := make(map[string][]rfc7946Feature)
+	var order []string
+	for _, f := range features {
+		routeID, ok := f.Properties[routeIDField].(string)
+		if !ok {
+			continue
+		}
+		if _, seen := groups[routeID]; !seen {
+			order = append(order, routeID)
+		}
+		groups[routeID] = append(groups[routeID], f)
+	}
+	return groups, order
+}
+
+// buildLRSRouteFromFeatures concatenates every feature's LineString/
+// MultiLineString/Polygon lines, in feature order, into one continuous
+// LAT/LON/MVAL/VERTEX_SEQ vertex sequence -- the multi-feature analogue
+// of NewLRSRouteFromGeoJSON's single-feature build, needed because a WFS
+// service commonly pages one route's geometry across several features.
+// crs falls back to docCRS (the feature collection's own "crs" member),
+// then to defaultGeoJSONCRS, mirroring NewLRSRouteFromGeoJSON.
+func buildLRSRouteFromFeatures(routeID string, features []rfc7946Feature, crs string, docCRS *rfc7946NamedCRS) (LRSRoute, error) {
+	if crs == "" {
+		if docCRS != nil && docCRS.Properties.Name != "" {
+			crs = docCRS.Properties.Name
+		} else {
+			crs = defaultGeoJSONCRS
+		}
+	}
+
+	pool := memory.NewGoAllocator()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "VERTEX_SEQ", Type: arrow.PrimitiveTypes.Int32},
+			{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+		},
+		nil,
+	)
+
+	lat_builder := array.NewFloat64Builder(pool)
+	long_builder := array.NewFloat64Builder(pool)
+	mval_builder := array.NewFloat64Builder(pool)
+	vertex_seq_builder := array.NewInt32Builder(pool)
+	routeid_builder := array.NewStringBuilder(pool)
+
+	defer lat_builder.Release()
+	defer long_builder.Release()
+	defer mval_builder.Release()
+	defer vertex_seq_builder.Release()
+	defer routeid_builder.Release()
+
+	vertexSeq := 0
+	for featIdx, feature := range features {
+		lines, err := lineStringsFromGeometry(feature.Geometry)
+		if err != nil {
+			return LRSRoute{}, fmt.Errorf("route %q feature %d: %w", routeID, featIdx, err)
+		}
+
+		mValues, err := measuresForFeature(feature, lines)
+		if err != nil {
+			return LRSRoute{}, fmt.Errorf("route %q feature %d: %w", routeID, featIdx, err)
+		}
+
+		for lineIdx, line := range lines {
+			for ptIdx, coord := range line {
+				if len(coord) < 2 {
+					return LRSRoute{}, fmt.Errorf("route %q feature %d: coordinate %d of line %d has fewer than 2 members", routeID, featIdx, ptIdx, lineIdx)
+				}
+				long_builder.Append(coord[0])
+				lat_builder.Append(coord[1])
+				mval_builder.Append(mValues[lineIdx][ptIdx])
+				vertex_seq_builder.Append(int32(vertexSeq))
+				routeid_builder.Append(routeID)
+				vertexSeq++
+			}
+		}
+	}
+
+	lat_arr := lat_builder.NewArray()
+	long_arr := long_builder.NewArray()
+	mval_arr := mval_builder.NewArray()
+	vertex_seq_arr := vertex_seq_builder.NewArray()
+	routeid_arr := routeid_builder.NewArray()
+
+	defer lat_arr.Release()
+	defer long_arr.Release()
+	defer mval_arr.Release()
+	defer vertex_seq_arr.Release()
+	defer routeid_arr.Release()
+
+	rec := array.NewRecordBatch(
+		schema,
+		[]arrow.Array{lat_arr, long_arr, mval_arr, vertex_seq_arr, routeid_arr},
+		int64(vertex_seq_arr.Len()),
+	)
+
+	return NewLRSRoute(routeID, []arrow.RecordBatch{rec}, crs), nil
+}
@@ -0,0 +1,84 @@
+package route_event
+
+import (
+	"bm-lrs/pkg/route"
+	"fmt"
+	"sort"
+)
+
+// ToEsriRouteJSON converts LRSEvents to the ESRI JSON schema consumed by
+// NewLRSRouteFromESRIGeoJSON, grouping rows by RouteIDColumn into one
+// feature per route, each carrying its events as a single path ordered by
+// MValueColumn.
+func (e *LRSEvents) ToEsriRouteJSON() ([]byte, error) {
+	if len(e.records) == 0 {
+		return nil, fmt.Errorf("no records to convert")
+	}
+
+	type indexedVertex struct {
+		mval float64
+		v    [3]float64
+	}
+	byRoute := make(map[string][]indexedVertex)
+	order := make([]string, 0)
+
+	for _, batch := range e.records {
+		schema := batch.Schema()
+
+		routeIDIndices := schema.FieldIndices(e.routeIDCol)
+		latIndices := schema.FieldIndices(e.latCol)
+		lonIndices := schema.FieldIndices(e.lonCol)
+		mValIndices := schema.FieldIndices(e.mValCol)
+		if len(routeIDIndices) == 0 || len(latIndices) == 0 || len(lonIndices) == 0 || len(mValIndices) == 0 {
+			return nil, fmt.Errorf("events missing one of %s, %s, %s, %s columns", e.routeIDCol, e.latCol, e.lonCol, e.mValCol)
+		}
+
+		routeIDCol := batch.Column(routeIDIndices[0])
+		latCol := batch.Column(latIndices[0])
+		lonCol := batch.Column(lonIndices[0])
+		mValCol := batch.Column(mValIndices[0])
+
+		for rowIdx := 0; rowIdx < int(batch.NumRows()); rowIdx++ {
+			routeIDVal, err := getColumnValue(routeIDCol, rowIdx)
+			if err != nil || routeIDVal == nil {
+				return nil, fmt.Errorf("failed to get %s value at row %d: %v", e.routeIDCol, rowIdx, err)
+			}
+			routeID, ok := routeIDVal.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s column is not a string", e.routeIDCol)
+			}
+
+			lat, err := getFloat64Value(latCol, rowIdx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get %s value at row %d: %v", e.latCol, rowIdx, err)
+			}
+			lon, err := getFloat64Value(lonCol, rowIdx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get %s value at row %d: %v", e.lonCol, rowIdx, err)
+			}
+			mval, err := getFloat64Value(mValCol, rowIdx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get %s value at row %d: %v", e.mValCol, rowIdx, err)
+			}
+
+			if _, seen := byRoute[routeID]; !seen {
+				order = append(order, routeID)
+			}
+			byRoute[routeID] = append(byRoute[routeID], indexedVertex{mval: mval, v: [3]float64{lon, lat, mval}})
+		}
+	}
+
+	features := make([]route.EsriRouteFeatureInput, 0, len(order))
+	for _, routeID := range order {
+		vertices := byRoute[routeID]
+		sort.Slice(vertices, func(i, j int) bool { return vertices[i].mval < vertices[j].mval })
+
+		path := make([][3]float64, len(vertices))
+		for i, v := range vertices {
+			path[i] = v.v
+		}
+		features = append(features, route.EsriRouteFeatureInput{RouteID: routeID, Path: path})
+	}
+
+	return route.MarshalEsriRouteJSON(e.crs, e.routeIDCol, features)
+}
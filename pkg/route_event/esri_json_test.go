@@ -0,0 +1,72 @@
+package route_event
+
+import (
+	"bm-lrs/pkg/route"
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestLRSEventsToEsriRouteJSON(t *testing.T) {
+	pool := memory.NewGoAllocator()
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+		{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+
+	builder := array.NewRecordBuilder(pool, schema)
+	defer builder.Release()
+
+	// Out of MVAL order on purpose, to exercise the per-route sort.
+	rows := []struct {
+		lat, lon, mval float64
+		routeID        string
+	}{
+		{5.649, 95.422, 10, "01002"},
+		{5.648, 95.421, 0, "01002"},
+	}
+	for _, r := range rows {
+		builder.Field(0).(*array.Float64Builder).Append(r.lat)
+		builder.Field(1).(*array.Float64Builder).Append(r.lon)
+		builder.Field(2).(*array.StringBuilder).Append(r.routeID)
+		builder.Field(3).(*array.Float64Builder).Append(r.mval)
+	}
+
+	rec := builder.NewRecordBatch()
+	defer rec.Release()
+
+	events, err := NewLRSEvents([]arrow.RecordBatch{rec}, "EPSG:4326")
+	if err != nil {
+		t.Fatalf("failed to create LRSEvents: %v", err)
+	}
+
+	data, err := events.ToEsriRouteJSON()
+	if err != nil {
+		t.Fatalf("ToEsriRouteJSON failed: %v", err)
+	}
+
+	var esri route.EsriRouteJson
+	if err := json.Unmarshal(data, &esri); err != nil {
+		t.Fatalf("failed to unmarshal result: %v", err)
+	}
+
+	if esri.FeatureCount() != 1 {
+		t.Fatalf("expected 1 feature (1 distinct route), got %d", esri.FeatureCount())
+	}
+	f := esri.Features[0]
+	if f.Attributes["ROUTEID"] != "01002" {
+		t.Errorf("expected ROUTEID 01002, got %v", f.Attributes["ROUTEID"])
+	}
+	if len(f.Geometry.Paths) != 1 || len(f.Geometry.Paths[0]) != 2 {
+		t.Fatalf("expected 1 path with 2 vertices, got %v", f.Geometry.Paths)
+	}
+	if path := f.Geometry.Paths[0]; path[0][2] != 0 || path[1][2] != 10 {
+		t.Errorf("expected vertices ordered by MVAL ascending, got %v", path)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
 )
 
@@ -26,7 +27,9 @@ type GeoJSONGeometry struct {
 	Coordinates []float64 `json:"coordinates"`
 }
 
-// ToGeoJSON converts LRSEvents to GeoJSON FeatureCollection bytes
+// ToGeoJSON converts LRSEvents to GeoJSON FeatureCollection bytes. For
+// batch sizes too large to hold the whole FeatureCollection in memory, use
+// WriteGeoJSON or WriteGeoJSONSeq instead, which stream feature by feature.
 func (e *LRSEvents) ToGeoJSON() ([]byte, error) {
 	if len(e.records) == 0 {
 		return nil, fmt.Errorf("no records to convert")
@@ -38,65 +41,69 @@ func (e *LRSEvents) ToGeoJSON() ([]byte, error) {
 	}
 
 	for _, batch := range e.records {
-		schema := batch.Schema()
+		feats, err := featuresFromBatch(batch, e.latCol, e.lonCol)
+		if err != nil {
+			return nil, err
+		}
+		fc.Features = append(fc.Features, feats...)
+	}
 
-		// Find LAT and LON column indices
-		latIndices := schema.FieldIndices(e.latCol)
-		lonIndices := schema.FieldIndices(e.lonCol)
+	return json.MarshalIndent(fc, "", "  ")
+}
 
-		if len(latIndices) == 0 || len(lonIndices) == 0 {
-			return nil, fmt.Errorf("LAT or LON column not found in records")
-		}
+// featuresFromBatch converts every row of batch into a GeoJSON Point
+// feature, with latCol/lonCol forming the geometry and every other column
+// carried as a property.
+func featuresFromBatch(batch arrow.RecordBatch, latCol, lonCol string) ([]GeoJSONFeature, error) {
+	schema := batch.Schema()
 
-		latIdx := latIndices[0]
-		lonIdx := lonIndices[0]
+	latIndices := schema.FieldIndices(latCol)
+	lonIndices := schema.FieldIndices(lonCol)
+	if len(latIndices) == 0 || len(lonIndices) == 0 {
+		return nil, fmt.Errorf("LAT or LON column not found in records")
+	}
 
-		latCol := batch.Column(latIdx)
-		lonCol := batch.Column(lonIdx)
+	latCol_ := batch.Column(latIndices[0])
+	lonCol_ := batch.Column(lonIndices[0])
 
-		numRows := int(batch.NumRows())
+	numRows := int(batch.NumRows())
+	features := make([]GeoJSONFeature, 0, numRows)
 
-		for rowIdx := 0; rowIdx < numRows; rowIdx++ {
-			// Get lat/lon values
-			lat, err := getFloat64Value(latCol, rowIdx)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get LAT value at row %d: %v", rowIdx, err)
-			}
-			lon, err := getFloat64Value(lonCol, rowIdx)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get LON value at row %d: %v", rowIdx, err)
-			}
+	for rowIdx := 0; rowIdx < numRows; rowIdx++ {
+		lat, err := getFloat64Value(latCol_, rowIdx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get LAT value at row %d: %v", rowIdx, err)
+		}
+		lon, err := getFloat64Value(lonCol_, rowIdx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get LON value at row %d: %v", rowIdx, err)
+		}
 
-			// Build properties from other columns
-			properties := make(map[string]interface{})
-			for colIdx := 0; colIdx < int(batch.NumCols()); colIdx++ {
-				fieldName := schema.Field(colIdx).Name
-				// Skip LAT and LON columns as they go into geometry
-				if fieldName == e.latCol || fieldName == e.lonCol {
-					continue
-				}
-
-				col := batch.Column(colIdx)
-				val, err := getColumnValue(col, rowIdx)
-				if err == nil && val != nil {
-					properties[fieldName] = val
-				}
+		properties := make(map[string]interface{})
+		for colIdx := 0; colIdx < int(batch.NumCols()); colIdx++ {
+			fieldName := schema.Field(colIdx).Name
+			if fieldName == latCol || fieldName == lonCol {
+				continue
 			}
 
-			feature := GeoJSONFeature{
-				Type: "Feature",
-				Geometry: GeoJSONGeometry{
-					Type:        "Point",
-					Coordinates: []float64{lon, lat}, // GeoJSON uses [lon, lat] order
-				},
-				Properties: properties,
+			col := batch.Column(colIdx)
+			val, err := getColumnValue(col, rowIdx)
+			if err == nil && val != nil {
+				properties[fieldName] = val
 			}
-
-			fc.Features = append(fc.Features, feature)
 		}
+
+		features = append(features, GeoJSONFeature{
+			Type: "Feature",
+			Geometry: GeoJSONGeometry{
+				Type:        "Point",
+				Coordinates: []float64{lon, lat}, // GeoJSON uses [lon, lat] order
+			},
+			Properties: properties,
+		})
 	}
 
-	return json.MarshalIndent(fc, "", "  ")
+	return features, nil
 }
 
 // getFloat64Value extracts a float64 value from an Arrow column at a given index
@@ -0,0 +1,174 @@
+package route_event
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// recordSeparator is the RFC 8142 GeoJSON Text Sequences record
+// separator, written before every feature in WriteGeoJSONSeq.
+var recordSeparator = []byte{0x1e}
+
+// writeGeoJSONConfig is configured by WriteGeoJSONOption.
+type writeGeoJSONConfig struct {
+	gzip      bool
+	targetCRS any
+}
+
+// WriteGeoJSONOption configures WriteGeoJSON and WriteGeoJSONSeq.
+type WriteGeoJSONOption func(*writeGeoJSONConfig)
+
+// WithGzip wraps the written output in a gzip stream.
+func WithGzip() WriteGeoJSONOption {
+	return func(c *writeGeoJSONConfig) {
+		c.gzip = true
+	}
+}
+
+// WithProjection reprojects e to crs (resolved via e.Registry(), so
+// anything Registry.Resolve accepts: an EPSG code, OGC URN, WKT, or
+// PROJJSON) through the projection.Transform pipeline before writing.
+func WithProjection(crs any) WriteGeoJSONOption {
+	return func(c *writeGeoJSONConfig) {
+		c.targetCRS = crs
+	}
+}
+
+// projectedSource returns e unchanged, or, if WithProjection was supplied,
+// a new LRSEvents holding the projected records. The returned cleanup
+// releases whatever records that projection allocated and must be called
+// once the caller is done streaming.
+func (e *LRSEvents) projectedSource(ctx context.Context, cfg *writeGeoJSONConfig) (*LRSEvents, func(), error) {
+	if cfg.targetCRS == nil {
+		return e, func() {}, nil
+	}
+
+	transformed, err := e.Registry().Transform(ctx, e, cfg.targetCRS, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to project events: %w", err)
+	}
+
+	projected, err := NewLRSEvents(transformed.GetRecords(), transformed.GetCRS())
+	if err != nil {
+		transformed.Release()
+		return nil, nil, err
+	}
+
+	return projected, func() { transformed.Release() }, nil
+}
+
+// WriteGeoJSON streams e as a GeoJSON FeatureCollection to w, iterating
+// record batches via Stream and encoding one feature at a time instead of
+// materializing the whole document, unlike ToGeoJSON.
+func (e *LRSEvents) WriteGeoJSON(ctx context.Context, w io.Writer, opts ...WriteGeoJSONOption) (err error) {
+	cfg := &writeGeoJSONConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	source, cleanup, err := e.projectedSource(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	dest := io.Writer(w)
+	var gz *gzip.Writer
+	if cfg.gzip {
+		gz = gzip.NewWriter(w)
+		dest = gz
+		defer func() {
+			if closeErr := gz.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+	}
+
+	if _, err := io.WriteString(dest, `{"type":"FeatureCollection","features":[`); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(dest)
+	first := true
+	for res := range source.Stream(ctx) {
+		if res.Err != nil {
+			return res.Err
+		}
+
+		feats, err := featuresFromBatch(res.Record, source.latCol, source.lonCol)
+		res.Record.Release()
+		if err != nil {
+			return err
+		}
+
+		for _, f := range feats {
+			if !first {
+				if _, err := io.WriteString(dest, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			if err := enc.Encode(f); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = io.WriteString(dest, "]}")
+	return err
+}
+
+// WriteGeoJSONSeq streams e to w as RFC 8142 GeoJSON Text Sequences: one
+// standalone Feature object per record, each preceded by the 0x1e record
+// separator, so downstream tools can consume events as they are produced
+// instead of waiting for a complete FeatureCollection.
+func (e *LRSEvents) WriteGeoJSONSeq(ctx context.Context, w io.Writer, opts ...WriteGeoJSONOption) (err error) {
+	cfg := &writeGeoJSONConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	source, cleanup, err := e.projectedSource(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	dest := io.Writer(w)
+	if cfg.gzip {
+		gz := gzip.NewWriter(w)
+		dest = gz
+		defer func() {
+			if closeErr := gz.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+	}
+
+	enc := json.NewEncoder(dest)
+	for res := range source.Stream(ctx) {
+		if res.Err != nil {
+			return res.Err
+		}
+
+		feats, err := featuresFromBatch(res.Record, source.latCol, source.lonCol)
+		res.Record.Release()
+		if err != nil {
+			return err
+		}
+
+		for _, f := range feats {
+			if _, err := dest.Write(recordSeparator); err != nil {
+				return err
+			}
+			if err := enc.Encode(f); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package route_event
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func newTestLRSEvents(t *testing.T) *LRSEvents {
+	t.Helper()
+
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+	}, nil)
+
+	builder := array.NewRecordBuilder(pool, schema)
+	defer builder.Release()
+
+	lats := []float64{5.5072984, 5.506638}
+	lons := []float64{95.3588172, 95.3594017}
+	for i := range lats {
+		builder.Field(0).(*array.Float64Builder).Append(lats[i])
+		builder.Field(1).(*array.Float64Builder).Append(lons[i])
+		builder.Field(2).(*array.StringBuilder).Append("01002")
+	}
+
+	rec := builder.NewRecordBatch()
+
+	events, err := NewLRSEvents([]arrow.RecordBatch{rec}, "EPSG:4326")
+	if err != nil {
+		t.Fatalf("failed to create LRSEvents: %v", err)
+	}
+	return events
+}
+
+func TestWriteGeoJSON_MatchesToGeoJSON(t *testing.T) {
+	events := newTestLRSEvents(t)
+
+	want, err := events.ToGeoJSON()
+	if err != nil {
+		t.Fatalf("ToGeoJSON failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := events.WriteGeoJSON(context.Background(), &buf); err != nil {
+		t.Fatalf("WriteGeoJSON failed: %v", err)
+	}
+
+	var wantFC, gotFC GeoJSONFeatureCollection
+	if err := json.Unmarshal(want, &wantFC); err != nil {
+		t.Fatalf("failed to unmarshal ToGeoJSON output: %v", err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &gotFC); err != nil {
+		t.Fatalf("failed to unmarshal WriteGeoJSON output: %v", err)
+	}
+	if len(gotFC.Features) != len(wantFC.Features) {
+		t.Fatalf("expected %d features, got %d", len(wantFC.Features), len(gotFC.Features))
+	}
+	if gotFC.Features[0].Geometry.Coordinates[0] != wantFC.Features[0].Geometry.Coordinates[0] {
+		t.Errorf("unexpected coordinates: %v", gotFC.Features[0].Geometry.Coordinates)
+	}
+}
+
+func TestWriteGeoJSON_Gzip(t *testing.T) {
+	events := newTestLRSEvents(t)
+
+	var buf bytes.Buffer
+	if err := events.WriteGeoJSON(context.Background(), &buf, WithGzip()); err != nil {
+		t.Fatalf("WriteGeoJSON failed: %v", err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("failed to open gzip reader: %v", err)
+	}
+	defer gz.Close()
+
+	raw, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("failed to read gzip stream: %v", err)
+	}
+
+	var fc GeoJSONFeatureCollection
+	if err := json.Unmarshal(raw, &fc); err != nil {
+		t.Fatalf("failed to unmarshal decompressed output: %v", err)
+	}
+	if len(fc.Features) != 2 {
+		t.Fatalf("expected 2 features, got %d", len(fc.Features))
+	}
+}
+
+func TestWriteGeoJSONSeq(t *testing.T) {
+	events := newTestLRSEvents(t)
+
+	var buf bytes.Buffer
+	if err := events.WriteGeoJSONSeq(context.Background(), &buf); err != nil {
+		t.Fatalf("WriteGeoJSONSeq failed: %v", err)
+	}
+
+	parts := bytes.Split(buf.Bytes(), recordSeparator)
+	// The first split segment is empty, since every record is preceded by
+	// the separator.
+	if len(parts) != 3 {
+		t.Fatalf("expected 2 records, got %d segments", len(parts)-1)
+	}
+	for _, part := range parts[1:] {
+		var f GeoJSONFeature
+		if err := json.Unmarshal(part, &f); err != nil {
+			t.Fatalf("failed to unmarshal feature: %v", err)
+		}
+		if f.Type != "Feature" {
+			t.Errorf("expected Feature type, got %s", f.Type)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package route_event
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// geoParquetVersion is the version of the GeoParquet spec this package writes.
+const geoParquetVersion = "1.1.0"
+
+// geoParquetKey is the Parquet file-level key/value metadata key defined by
+// the GeoParquet spec.
+const geoParquetKey = "geo"
+
+// geometryColumn is the name of the WKB geometry column Sink writes
+// alongside LAT/LON when WithGeoParquetMetadata is used.
+const geometryColumn = "geometry"
+
+// geoParquetEncodingWKB is the only geometry encoding this package writes
+// or accepts on read.
+const geoParquetEncodingWKB = "WKB"
+
+// geoParquetColumn describes a single geometry column entry in the "geo"
+// metadata, following the GeoParquet column object spec.
+type geoParquetColumn struct {
+	Encoding      string    `json:"encoding"`
+	GeometryTypes []string  `json:"geometry_types"`
+	CRS           any       `json:"crs"`
+	Edges         string    `json:"edges,omitempty"`
+	Bbox          []float64 `json:"bbox,omitempty"`
+}
+
+// geoParquetMeta is the top level "geo" metadata object.
+type geoParquetMeta struct {
+	Version       string                       `json:"version"`
+	PrimaryColumn string                       `json:"primary_column"`
+	Columns       map[string]*geoParquetColumn `json:"columns"`
+}
+
+// projjsonFromWKT wraps a WKT CRS definition in a PROJJSON-shaped object. No
+// projection library is wired up in this package, so the WKT is not
+// converted: the object is typed ProjectedCRS, named by the verbatim WKT, and
+// carries an "assumed" note so downstream GeoParquet readers don't mistake it
+// for WGS84. An empty wkt gives a null CRS.
+func projjsonFromWKT(wkt string) any {
+	if wkt == "" {
+		return nil
+	}
+
+	return map[string]any{
+		"$schema": "https://proj.org/schemas/v0.7/projjson.schema.json",
+		"type":    "ProjectedCRS",
+		"name":    wkt,
+		"note":    "assumed: PROJJSON derived verbatim from the source WKT, not independently validated",
+	}
+}
+
+// wktFromProjJSON reverses projjsonFromWKT, recovering the original WKT
+// string stashed in its "name" field.
+func wktFromProjJSON(crs any) string {
+	m, ok := crs.(map[string]any)
+	if !ok {
+		return ""
+	}
+	name, _ := m["name"].(string)
+	return name
+}
+
+// encodeWKBPoint encodes (lon, lat) as a 2D little-endian WKB Point.
+func encodeWKBPoint(lon, lat float64) []byte {
+	buf := make([]byte, 21)
+	buf[0] = 1 // little-endian byte order
+	binary.LittleEndian.PutUint32(buf[1:5], 1 /* wkbPoint */)
+	binary.LittleEndian.PutUint64(buf[5:13], math.Float64bits(lon))
+	binary.LittleEndian.PutUint64(buf[13:21], math.Float64bits(lat))
+	return buf
+}
+
+// decodeWKBPoint decodes a 2D little-endian WKB Point into (lon, lat).
+func decodeWKBPoint(b []byte) (lon, lat float64, err error) {
+	if len(b) < 21 {
+		return 0, 0, fmt.Errorf("WKB point too short: %d bytes", len(b))
+	}
+	if b[0] != 1 {
+		return 0, 0, fmt.Errorf("only little-endian WKB is supported")
+	}
+	if geomType := binary.LittleEndian.Uint32(b[1:5]); geomType != 1 {
+		return 0, 0, fmt.Errorf("expected WKB Point (type 1), got type %d", geomType)
+	}
+	lon = math.Float64frombits(binary.LittleEndian.Uint64(b[5:13]))
+	lat = math.Float64frombits(binary.LittleEndian.Uint64(b[13:21]))
+	return lon, lat, nil
+}
+
+// geoBounds accumulates a bounding box across records.
+type geoBounds struct {
+	minX, minY, maxX, maxY float64
+	set                    bool
+}
+
+func (b *geoBounds) extend(x, y float64) {
+	if !b.set {
+		b.minX, b.maxX = x, x
+		b.minY, b.maxY = y, y
+		b.set = true
+		return
+	}
+	b.minX = math.Min(b.minX, x)
+	b.maxX = math.Max(b.maxX, x)
+	b.minY = math.Min(b.minY, y)
+	b.maxY = math.Max(b.maxY, y)
+}
+
+func (b *geoBounds) bbox() []float64 {
+	if !b.set {
+		return nil
+	}
+	return []float64{b.minX, b.minY, b.maxX, b.maxY}
+}
+
+// eventsBounds computes the LAT/LON bounding box across every record.
+func (e *LRSEvents) eventsBounds() (*geoBounds, error) {
+	b := &geoBounds{}
+	for _, rec := range e.records {
+		if err := extendBoundsFromRecord(b, rec, e.latCol, e.lonCol); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// extendBoundsFromRecord extends b with every non-null (lon, lat) pair in
+// rec's latCol/lonCol columns.
+func extendBoundsFromRecord(b *geoBounds, rec arrow.RecordBatch, latCol, lonCol string) error {
+	schema := rec.Schema()
+	latIdx := schema.FieldIndices(latCol)
+	lonIdx := schema.FieldIndices(lonCol)
+	if len(latIdx) == 0 || len(lonIdx) == 0 {
+		return fmt.Errorf("missing %s/%s column for bbox computation", latCol, lonCol)
+	}
+
+	lat, ok := rec.Column(latIdx[0]).(*array.Float64)
+	if !ok {
+		return fmt.Errorf("%s column is not float64", latCol)
+	}
+	lon, ok := rec.Column(lonIdx[0]).(*array.Float64)
+	if !ok {
+		return fmt.Errorf("%s column is not float64", lonCol)
+	}
+
+	for i := 0; i < lat.Len(); i++ {
+		if lat.IsNull(i) || lon.IsNull(i) {
+			continue
+		}
+		b.extend(lon.Value(i), lat.Value(i))
+	}
+	return nil
+}
+
+// buildGeoParquetSchema appends a binary geometry field to schema and
+// attaches the "geo" file-level key/value metadata describing it.
+func buildGeoParquetSchema(schema *arrow.Schema, crsWKT string, bbox []float64) (*arrow.Schema, error) {
+	meta := &geoParquetMeta{
+		Version:       geoParquetVersion,
+		PrimaryColumn: geometryColumn,
+		Columns: map[string]*geoParquetColumn{
+			geometryColumn: {
+				Encoding:      geoParquetEncodingWKB,
+				GeometryTypes: []string{"Point"},
+				CRS:           projjsonFromWKT(crsWKT),
+				Edges:         "planar",
+				Bbox:          bbox,
+			},
+		},
+	}
+
+	blob, err := json.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal geo metadata: %v", err)
+	}
+	kv := arrow.NewMetadata([]string{geoParquetKey}, []string{string(blob)})
+
+	fields := append(append([]arrow.Field{}, schema.Fields()...), arrow.Field{Name: geometryColumn, Type: arrow.BinaryTypes.Binary})
+	return arrow.NewSchema(fields, &kv), nil
+}
+
+// withGeometryColumn returns a copy of rec under newSchema with a WKB
+// geometry column appended, derived from the record's LAT/LON columns.
+func withGeometryColumn(rec arrow.RecordBatch, newSchema *arrow.Schema, latCol, lonCol string) (arrow.RecordBatch, error) {
+	schema := rec.Schema()
+	latIdx := schema.FieldIndices(latCol)
+	lonIdx := schema.FieldIndices(lonCol)
+	if len(latIdx) == 0 || len(lonIdx) == 0 {
+		return nil, fmt.Errorf("missing %s/%s column to derive geometry", latCol, lonCol)
+	}
+	lat, ok := rec.Column(latIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not float64", latCol)
+	}
+	lon, ok := rec.Column(lonIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not float64", lonCol)
+	}
+
+	pool := memory.NewGoAllocator()
+	builder := array.NewBinaryBuilder(pool, arrow.BinaryTypes.Binary)
+	defer builder.Release()
+
+	for i := 0; i < int(rec.NumRows()); i++ {
+		if lat.IsNull(i) || lon.IsNull(i) {
+			builder.AppendNull()
+			continue
+		}
+		builder.Append(encodeWKBPoint(lon.Value(i), lat.Value(i)))
+	}
+	geometry := builder.NewArray()
+	defer geometry.Release()
+
+	cols := recordColumns(rec)
+	cols = append(cols, geometry)
+	return array.NewRecordBatch(newSchema, cols, rec.NumRows()), nil
+}
+
+// recordColumns returns every column array of rec, in schema order.
+func recordColumns(rec arrow.RecordBatch) []arrow.Array {
+	cols := make([]arrow.Array, int(rec.NumCols()))
+	for i := range cols {
+		cols[i] = rec.Column(i)
+	}
+	return cols
+}
+
+// parseGeoParquetKV reads back the "geo" metadata written by
+// buildGeoParquetSchema.
+func parseGeoParquetKV(meta arrow.Metadata) (*geoParquetMeta, bool) {
+	for i, k := range meta.Keys() {
+		if k != geoParquetKey {
+			continue
+		}
+		var out geoParquetMeta
+		if err := json.Unmarshal([]byte(meta.Values()[i]), &out); err != nil {
+			return nil, false
+		}
+		return &out, true
+	}
+	return nil, false
+}
+
+// GeoParquetReadOption configures NewLRSEventsFromGeoParquet.
+type GeoParquetReadOption func(*geoParquetReadConfig)
+
+type geoParquetReadConfig struct {
+	crs string
+}
+
+// WithCRS overrides the CRS used when the file's "geo" metadata doesn't
+// declare one. Defaults to EPSG:4326.
+func WithCRS(crs string) GeoParquetReadOption {
+	return func(c *geoParquetReadConfig) {
+		c.crs = crs
+	}
+}
+
+// NewLRSEventsFromGeoParquet reads a GeoParquet 1.1 file written by
+// Sink(WithGeoParquetMetadata()), decoding its WKB geometry column back
+// into LAT/LON columns (the geometry column is kept alongside them) and
+// honoring the file's declared CRS.
+func NewLRSEventsFromGeoParquet(filePath string, opts ...GeoParquetReadOption) (*LRSEvents, error) {
+	cfg := &geoParquetReadConfig{crs: "EPSG:4326"}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	pf, err := file.OpenParquetFile(filePath, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open parquet file: %v", err)
+	}
+	defer pf.Close()
+
+	reader, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{}, memory.NewGoAllocator())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create arrow reader: %v", err)
+	}
+
+	schema, err := reader.Schema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %v", err)
+	}
+
+	meta, ok := parseGeoParquetKV(schema.Metadata())
+	if !ok {
+		return nil, fmt.Errorf("file has no GeoParquet \"geo\" metadata")
+	}
+	col, ok := meta.Columns[meta.PrimaryColumn]
+	if !ok {
+		return nil, fmt.Errorf("geo metadata missing primary column %q", meta.PrimaryColumn)
+	}
+	if col.Encoding != geoParquetEncodingWKB {
+		return nil, fmt.Errorf("unsupported GeoParquet encoding %q, only %q is supported", col.Encoding, geoParquetEncodingWKB)
+	}
+
+	crs := cfg.crs
+	if wkt := wktFromProjJSON(col.CRS); wkt != "" {
+		crs = wkt
+	}
+
+	geomIdx := schema.FieldIndices(meta.PrimaryColumn)
+	if len(geomIdx) == 0 {
+		return nil, fmt.Errorf("geometry column %q not found in schema", meta.PrimaryColumn)
+	}
+
+	recordReader, err := reader.GetRecordReader(context.Background(), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get record reader: %v", err)
+	}
+	defer recordReader.Release()
+
+	var records []arrow.RecordBatch
+	for recordReader.Next() {
+		rec := recordReader.RecordBatch()
+		decoded, err := withLatLonFromGeometry(rec, geomIdx[0])
+		if err != nil {
+			for _, r := range records {
+				r.Release()
+			}
+			return nil, fmt.Errorf("failed to decode geometry column: %v", err)
+		}
+		records = append(records, decoded)
+	}
+	if err := recordReader.Err(); err != nil {
+		for _, r := range records {
+			r.Release()
+		}
+		return nil, fmt.Errorf("error reading records: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no records found in file")
+	}
+
+	out := &LRSEvents{
+		routeIDCol:   detectColumn(records[0].Schema(), []string{"ROUTEID", "LINKID", "route_id", "id"}),
+		latCol:       "LAT",
+		lonCol:       "LON",
+		mValCol:      detectColumn(records[0].Schema(), []string{"MVAL", "m", "m_value"}),
+		distToLRSCol: detectColumn(records[0].Schema(), []string{"DIST_TO_LRS", "dist_to_lrs", "distance"}),
+		records:      records,
+		crs:          crs,
+		materialized: false,
+	}
+
+	return out, nil
+}
+
+// withLatLonFromGeometry returns a copy of rec with LAT/LON columns
+// appended, decoded from its WKB geometry column at geomIdx. The geometry
+// column itself is kept.
+func withLatLonFromGeometry(rec arrow.RecordBatch, geomIdx int) (arrow.RecordBatch, error) {
+	geometry, ok := rec.Column(geomIdx).(*array.Binary)
+	if !ok {
+		return nil, fmt.Errorf("geometry column is not a binary array")
+	}
+
+	pool := memory.NewGoAllocator()
+	latBuilder := array.NewFloat64Builder(pool)
+	defer latBuilder.Release()
+	lonBuilder := array.NewFloat64Builder(pool)
+	defer lonBuilder.Release()
+
+	for i := 0; i < geometry.Len(); i++ {
+		if geometry.IsNull(i) {
+			latBuilder.AppendNull()
+			lonBuilder.AppendNull()
+			continue
+		}
+		lon, lat, err := decodeWKBPoint(geometry.Value(i))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", i, err)
+		}
+		latBuilder.Append(lat)
+		lonBuilder.Append(lon)
+	}
+
+	latArr := latBuilder.NewArray()
+	defer latArr.Release()
+	lonArr := lonBuilder.NewArray()
+	defer lonArr.Release()
+
+	fields := append(append([]arrow.Field{}, rec.Schema().Fields()...),
+		arrow.Field{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+		arrow.Field{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+	)
+	meta := rec.Schema().Metadata()
+	newSchema := arrow.NewSchema(fields, &meta)
+
+	cols := append(recordColumns(rec), latArr, lonArr)
+	return array.NewRecordBatch(newSchema, cols, rec.NumRows()), nil
+}
@@ -2,6 +2,8 @@ package route_event
 
 import (
 	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/projection"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -12,6 +14,7 @@ import (
 	"github.com/apache/arrow-go/v18/arrow/memory"
 	"github.com/apache/arrow-go/v18/parquet"
 	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/file"
 	"github.com/apache/arrow-go/v18/parquet/pqarrow"
 )
 
@@ -27,6 +30,40 @@ type LRSEvents struct {
 	sourceFile   *string
 	// Flag to indicate if records are in memory or only stored in file
 	materialized bool
+
+	geoParquet bool
+
+	// crsRegistry resolves and caches transforms for this events' CRS.
+	// Defaults to projection.DefaultRegistry when nil; see Registry and
+	// SetCRSRegistry.
+	crsRegistry *projection.Registry
+}
+
+// Registry returns the CRS registry e.SetCRSRegistry last injected,
+// defaulting to projection.DefaultRegistry when none was set.
+func (e *LRSEvents) Registry() *projection.Registry {
+	if e.crsRegistry != nil {
+		return e.crsRegistry
+	}
+	return projection.DefaultRegistry
+}
+
+// SetCRSRegistry injects the CRS registry e.Registry should return, in
+// place of the process-wide projection.DefaultRegistry.
+func (e *LRSEvents) SetCRSRegistry(r *projection.Registry) {
+	e.crsRegistry = r
+}
+
+// SinkOption configures optional behavior of LRSEvents.Sink.
+type SinkOption func(*LRSEvents)
+
+// WithGeoParquetMetadata makes Sink write a GeoParquet 1.1 compliant file:
+// a WKB "geometry" column derived from LAT/LON, plus the "geo" file-level
+// key/value metadata (version, primary_column, columns, crs, bbox).
+func WithGeoParquetMetadata() SinkOption {
+	return func(e *LRSEvents) {
+		e.geoParquet = true
+	}
 }
 
 func NewLRSEvents(records []arrow.RecordBatch, crs string) (*LRSEvents, error) {
@@ -68,12 +105,27 @@ func (e *LRSEvents) validate() error {
 	return nil
 }
 
-// NewLRSEventsFromGeoJSON creates LRSEvents from GeoJSON byte array
+// geoJSONCRS represents the legacy (pre RFC 7946, but still widely
+// produced) GeoJSON "crs" member. Properties["name"] usually carries a
+// plain CRS identifier or an OGC URN like "urn:ogc:def:crs:EPSG::4326";
+// some exporters instead embed a full PROJJSON object directly in
+// Properties.
+type geoJSONCRS struct {
+	Type       string         `json:"type"`
+	Properties map[string]any `json:"properties"`
+}
+
+// NewLRSEventsFromGeoJSON creates LRSEvents from GeoJSON byte array. If the
+// document (or, failing that, one of its features) declares a "crs"
+// member, it takes precedence over the crs parameter, which is used only
+// as the default when no such member is present.
 func NewLRSEventsFromGeoJSON(data []byte, crs string) (*LRSEvents, error) {
 	var fc struct {
-		Type     string `json:"type"`
+		Type     string      `json:"type"`
+		Crs      *geoJSONCRS `json:"crs"`
 		Features []struct {
-			Type     string `json:"type"`
+			Type     string      `json:"type"`
+			Crs      *geoJSONCRS `json:"crs"`
 			Geometry struct {
 				Type        string    `json:"type"`
 				Coordinates []float64 `json:"coordinates"`
@@ -86,6 +138,16 @@ func NewLRSEventsFromGeoJSON(data []byte, crs string) (*LRSEvents, error) {
 		return nil, fmt.Errorf("failed to unmarshal geojson: %w", err)
 	}
 
+	candidates := []*geoJSONCRS{fc.Crs}
+	for _, f := range fc.Features {
+		candidates = append(candidates, f.Crs)
+	}
+	if declared := firstDeclaredCRS(candidates); declared != nil {
+		if handle, err := projection.DefaultRegistry.Resolve(declared); err == nil {
+			crs = handle.String()
+		}
+	}
+
 	pool := memory.NewGoAllocator()
 
 	// Create a list of all property keys to build the schema
@@ -164,6 +226,29 @@ func NewLRSEventsFromGeoJSON(data []byte, crs string) (*LRSEvents, error) {
 	return NewLRSEvents([]arrow.RecordBatch{rec}, crs)
 }
 
+// firstDeclaredCRS returns the first non-empty "crs" member among
+// candidates, as a value suitable for Registry.Resolve: the full
+// Properties map when it looks like a PROJJSON object (carries an "id" or
+// "$schema" member), otherwise its "name" string.
+func firstDeclaredCRS(candidates []*geoJSONCRS) any {
+	for _, c := range candidates {
+		if c == nil || len(c.Properties) == 0 {
+			continue
+		}
+		if _, ok := c.Properties["id"]; ok {
+			return c.Properties
+		}
+		if _, ok := c.Properties["$schema"]; ok {
+			return c.Properties
+		}
+		if name, ok := c.Properties["name"].(string); ok && name != "" {
+			return name
+		}
+		return c.Properties
+	}
+	return nil
+}
+
 // GetCRS returns the coordinate reference system of the events
 func (e *LRSEvents) GetCRS() string {
 	return e.crs
@@ -174,7 +259,10 @@ func (e *LRSEvents) GetRecords() []arrow.RecordBatch {
 	return e.records
 }
 
-// IsMaterialized returns true if the events are stored in memory, false if only in file
+// IsMaterialized returns true if the events are backed by a parquet file on
+// disk, even if that file hasn't been loaded into memory yet (see
+// LoadToBuffer and Stream); false if they only exist as in-memory record
+// batches.
 func (e *LRSEvents) IsMaterialized() bool {
 	return e.materialized
 }
@@ -196,8 +284,16 @@ func (e *LRSEvents) Release() {
 	}
 }
 
-// Sink the source record batch into parquet file
-func (e *LRSEvents) Sink() error {
+// Sink the source record batch into parquet file. If the events are
+// file-backed and not yet loaded into memory (materialized && records ==
+// nil), it transparently streams the source file row group by row group
+// instead of requiring a full load, so this works for files larger than
+// RAM.
+func (e *LRSEvents) Sink(opts ...SinkOption) error {
+	for _, opt := range opts {
+		opt(e)
+	}
+
 	// Create temporary directory
 	tempDir, err := os.MkdirTemp("", "lrs_events_*")
 	if err != nil {
@@ -213,11 +309,43 @@ func (e *LRSEvents) Sink() error {
 	}
 	defer f.Close()
 
-	if len(e.records) == 0 {
-		return fmt.Errorf("records are empty")
+	if e.records == nil && e.materialized {
+		if err := e.sinkStreaming(f); err != nil {
+			return err
+		}
+	} else {
+		if len(e.records) == 0 {
+			return fmt.Errorf("records are empty")
+		}
+		if err := e.sinkBuffered(f); err != nil {
+			return err
+		}
 	}
 
+	// Make a copy of the file path on the heap before storing
+	sourceFile := filePath
+	e.sourceFile = &sourceFile
+	e.materialized = true
+
+	return nil
+}
+
+// sinkBuffered writes e's in-memory records to f, deriving a geometry
+// column when geoParquet is set. It releases and clears e.records on
+// success.
+func (e *LRSEvents) sinkBuffered(f *os.File) error {
 	schema := e.records[0].Schema()
+	if e.geoParquet {
+		bounds, err := e.eventsBounds()
+		if err != nil {
+			return fmt.Errorf("failed to compute geo bbox: %v", err)
+		}
+		schema, err = buildGeoParquetSchema(schema, e.crs, bounds.bbox())
+		if err != nil {
+			return fmt.Errorf("failed to build geo metadata: %v", err)
+		}
+	}
+
 	writer, err := pqarrow.NewFileWriter(
 		schema,
 		f,
@@ -225,13 +353,22 @@ func (e *LRSEvents) Sink() error {
 			parquet.WithCompression(compress.Codecs.Snappy)),
 		pqarrow.DefaultWriterProps(),
 	)
-
 	if err != nil {
 		return fmt.Errorf("failed to create parquet writer: %v", err)
 	}
 
 	for _, rec := range e.records {
-		if err := writer.WriteBuffered(rec); err != nil {
+		toWrite := rec
+		if e.geoParquet {
+			toWrite, err = withGeometryColumn(rec, schema, e.latCol, e.lonCol)
+			if err != nil {
+				writer.Close()
+				return fmt.Errorf("failed to derive geometry column: %v", err)
+			}
+			defer toWrite.Release()
+		}
+
+		if err := writer.WriteBuffered(toWrite); err != nil {
 			writer.Close()
 			return fmt.Errorf("failed to write record batch: %v", err)
 		}
@@ -242,20 +379,118 @@ func (e *LRSEvents) Sink() error {
 		return fmt.Errorf("failed to close parquet writer: %v", err)
 	}
 
-	// Make a copy of the file path on the heap before storing
-	sourceFile := filePath
-	e.sourceFile = &sourceFile
-
 	// Release the in-memory RecordBatch buffers to free memory, but don't delete temp files
 	for _, rec := range e.records {
 		rec.Release()
 	}
 	e.records = nil
-	e.materialized = true
 
 	return nil
 }
 
+// sinkStreaming writes e's source file through to f without ever loading
+// the whole thing into memory, using Stream to pull one row group at a
+// time. When geoParquet metadata is requested, computing the bbox requires
+// a first streaming pass over the file before the writer (whose schema
+// carries the bbox) can be opened, so the source is streamed twice.
+func (e *LRSEvents) sinkStreaming(f *os.File) error {
+	ctx := context.Background()
+
+	schema, err := e.peekSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	if e.geoParquet {
+		bounds, err := e.streamBounds(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to compute geo bbox: %v", err)
+		}
+		schema, err = buildGeoParquetSchema(schema, e.crs, bounds.bbox())
+		if err != nil {
+			return fmt.Errorf("failed to build geo metadata: %v", err)
+		}
+	}
+
+	writer, err := pqarrow.NewFileWriter(
+		schema,
+		f,
+		parquet.NewWriterProperties(
+			parquet.WithCompression(compress.Codecs.Snappy)),
+		pqarrow.DefaultWriterProps(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create parquet writer: %v", err)
+	}
+
+	for res := range e.Stream(ctx) {
+		if res.Err != nil {
+			writer.Close()
+			return fmt.Errorf("failed to stream record batch: %v", res.Err)
+		}
+
+		toWrite := res.Record
+		if e.geoParquet {
+			derived, err := withGeometryColumn(res.Record, schema, e.latCol, e.lonCol)
+			res.Record.Release()
+			if err != nil {
+				writer.Close()
+				return fmt.Errorf("failed to derive geometry column: %v", err)
+			}
+			toWrite = derived
+		}
+
+		err := writer.WriteBuffered(toWrite)
+		toWrite.Release()
+		if err != nil {
+			writer.Close()
+			return fmt.Errorf("failed to write record batch: %v", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close parquet writer: %v", err)
+	}
+
+	return nil
+}
+
+// peekSchema returns the schema of e's source file by pulling and
+// releasing its first streamed batch. The stream is canceled immediately
+// afterward so its producer goroutine doesn't leak waiting to send a
+// second batch nobody will read.
+func (e *LRSEvents) peekSchema(ctx context.Context) (*arrow.Schema, error) {
+	peekCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for res := range e.Stream(peekCtx) {
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		schema := res.Record.Schema()
+		res.Record.Release()
+		return schema, nil
+	}
+	return nil, fmt.Errorf("records are empty")
+}
+
+// streamBounds computes the LAT/LON bounding box across e's source file
+// without loading it into memory.
+func (e *LRSEvents) streamBounds(ctx context.Context) (*geoBounds, error) {
+	b := &geoBounds{}
+	for res := range e.Stream(ctx) {
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		err := extendBoundsFromRecord(b, res.Record, e.latCol, e.lonCol)
+		res.Record.Release()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
 // NewLRSEventsFromFile creates LRSEvents from a parquet file path
 // The records are NOT eagerly loaded; the file path is stored and records remain nil
 // until LoadToBuffer() is called
@@ -329,43 +564,25 @@ func (e *LRSEvents) GetAttributes() map[string]any {
 	}
 }
 
-// GetRouteIDs returns all unique route IDs from the records
+// GetRouteIDs returns all unique route IDs from the records. If the events
+// are file-backed and not yet loaded into memory (materialized && records
+// == nil), it transparently streams the source file row group by row group
+// instead of requiring a full load, so this works for files larger than
+// RAM.
 func (e *LRSEvents) GetRouteIDs() []string {
 	routeIDs := make(map[string]struct{})
-	for _, batch := range e.records {
-		schema := batch.Schema()
-		indices := schema.FieldIndices(e.routeIDCol)
-		if len(indices) == 0 {
-			continue
-		}
-		colIdx := indices[0]
-		col := batch.Column(colIdx)
 
-		switch c := col.(type) {
-		case *array.String:
-			for i := 0; i < c.Len(); i++ {
-				if !c.IsNull(i) {
-					routeIDs[c.Value(i)] = struct{}{}
-				}
-			}
-		case *array.LargeString:
-			for i := 0; i < c.Len(); i++ {
-				if !c.IsNull(i) {
-					routeIDs[c.Value(i)] = struct{}{}
-				}
-			}
-		case *array.Binary:
-			for i := 0; i < c.Len(); i++ {
-				if !c.IsNull(i) {
-					routeIDs[string(c.Value(i))] = struct{}{}
-				}
-			}
-		case *array.LargeBinary:
-			for i := 0; i < c.Len(); i++ {
-				if !c.IsNull(i) {
-					routeIDs[string(c.Value(i))] = struct{}{}
-				}
+	if e.records == nil && e.materialized {
+		for res := range e.Stream(context.Background()) {
+			if res.Err != nil {
+				return nil
 			}
+			addRouteIDs(routeIDs, res.Record, e.routeIDCol)
+			res.Record.Release()
+		}
+	} else {
+		for _, batch := range e.records {
+			addRouteIDs(routeIDs, batch, e.routeIDCol)
 		}
 	}
 
@@ -376,6 +593,43 @@ func (e *LRSEvents) GetRouteIDs() []string {
 	return out
 }
 
+// addRouteIDs adds every non-null value of batch's routeIDCol column into
+// routeIDs.
+func addRouteIDs(routeIDs map[string]struct{}, batch arrow.RecordBatch, routeIDCol string) {
+	indices := batch.Schema().FieldIndices(routeIDCol)
+	if len(indices) == 0 {
+		return
+	}
+	col := batch.Column(indices[0])
+
+	switch c := col.(type) {
+	case *array.String:
+		for i := 0; i < c.Len(); i++ {
+			if !c.IsNull(i) {
+				routeIDs[c.Value(i)] = struct{}{}
+			}
+		}
+	case *array.LargeString:
+		for i := 0; i < c.Len(); i++ {
+			if !c.IsNull(i) {
+				routeIDs[c.Value(i)] = struct{}{}
+			}
+		}
+	case *array.Binary:
+		for i := 0; i < c.Len(); i++ {
+			if !c.IsNull(i) {
+				routeIDs[string(c.Value(i))] = struct{}{}
+			}
+		}
+	case *array.LargeBinary:
+		for i := 0; i < c.Len(); i++ {
+			if !c.IsNull(i) {
+				routeIDs[string(c.Value(i))] = struct{}{}
+			}
+		}
+	}
+}
+
 // RouteIDColumn returns the name of the route ID column
 func (e *LRSEvents) RouteIDColumn() string {
 	return e.routeIDCol
@@ -3,6 +3,7 @@ package route_event_test
 import (
 	"bm-lrs/pkg/geom"
 	"bm-lrs/pkg/route_event"
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -144,6 +145,65 @@ func TestLRSEventsColumnGettersSetters(t *testing.T) {
 	assert.Equal(t, "distance", events.DistanceToLRSColumn())
 }
 
+func TestLRSEventsGeoParquetRoundTrip(t *testing.T) {
+	rec := createMockRecordBatch()
+	events, err := route_event.NewLRSEvents([]arrow.RecordBatch{rec}, "EPSG:4326")
+	assert.NoError(t, err)
+
+	err = events.Sink(route_event.WithGeoParquetMetadata())
+	assert.NoError(t, err)
+
+	sourceFile := events.GetSourceFile()
+	assert.NotNil(t, sourceFile)
+
+	readBack, err := route_event.NewLRSEventsFromGeoParquet(*sourceFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "EPSG:4326", readBack.GetCRS())
+	assert.ElementsMatch(t, []string{"01002"}, readBack.GetRouteIDs())
+
+	recs := readBack.GetRecords()
+	assert.Equal(t, 1, len(recs))
+	assert.Equal(t, int64(2), recs[0].NumRows())
+
+	readBack.Release()
+	events.Release()
+}
+
+func TestLRSEventsStreamAndLoadToBuffer(t *testing.T) {
+	rec := createMockRecordBatch()
+	events, err := route_event.NewLRSEvents([]arrow.RecordBatch{rec}, "EPSG:4326")
+	assert.NoError(t, err)
+
+	err = events.Sink()
+	assert.NoError(t, err)
+	sourceFile := events.GetSourceFile()
+	assert.NotNil(t, sourceFile)
+
+	fileEvents, err := route_event.NewLRSEventsFromFile(*sourceFile, "EPSG:4326")
+	assert.NoError(t, err)
+	assert.True(t, fileEvents.IsMaterialized())
+	assert.Nil(t, fileEvents.GetRecords())
+
+	// GetRouteIDs transparently streams from the source file without a
+	// full load.
+	assert.ElementsMatch(t, []string{"01002"}, fileEvents.GetRouteIDs())
+	assert.Nil(t, fileEvents.GetRecords())
+
+	ctx := context.Background()
+	err = fileEvents.LoadToBuffer(ctx)
+	assert.NoError(t, err)
+	assert.NotNil(t, fileEvents.GetRecords())
+
+	var rows int64
+	for _, r := range fileEvents.GetRecords() {
+		rows += r.NumRows()
+	}
+	assert.Equal(t, int64(2), rows)
+
+	fileEvents.Release()
+	events.Release()
+}
+
 func TestNewLRSEventsValidation(t *testing.T) {
 	pool := memory.NewGoAllocator()
 	schema := arrow.NewSchema(
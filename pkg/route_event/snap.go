@@ -0,0 +1,220 @@
+package route_event
+
+import (
+	"bm-lrs/pkg/geom"
+	"bm-lrs/pkg/route"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// DefaultSnapPrecisionMeters is SnapToRoute's precisionMeters default,
+// matching common GIS point-to-line snapping practice.
+const DefaultSnapPrecisionMeters = 10.0
+
+// earthRadiusMeters is the sphere radius the equirectangular distance
+// approximation in this file assumes, matching the WGS 84 mean radius.
+const earthRadiusMeters = 6371000.0
+
+// snapCandidateCount bounds how many of the route's nearest segments (by
+// bounding-box center) SnapToRoute inspects per event point before
+// picking the true nearest by perpendicular distance.
+const snapCandidateCount = 8
+
+// SnapToRoute projects every event point onto the nearest segment of
+// rte's linestring, using rte.Index's R-tree to narrow the search to a
+// handful of candidate segments per point before measuring exact
+// perpendicular distance with an equirectangular approximation (CRS is
+// typically EPSG:4326). A point within precisionMeters of its nearest
+// segment has its LAT/LON replaced by the projected point, with its
+// M-Value derived by linear interpolation between the segment's
+// endpoint M-Values; a point outside the tolerance keeps its original
+// LAT/LON. precisionMeters <= 0 uses DefaultSnapPrecisionMeters.
+//
+// Every point in the result gets a "dist_to_line" (meters), a
+// "snapped_mval", and a "snap_status" ("snapped" or "rejected") column,
+// which ToGeoJSON then carries through as properties so downstream
+// consumers can audit the match.
+func (e *LRSEvents) SnapToRoute(rte *route.LRSRoute, precisionMeters float64) (*LRSEvents, error) {
+	if precisionMeters <= 0 {
+		precisionMeters = DefaultSnapPrecisionMeters
+	}
+
+	index, err := rte.Index()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build route index: %w", err)
+	}
+
+	pool := memory.NewGoAllocator()
+
+	var outRecs []arrow.RecordBatch
+	for _, batch := range e.records {
+		rec, err := snapBatch(pool, batch, e.latCol, e.lonCol, index, precisionMeters)
+		if err != nil {
+			return nil, err
+		}
+		outRecs = append(outRecs, rec)
+	}
+
+	return NewLRSEvents(outRecs, e.crs)
+}
+
+// snapBatch rebuilds batch with its latCol/lonCol columns replaced by
+// each row's snapped position (or left as-is when rejected), and
+// appends dist_to_line/snapped_mval/snap_status.
+func snapBatch(pool memory.Allocator, batch arrow.RecordBatch, latCol, lonCol string, index *geom.STRTree, precisionMeters float64) (arrow.RecordBatch, error) {
+	schema := batch.Schema()
+
+	latIdx := schema.FieldIndices(latCol)
+	lonIdx := schema.FieldIndices(lonCol)
+	if len(latIdx) == 0 || len(lonIdx) == 0 {
+		return nil, fmt.Errorf("records missing %s or %s column", latCol, lonCol)
+	}
+	lat, ok := batch.Column(latIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not float64", latCol)
+	}
+	lon, ok := batch.Column(lonIdx[0]).(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("%s column is not float64", lonCol)
+	}
+
+	numRows := int(batch.NumRows())
+
+	latOut := array.NewFloat64Builder(pool)
+	lonOut := array.NewFloat64Builder(pool)
+	distOut := array.NewFloat64Builder(pool)
+	mvalOut := array.NewFloat64Builder(pool)
+	statusOut := array.NewStringBuilder(pool)
+	defer latOut.Release()
+	defer lonOut.Release()
+	defer distOut.Release()
+	defer mvalOut.Release()
+	defer statusOut.Release()
+
+	for i := 0; i < numRows; i++ {
+		y, x := lat.Value(i), lon.Value(i)
+
+		snapLat, snapLon, dist, mval, snapped := nearestOnRoute(index, x, y, precisionMeters)
+
+		if snapped {
+			latOut.Append(snapLat)
+			lonOut.Append(snapLon)
+			statusOut.Append("snapped")
+		} else {
+			latOut.Append(y)
+			lonOut.Append(x)
+			statusOut.Append("rejected")
+		}
+		distOut.Append(dist)
+		mvalOut.Append(mval)
+	}
+
+	latArr := latOut.NewArray()
+	lonArr := lonOut.NewArray()
+	distArr := distOut.NewArray()
+	mvalArr := mvalOut.NewArray()
+	statusArr := statusOut.NewArray()
+	defer latArr.Release()
+	defer lonArr.Release()
+	defer distArr.Release()
+	defer mvalArr.Release()
+	defer statusArr.Release()
+
+	fields := make([]arrow.Field, 0, schema.NumFields()+3)
+	cols := make([]arrow.Array, 0, schema.NumFields()+3)
+	for i := 0; i < schema.NumFields(); i++ {
+		field := schema.Field(i)
+		switch field.Name {
+		case latCol:
+			cols = append(cols, latArr)
+		case lonCol:
+			cols = append(cols, lonArr)
+		default:
+			cols = append(cols, batch.Column(i))
+		}
+		fields = append(fields, field)
+	}
+	fields = append(fields,
+		arrow.Field{Name: "dist_to_line", Type: arrow.PrimitiveTypes.Float64},
+		arrow.Field{Name: "snapped_mval", Type: arrow.PrimitiveTypes.Float64},
+		arrow.Field{Name: "snap_status", Type: arrow.BinaryTypes.String},
+	)
+	cols = append(cols, distArr, mvalArr, statusArr)
+
+	outSchema := arrow.NewSchema(fields, nil)
+	return array.NewRecordBatch(outSchema, cols, int64(numRows)), nil
+}
+
+// nearestOnRoute narrows index to snapCandidateCount candidates nearest
+// (lon, lat) by bounding-box center, then measures the true perpendicular
+// distance to each candidate segment, returning the closest projection.
+// snapped reports whether that distance is within precisionMeters; dist
+// and the projected lat/lon/mval are still returned when it isn't, so
+// callers can record them for audit.
+func nearestOnRoute(index *geom.STRTree, lon, lat, precisionMeters float64) (snapLat, snapLon, dist, mval float64, snapped bool) {
+	candidates := index.NearestK(lon, lat, snapCandidateCount)
+
+	dist = math.Inf(1)
+	for _, c := range candidates {
+		seg, ok := c.Item.(route.Segment)
+		if !ok {
+			continue
+		}
+
+		candLon, candLat, candMVal, candDist := projectOntoSegment(lon, lat, seg)
+		if candDist < dist {
+			dist = candDist
+			snapLat, snapLon, mval = candLat, candLon, candMVal
+		}
+	}
+
+	if math.IsInf(dist, 1) {
+		return 0, 0, 0, 0, false
+	}
+	return snapLat, snapLon, dist, mval, dist <= precisionMeters
+}
+
+// projectOntoSegment finds the closest point on seg to (lon, lat),
+// working in an equirectangular projection centered on lat (accurate
+// enough for the short point-to-segment distances SnapToRoute measures),
+// and linearly interpolates seg's endpoint M-Values at that point.
+func projectOntoSegment(lon, lat float64, seg route.Segment) (snapLon, snapLat, mval, distMeters float64) {
+	ex, ey := degToMeters(seg.EndLon-seg.StartLon, seg.EndLat-seg.StartLat, lat)
+	px, py := degToMeters(lon-seg.StartLon, lat-seg.StartLat, lat)
+
+	lenSq := ex*ex + ey*ey
+
+	t := 0.0
+	if lenSq > 0 {
+		t = (px*ex + py*ey) / lenSq
+		if t < 0 {
+			t = 0
+		} else if t > 1 {
+			t = 1
+		}
+	}
+
+	projX, projY := t*ex, t*ey
+	distMeters = math.Hypot(px-projX, py-projY)
+
+	rad := lat * math.Pi / 180
+	snapLon = seg.StartLon + (projX/(earthRadiusMeters*math.Cos(rad)))*180/math.Pi
+	snapLat = seg.StartLat + (projY/earthRadiusMeters)*180/math.Pi
+	mval = seg.StartMVal + t*(seg.EndMVal-seg.StartMVal)
+
+	return snapLon, snapLat, mval, distMeters
+}
+
+// degToMeters converts a (dLon, dLat) offset in degrees, at reference
+// latitude refLat (degrees), to an approximate (dx, dy) offset in meters
+// under an equirectangular projection.
+func degToMeters(dLon, dLat, refLat float64) (dx, dy float64) {
+	rad := refLat * math.Pi / 180
+	dx = dLon * math.Pi / 180 * earthRadiusMeters * math.Cos(rad)
+	dy = dLat * math.Pi / 180 * earthRadiusMeters
+	return dx, dy
+}
@@ -0,0 +1,116 @@
+package route_event_test
+
+import (
+	"bm-lrs/pkg/route"
+	"bm-lrs/pkg/route_event"
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestRouteForSnap() route.LRSRoute {
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "MVAL", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "VERTEX_SEQ", Type: arrow.PrimitiveTypes.Int32},
+		},
+		nil,
+	)
+
+	rb := array.NewRecordBuilder(pool, schema)
+	defer rb.Release()
+
+	rb.Field(0).(*array.StringBuilder).AppendValues([]string{"01001", "01001"}, nil)
+	rb.Field(1).(*array.Float64Builder).AppendValues([]float64{0, 0}, nil)
+	rb.Field(2).(*array.Float64Builder).AppendValues([]float64{0, 0.01}, nil)
+	rb.Field(3).(*array.Float64Builder).AppendValues([]float64{0, 100}, nil)
+	rb.Field(4).(*array.Int32Builder).AppendValues([]int32{0, 1}, nil)
+
+	rec := rb.NewRecordBatch()
+	return route.NewLRSRoute("01001", []arrow.RecordBatch{rec}, "EPSG:4326")
+}
+
+func createEventsForSnap(lat, lon []float64) *route_event.LRSEvents {
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "ROUTEID", Type: arrow.BinaryTypes.String},
+			{Name: "LAT", Type: arrow.PrimitiveTypes.Float64},
+			{Name: "LON", Type: arrow.PrimitiveTypes.Float64},
+		},
+		nil,
+	)
+
+	rb := array.NewRecordBuilder(pool, schema)
+	defer rb.Release()
+
+	ids := make([]string, len(lat))
+	for i := range ids {
+		ids[i] = "01001"
+	}
+	rb.Field(0).(*array.StringBuilder).AppendValues(ids, nil)
+	rb.Field(1).(*array.Float64Builder).AppendValues(lat, nil)
+	rb.Field(2).(*array.Float64Builder).AppendValues(lon, nil)
+
+	rec := rb.NewRecordBatch()
+	events, err := route_event.NewLRSEvents([]arrow.RecordBatch{rec}, "EPSG:4326")
+	if err != nil {
+		panic(err)
+	}
+	return events
+}
+
+func TestSnapToRouteSnapsWithinPrecision(t *testing.T) {
+	rte := createTestRouteForSnap()
+	defer rte.Release()
+
+	events := createEventsForSnap([]float64{0.000001}, []float64{0.005})
+	defer events.Release()
+
+	snapped, err := events.SnapToRoute(&rte, route_event.DefaultSnapPrecisionMeters)
+	assert.NoError(t, err)
+
+	geojson, err := snapped.ToGeoJSON()
+	assert.NoError(t, err)
+
+	var parsed struct {
+		Features []struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	assert.NoError(t, json.Unmarshal(geojson, &parsed))
+	assert.Len(t, parsed.Features, 1)
+	assert.Equal(t, "snapped", parsed.Features[0].Properties["snap_status"])
+	assert.InDelta(t, 50, parsed.Features[0].Properties["snapped_mval"], 1)
+}
+
+func TestSnapToRouteRejectsBeyondPrecision(t *testing.T) {
+	rte := createTestRouteForSnap()
+	defer rte.Release()
+
+	events := createEventsForSnap([]float64{1}, []float64{0.005})
+	defer events.Release()
+
+	snapped, err := events.SnapToRoute(&rte, route_event.DefaultSnapPrecisionMeters)
+	assert.NoError(t, err)
+
+	geojson, err := snapped.ToGeoJSON()
+	assert.NoError(t, err)
+
+	var parsed struct {
+		Features []struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	assert.NoError(t, json.Unmarshal(geojson, &parsed))
+	assert.Len(t, parsed.Features, 1)
+	assert.Equal(t, "rejected", parsed.Features[0].Properties["snap_status"])
+}
@@ -0,0 +1,219 @@
+package route_event
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// StreamResult is a single item yielded by Stream: either a RecordBatch or
+// an error that terminates the stream. The caller owns Record and must
+// Release it.
+type StreamResult struct {
+	Record arrow.RecordBatch
+	Err    error
+}
+
+// StreamOption configures Stream.
+type StreamOption func(*streamConfig)
+
+type streamConfig struct {
+	batchTimeout time.Duration
+}
+
+// WithBatchTimeout bounds how long a single row-group read may take before
+// Stream aborts with a timeout error, modeled on the SetReadDeadline
+// pattern used by the Flight DoExchange stream (see
+// pkg/flight/deadline.go). Zero (the default) means no per-batch timeout
+// beyond whatever deadline ctx itself carries.
+func WithBatchTimeout(d time.Duration) StreamOption {
+	return func(c *streamConfig) {
+		c.batchTimeout = d
+	}
+}
+
+// Stream yields e's record batches one at a time, honoring ctx cancellation
+// and a configurable per-batch timeout, so files larger than RAM can be
+// processed without a full in-memory load. When e is backed by a source
+// file (IsMaterialized() with no records loaded yet), batches are read
+// row-group by row-group via pqarrow.FileReader.GetRecordReader with
+// row-group-level pushdown. When e already has in-memory records, those
+// are streamed instead of re-reading from disk. The returned channel is
+// closed once every batch has been sent, ctx is done, or an error occurs;
+// on error the final item carries Err and no further items follow.
+func (e *LRSEvents) Stream(ctx context.Context, opts ...StreamOption) <-chan StreamResult {
+	cfg := &streamConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	out := make(chan StreamResult)
+
+	if e.records != nil {
+		go func() {
+			defer close(out)
+			for _, rec := range e.records {
+				rec.Retain()
+				select {
+				case out <- StreamResult{Record: rec}:
+				case <-ctx.Done():
+					rec.Release()
+					return
+				}
+			}
+		}()
+		return out
+	}
+
+	go func() {
+		defer close(out)
+
+		if e.sourceFile == nil {
+			sendErr(ctx, out, fmt.Errorf("no source file to stream from"))
+			return
+		}
+
+		pf, err := file.OpenParquetFile(*e.sourceFile, false)
+		if err != nil {
+			sendErr(ctx, out, fmt.Errorf("failed to open parquet file: %v", err))
+			return
+		}
+		defer pf.Close()
+
+		reader, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{}, memory.NewGoAllocator())
+		if err != nil {
+			sendErr(ctx, out, fmt.Errorf("failed to create arrow reader: %v", err))
+			return
+		}
+
+		for rg := 0; rg < pf.NumRowGroups(); rg++ {
+			rec, err := readRowGroup(ctx, reader, rg, cfg.batchTimeout)
+			if err != nil {
+				sendErr(ctx, out, err)
+				return
+			}
+			if rec == nil {
+				continue
+			}
+
+			select {
+			case out <- StreamResult{Record: rec}:
+			case <-ctx.Done():
+				rec.Release()
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// sendErr delivers a terminal error to out, abandoning the send rather than
+// blocking forever if the consumer has already stopped reading (e.g. after
+// taking just the first batch to peek at the schema).
+func sendErr(ctx context.Context, out chan<- StreamResult, err error) {
+	select {
+	case out <- StreamResult{Err: err}:
+	case <-ctx.Done():
+	}
+}
+
+// rowGroupResult is the outcome of the background read started by
+// readRowGroup: either a retained RecordBatch or an error.
+type rowGroupResult struct {
+	rec arrow.RecordBatch
+	err error
+}
+
+// readRowGroup reads row group rg of reader as a single RecordBatch,
+// racing it against ctx and, if timeout is non-zero, against timeout.
+// Returns a nil RecordBatch and nil error for an empty row group.
+func readRowGroup(ctx context.Context, reader *pqarrow.FileReader, rg int, timeout time.Duration) (arrow.RecordBatch, error) {
+	done := make(chan rowGroupResult, 1)
+
+	go func() {
+		recordReader, err := reader.GetRecordReader(ctx, nil, []int{rg})
+		if err != nil {
+			done <- rowGroupResult{nil, fmt.Errorf("failed to get record reader for row group %d: %v", rg, err)}
+			return
+		}
+		defer recordReader.Release()
+
+		if !recordReader.Next() {
+			if err := recordReader.Err(); err != nil {
+				done <- rowGroupResult{nil, fmt.Errorf("error reading row group %d: %v", rg, err)}
+				return
+			}
+			done <- rowGroupResult{nil, nil}
+			return
+		}
+
+		rec := recordReader.RecordBatch()
+		rec.Retain()
+		done <- rowGroupResult{rec, nil}
+	}()
+
+	var timeoutCh <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+
+	select {
+	case r := <-done:
+		return r.rec, r.err
+	case <-ctx.Done():
+		go releaseDeferredResult(done)
+		return nil, fmt.Errorf("row group %d: %w", rg, ctx.Err())
+	case <-timeoutCh:
+		go releaseDeferredResult(done)
+		return nil, fmt.Errorf("timed out reading row group %d after %s", rg, timeout)
+	}
+}
+
+// releaseDeferredResult waits for the reader goroutine started by
+// readRowGroup to finish after its caller has already given up on ctx or
+// timeout, and releases the RecordBatch it retained, if any -- otherwise
+// that retained buffer is never freed since nothing else reads from done.
+func releaseDeferredResult(done <-chan rowGroupResult) {
+	r := <-done
+	if r.rec != nil {
+		r.rec.Release()
+	}
+}
+
+// LoadToBuffer fully materializes e's records into memory by draining
+// Stream, so subsequent GetRecords/GetRouteIDs/Sink calls see them
+// in-memory rather than transparently re-reading the source file. It is a
+// no-op if records are already loaded.
+func (e *LRSEvents) LoadToBuffer(ctx context.Context) error {
+	if e.records != nil {
+		return nil
+	}
+
+	var records []arrow.RecordBatch
+	for res := range e.Stream(ctx) {
+		if res.Err != nil {
+			for _, r := range records {
+				r.Release()
+			}
+			return res.Err
+		}
+		records = append(records, res.Record)
+	}
+	if err := ctx.Err(); err != nil {
+		for _, r := range records {
+			r.Release()
+		}
+		return err
+	}
+
+	e.records = records
+	return nil
+}